@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// parseRoleARNs splits --aws-role-arns into its roles, dropping blanks and
+// duplicates. anything that is not an IAM role ARN is rejected up front
+// rather than failing later inside sts:AssumeRole.
+func parseRoleARNs(value string) ([]string, error) {
+	var roles []string
+	seen := map[string]bool{}
+	for _, role := range strings.Split(value, ",") {
+		role = strings.TrimSpace(role)
+		if role == "" || seen[role] {
+			continue
+		}
+		if !strings.HasPrefix(role, "arn:") || !strings.Contains(role, ":role/") {
+			return nil, fmt.Errorf("%q is not an IAM role ARN", role)
+		}
+		seen[role] = true
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// sweepAccounts runs one pass per account the executor reports. providers
+// that reach a single account get one pass. name is the credentials file
+// account the --clouds entry selected, if any.
+func sweepAccounts(ctx context.Context, cloud, name string, executor core.ExecutorInterface) {
+	accounts, err := executor.AccountsGet(ctx)
+	if err != nil {
+		if !errors.Is(err, core.ErrUnsupported) {
+			p := passOf(ctx)
+			_, _ = fmt.Fprintf(p.out, "Cannot get accounts due to %s\n", err.Error())
+			p.report.listFailed(cloud, name, kindAccount, err)
+			return
+		}
+		// single-account provider
+		accounts = []core.Account{{}}
+	}
+	for _, account := range accounts {
+		if cancelled(ctx) {
+			return
+		}
+		sweepAccount(ctx, cloud, name, executor, account)
+	}
+}
+
+// accountLabel is what a pass's output lines and report records carry: the
+// credentials file account name, the provider account ID, or both.
+func accountLabel(name string, account core.Account) string {
+	switch {
+	case name != "" && account.ID != "":
+		return name + "/" + account.ID
+	case name != "":
+		return name
+	}
+	return account.ID
+}
+
+// sweepAccount runs the whole cloud pass under account. when the pass has a
+// label every output line and report record of it carries the label.
+func sweepAccount(ctx context.Context, cloud, name string, executor core.ExecutorInterface, account core.Account) {
+	ctx = context.WithValue(ctx, core.AccountKey, account)
+	p := passOf(ctx)
+	if label := accountLabel(name, account); label != "" {
+		prefix := fmt.Sprintf("[%s] ", label)
+		p.account = label
+		p.out = &linePrefixWriter{w: p.out, prefix: prefix}
+		ctx = context.WithValue(ctx, core.OutWriterKey, p.out)
+		if warn, ok := ctx.Value(core.WarnWriterKey).(io.Writer); ok {
+			ctx = context.WithValue(ctx, core.WarnWriterKey, io.Writer(&linePrefixWriter{w: warn, prefix: prefix}))
+		}
+	}
+	ctx = withPass(ctx, p)
+	// the cloud header already names a credentials file account; only a
+	// provider-reported account needs its own header.
+	if account.Role != "" {
+		fprettyPrint(p.out, fmt.Sprintf("[ACCOUNT %s via %s]\n", account.ID, account.Role), p.run.Mock)
+	} else if account.ID != "" {
+		fprettyPrint(p.out, fmt.Sprintf("[ACCOUNT %s]\n", account.ID), p.run.Mock)
+	}
+	sweepCloud(ctx, cloud, executor)
+}
+
+// pass is what one account pass of a sweep runs under: the label its records
+// carry, the age limits its policy resolves, the writer its lines go to, and
+// the options and reporter of the run it belongs to. it travels on ctx, so a
+// pass never changes anything another run can see.
+type pass struct {
+	account string
+	limits  ageLimits
+	out     io.Writer
+	run     runOptions
+	report  *reporter
+}
+
+type passKey struct{}
+
+// withPass returns ctx running under p.
+func withPass(ctx context.Context, p pass) context.Context {
+	return context.WithValue(ctx, passKey{}, p)
+}
+
+// passOf returns the pass ctx runs under. outside of one (the CLI, and tests
+// driving the delete loops directly, with a nil ctx included) it is the
+// unlabelled pass of the flags writing to out and the package report.
+func passOf(ctx context.Context) pass {
+	if ctx != nil {
+		if p, ok := ctx.Value(passKey{}).(pass); ok {
+			return p
+		}
+	}
+	return pass{
+		limits: currentAgeLimits(),
+		out:    out,
+		run:    runOptions{Clouds: flagClouds, Mock: flagMock},
+		report: report,
+	}
+}
+
+// linePrefixWriter starts every line written through it with prefix. lines
+// are often written in pieces ("[name] ▶ " then "Deleted!\n"), so it tracks
+// whether the next byte begins a line.
+type linePrefixWriter struct {
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+func (p *linePrefixWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		if !p.midLine {
+			if _, err := io.WriteString(p.w, p.prefix); err != nil {
+				return written, err
+			}
+			p.midLine = true
+		}
+		chunk := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			chunk = b[:i+1]
+			p.midLine = false
+		}
+		n, err := p.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[len(chunk):]
+	}
+	return written, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cloud66/janitor/core"
+)
+
+func TestParseRoleARNs(t *testing.T) {
+	roles, err := parseRoleARNs(" arn:aws:iam::111111111111:role/janitor, ,arn:aws:iam::222222222222:role/janitor,arn:aws:iam::111111111111:role/janitor")
+	if err != nil {
+		t.Fatalf("parseRoleARNs: %v", err)
+	}
+	want := []string{"arn:aws:iam::111111111111:role/janitor", "arn:aws:iam::222222222222:role/janitor"}
+	if !sliceEqual(roles, want) {
+		t.Errorf("want %v, got %v", want, roles)
+	}
+
+	if roles, err := parseRoleARNs(""); err != nil || len(roles) != 0 {
+		t.Errorf("empty flag should mean no roles, got %v / %v", roles, err)
+	}
+	for _, bad := range []string{"janitor", "arn:aws:iam::111111111111:user/bob"} {
+		if _, err := parseRoleARNs(bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+func TestLinePrefixWriter_PrefixesLinesWrittenInPieces(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := &linePrefixWriter{w: buf, prefix: "[123] "}
+	for _, piece := range []string{"[a] ▶ ", "Deleted!\n", "one\ntwo\n", "three"} {
+		if _, err := w.Write([]byte(piece)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	want := "[123] [a] ▶ Deleted!\n[123] one\n[123] two\n[123] three"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+// volumeExecutor serves a fixed volume list and records the account each
+// VolumesGet ran under.
+type volumeExecutor struct {
+	*fakeExecutor
+	volumes  []core.Volume
+	accounts []core.Account
+}
+
+func (v *volumeExecutor) VolumesGet(ctx context.Context) ([]core.Volume, error) {
+	account, _ := ctx.Value(core.AccountKey).(core.Account)
+	v.accounts = append(v.accounts, account)
+	return v.volumes, nil
+}
+
+func TestSweepAccount_LabelsOutputAndRecords(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	prevAction := flagAction
+	flagAction = actionDelete
+	t.Cleanup(func() { flagAction = prevAction })
+	buf := captureReport(t, outputNDJSON)
+	exec := &volumeExecutor{fakeExecutor: &fakeExecutor{}, volumes: []core.Volume{{VendorID: "vol-1", Name: "data", Age: 3, Region: "us-east-1"}}}
+	ctx := context.WithValue(context.Background(), core.ExecutorKey, core.ExecutorInterface(exec))
+	account := core.Account{ID: "222222222222", Role: "arn:aws:iam::222222222222:role/janitor"}
+
+	var sink io.Writer
+	text := captureOutput(t, func() {
+		sink = out
+		sweepAccount(ctx, "aws", "", exec, account)
+		if out != sink {
+			t.Error("the pass swapped the package output writer")
+		}
+	})
+	if len(exec.accounts) != 1 || exec.accounts[0] != account {
+		t.Errorf("want the pass to run under %v, got %v", account, exec.accounts)
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("want a header, a count and a volume line, got:\n%s", text)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[222222222222] ") {
+			t.Errorf("line not labelled with the account: %q", line)
+		}
+	}
+	if !strings.Contains(lines[0], "[ACCOUNT 222222222222 via arn:aws:iam::222222222222:role/janitor]") {
+		t.Errorf("want an account header first, got %q", lines[0])
+	}
+	records := decodeNDJSON(t, buf)
+	if len(records) != 1 || records[0].Account != "222222222222" {
+		t.Errorf("want one record stamped with the account, got %+v", records)
+	}
+	if p := passOf(ctx); p.account != "" {
+		t.Errorf("account label leaked past the pass: %q", p.account)
+	}
+}
+
+func TestSweepAccount_UnlabelledWithoutID(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	prevAction := flagAction
+	flagAction = actionDelete
+	t.Cleanup(func() { flagAction = prevAction })
+	buf := captureReport(t, outputNDJSON)
+	exec := &volumeExecutor{fakeExecutor: &fakeExecutor{}, volumes: []core.Volume{{VendorID: "vol-1", Name: "data", Age: 3}}}
+
+	text := captureOutput(t, func() {
+		sweepAccount(context.Background(), "aws", "", exec, core.Account{})
+	})
+	if !strings.HasPrefix(text, "[MOCK] [1 VOLUMES]") {
+		t.Errorf("single-account output should be unchanged, got:\n%s", text)
+	}
+	if records := decodeNDJSON(t, buf); len(records) != 1 || records[0].Account != "" {
+		t.Errorf("want an unstamped record, got %+v", records)
+	}
+}
+
+func TestAccountLabel(t *testing.T) {
+	cases := []struct {
+		name    string
+		account core.Account
+		want    string
+	}{
+		{"", core.Account{}, ""},
+		{"ci", core.Account{}, "ci"},
+		{"", core.Account{ID: "111111111111"}, "111111111111"},
+		{"ci", core.Account{ID: "111111111111", Role: "arn:aws:iam::111111111111:role/janitor"}, "ci/111111111111"},
+	}
+	for _, tc := range cases {
+		if got := accountLabel(tc.name, tc.account); got != tc.want {
+			t.Errorf("accountLabel(%q, %v): want %q, got %q", tc.name, tc.account, tc.want, got)
+		}
+	}
+}
+
+func TestPass_LimitsLabelAndOutputComeFromCtx(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	buf := captureReport(t, outputNDJSON)
+	var passOut bytes.Buffer
+	ctx := withPass(ctxWithExec(&fakeExecutor{}), pass{account: "ci", limits: ageLimits{Normal: 10, Long: 20}, out: &passOut, run: runOptions{Mock: true}, report: report})
+
+	text := captureOutput(t, func() {
+		deleteServers(ctx, "aws", []core.Server{{VendorID: "i-1", Name: "box", Age: 3, State: "ON"}})
+	})
+	if text != "" {
+		t.Errorf("want nothing on the package output, got %q", text)
+	}
+	if !strings.Contains(passOut.String(), "skipped (") {
+		t.Errorf("want the decision on the pass output, got %q", passOut.String())
+	}
+	records := decodeNDJSON(t, buf)
+	if len(records) != 1 || records[0].Decision != policyKeep || records[0].Account != "ci" || records[0].MaxAgeDays != 10 {
+		t.Errorf("want i-1 kept under the pass's 10 day limit in account ci, got %+v", records)
+	}
+	if limits := currentAgeLimits(); limits.Normal != 0.38 {
+		t.Errorf("the pass changed the flag ages: %+v", limits)
+	}
+}
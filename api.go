@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRunRequestBytes caps the POST /runs body.
+const maxRunRequestBytes = 1 << 16
+
+// runRequest is the POST /runs body. Clouds defaults to --clouds and takes
+// the same entries (aws, hetzner:ci); Mock defaults to true, and a live run
+// needs the --api-token as a bearer token, the API's --yes.
+type runRequest struct {
+	Clouds []string `json:"clouds"`
+	Mock   *bool    `json:"mock"`
+}
+
+// writeJSON writes v with status. best-effort, same rationale as handler.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes {"error": message} with status.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// authorized reports whether r carries the API token. an empty token never
+// authorizes anything.
+func (d *daemon) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && d.apiToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(d.apiToken)) == 1
+}
+
+// options validates the request against the configured clouds and
+// credentials file, the checks main applies to --clouds at startup.
+func (req runRequest) options(defaults runOptions) (runOptions, error) {
+	opts := defaults
+	if req.Mock != nil {
+		opts.Mock = *req.Mock
+	}
+	if len(req.Clouds) == 0 {
+		return opts, nil
+	}
+	for _, token := range req.Clouds {
+		if token == "" || strings.Contains(token, ",") {
+			return opts, fmt.Errorf("invalid cloud entry %q", token)
+		}
+		if cloud, _ := splitCloudToken(token); clouds[cloud] == nil {
+			return opts, fmt.Errorf("unknown cloud %q", cloud)
+		}
+	}
+	if err := checkCloudAccounts(req.Clouds, credentials); err != nil {
+		return opts, err
+	}
+	opts.Clouds = strings.Join(req.Clouds, ",")
+	return opts, nil
+}
+
+// handleStartRun serves POST /runs: it starts a sweep in the background and
+// answers 202 with the run, or 409 with the run already in flight.
+func (d *daemon) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	var req runRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRunRequestBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err.Error()))
+			return
+		}
+	}
+	opts, err := req.options(d.defaults)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !opts.Mock {
+		switch {
+		case d.apiToken == "":
+			writeError(w, http.StatusForbidden, "live runs over the API are disabled; start the daemon with --api-token")
+			return
+		case !d.authorized(r):
+			writeError(w, http.StatusUnauthorized, "a live run needs the API token as a bearer token")
+			return
+		}
+	}
+
+	run, ok := d.start(triggerAPI, opts)
+	if !ok {
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %d is still in progress", run.ID))
+		return
+	}
+	d.inFlight.Add(1)
+	go func() {
+		defer d.inFlight.Done()
+		d.execute(d.ctx, run, opts)
+	}()
+	started, _ := d.history.get(run.ID)
+	w.Header().Set("Location", fmt.Sprintf("/runs/%d", run.ID))
+	writeJSON(w, http.StatusAccepted, started)
+}
+
+// handleRuns serves GET /runs: the kept runs, newest first, without their
+// records.
+func (d *daemon) handleRuns(w http.ResponseWriter, r *http.Request) {
+	runs := d.history.list()
+	for i := range runs {
+		runs[i].Records = nil
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// handleRun serves GET /runs/{id}: the run with every decision made so far.
+func (d *daemon) handleRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "run id must be a number")
+		return
+	}
+	run, ok := d.history.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %d is not kept", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// inventory is the GET /resources response. it is only as complete as the
+// run it comes from: Clouds names what that run swept, and ListingFailures
+// the kinds it could not list in full, whose resources are missing or
+// partial below.
+type inventory struct {
+	RunID           int            `json:"run_id"`
+	Finished        time.Time      `json:"finished"`
+	Clouds          string         `json:"clouds"`
+	Mock            bool           `json:"mock"`
+	ExitCode        int            `json:"exit_code"`
+	ListingFailures []summaryEntry `json:"listing_failures"`
+	Resources       []record       `json:"resources"`
+}
+
+// handleResources serves GET /resources: every resource the latest finished
+// run classified and left in place, optionally narrowed with ?cloud=, ?kind=
+// and ?decision=, along with what that run covered. it reads the run
+// history; it never calls a provider. with no finished run it answers 404,
+// or 409 while the first run is still in flight, and ?cloud= for a cloud the
+// run did not sweep is a 409 too: an empty list would read as "nothing
+// there".
+func (d *daemon) handleResources(w http.ResponseWriter, r *http.Request) {
+	run, ok := d.history.latestFinished()
+	if !ok {
+		d.mu.Lock()
+		running := d.running
+		d.mu.Unlock()
+		if running != nil {
+			writeError(w, http.StatusConflict, fmt.Sprintf("no run has finished yet; run %d is still in progress", running.ID))
+			return
+		}
+		writeError(w, http.StatusNotFound, "no run has finished yet; POST /runs to start one")
+		return
+	}
+	query := r.URL.Query()
+	if query.Has("cloud") && !runSwept(run, query.Get("cloud")) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %d did not sweep %s (clouds %q)", run.ID, query.Get("cloud"), run.Clouds))
+		return
+	}
+	resources := []record{}
+	for _, rec := range run.Records {
+		switch {
+		case rec.Kind == kindRegion:
+			continue
+		case rec.Result == resultDeleted || rec.Result == resultReleased:
+			// gone since the run
+			continue
+		case query.Has("cloud") && rec.Cloud != query.Get("cloud"),
+			query.Has("kind") && rec.Kind != query.Get("kind"),
+			query.Has("decision") && rec.Decision != query.Get("decision"):
+			continue
+		}
+		resources = append(resources, rec)
+	}
+	listingFailures := []summaryEntry{}
+	for _, entry := range run.Summary {
+		if entry.ListError != "" || len(entry.FailedRegions) > 0 {
+			listingFailures = append(listingFailures, entry)
+		}
+	}
+	inv := inventory{
+		RunID:           run.ID,
+		Finished:        *run.Finished,
+		Clouds:          run.Clouds,
+		Mock:            run.Mock,
+		ListingFailures: listingFailures,
+		Resources:       resources,
+	}
+	if run.ExitCode != nil {
+		inv.ExitCode = *run.ExitCode
+	}
+	writeJSON(w, http.StatusOK, inv)
+}
+
+// runSwept reports whether run's --clouds entries include cloud.
+func runSwept(run runReport, cloud string) bool {
+	for _, token := range strings.Split(run.Clouds, ",") {
+		if name, _ := splitCloudToken(strings.TrimSpace(token)); name == cloud {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// apiDaemon serves a daemon sweeping exec as the "fake" cloud, mock by
+// default, with token as its --api-token.
+func apiDaemon(t *testing.T, exec core.ExecutorInterface, token string) (*daemon, *httptest.Server) {
+	t.Helper()
+	withFlags(t, true, 0.38, 5.0)
+	withDaemonCloud(t, exec)
+	// runs sweep in the background; keep their output out of the test log
+	prevOut := out
+	out = io.Discard
+	t.Cleanup(func() { out = prevOut })
+	d := newTestDaemon(context.Background(), 5)
+	d.apiToken = token
+	ts := httptest.NewServer(d.serveMux())
+	t.Cleanup(ts.Close)
+	return d, ts
+}
+
+// call sends method path with body and bearer token (when non-empty) and
+// decodes the JSON answer into v (when non-nil).
+func call(t *testing.T, ts *httptest.Server, method, path, body, token string, v interface{}) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("building %s %s: %v", method, path, err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if v != nil {
+		if err := json.Unmarshal(b, v); err != nil {
+			t.Fatalf("decoding %s %s answer %q: %v", method, path, b, err)
+		}
+	}
+	return resp
+}
+
+func twoVolumes() *volumeExecutor {
+	return &volumeExecutor{fakeExecutor: &fakeExecutor{}, volumes: []core.Volume{
+		{VendorID: "vol-old", Name: "old", Age: 3, Region: "nyc1"},
+		{VendorID: "vol-used", Name: "used", Age: 3, Region: "nyc1", Attached: true},
+	}}
+}
+
+func TestAPI_StartMockRunAndFollowIt(t *testing.T) {
+	exec := twoVolumes()
+	d, ts := apiDaemon(t, exec, "")
+
+	var started runReport
+	resp := call(t, ts, http.MethodPost, "/runs", `{"clouds": ["fake"]}`, "", &started)
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/runs/1" {
+		t.Fatalf("want 202 with Location /runs/1, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if started.ID != 1 || started.Trigger != triggerAPI || !started.Mock || started.Clouds != "fake" {
+		t.Errorf("unexpected run %+v", started)
+	}
+	d.inFlight.Wait()
+
+	var run runReport
+	call(t, ts, http.MethodGet, "/runs/1", "", "", &run)
+	if run.Status != runFinished || len(run.Records) != 2 {
+		t.Fatalf("want the finished run with its decisions, got %+v", run)
+	}
+	if len(exec.deletedVolumes) != 0 {
+		t.Errorf("mock run deleted %+v", exec.deletedVolumes)
+	}
+
+	var runs []runReport
+	call(t, ts, http.MethodGet, "/runs", "", "", &runs)
+	if len(runs) != 1 || runs[0].Records != nil {
+		t.Errorf("want the run listed without its records, got %+v", runs)
+	}
+}
+
+func TestAPI_LiveRunNeedsToken(t *testing.T) {
+	live := `{"clouds": ["fake"], "mock": false}`
+
+	_, ts := apiDaemon(t, twoVolumes(), "")
+	if resp := call(t, ts, http.MethodPost, "/runs", live, "anything", nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("without --api-token: want 403, got %d", resp.StatusCode)
+	}
+
+	exec := twoVolumes()
+	d, ts := apiDaemon(t, exec, "s3cret")
+	for _, token := range []string{"", "wrong"} {
+		if resp := call(t, ts, http.MethodPost, "/runs", live, token, nil); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: want 401, got %d", token, resp.StatusCode)
+		}
+	}
+	if len(d.history.list()) != 0 {
+		t.Fatal("a refused request started a run")
+	}
+	if resp := call(t, ts, http.MethodPost, "/runs", live, "s3cret", nil); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("with the token: want 202, got %d", resp.StatusCode)
+	}
+	d.inFlight.Wait()
+	if len(exec.deletedVolumes) != 1 || exec.deletedVolumes[0].VendorID != "vol-old" {
+		t.Errorf("want the live run to delete vol-old, got %+v", exec.deletedVolumes)
+	}
+	if !flagMock {
+		t.Error("the live run's --mock leaked past it")
+	}
+}
+
+func TestAPI_RejectsBadRequests(t *testing.T) {
+	d, ts := apiDaemon(t, twoVolumes(), "")
+	for _, body := range []string{
+		`{"clouds": ["nope"]}`,
+		`{"clouds": ["fake:ci"]}`,
+		`{"clouds": ["fake,aws"]}`,
+		`{"cloud": "fake"}`,
+		`not json`,
+	} {
+		if resp := call(t, ts, http.MethodPost, "/runs", body, "", nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: want 400, got %d", body, resp.StatusCode)
+		}
+	}
+	if resp := call(t, ts, http.MethodGet, "/runs/abc", "", "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET /runs/abc: want 400, got %d", resp.StatusCode)
+	}
+	if resp := call(t, ts, http.MethodGet, "/runs/7", "", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /runs/7: want 404, got %d", resp.StatusCode)
+	}
+
+	// a run in flight, scheduled or not, blocks another
+	run, _ := d.start(triggerSchedule, d.defaults)
+	var answer map[string]string
+	if resp := call(t, ts, http.MethodPost, "/runs", "", "", &answer); resp.StatusCode != http.StatusConflict {
+		t.Errorf("want 409 while run %d is in flight, got %d", run.ID, resp.StatusCode)
+	}
+	if !strings.Contains(answer["error"], "run 1 is still in progress") {
+		t.Errorf("want the in-flight run named, got %q", answer["error"])
+	}
+}
+
+func TestAPI_Resources(t *testing.T) {
+	exec := twoVolumes()
+	d, ts := apiDaemon(t, exec, "")
+	if resp := call(t, ts, http.MethodGet, "/resources", "", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("before any run: want 404, got %d", resp.StatusCode)
+	}
+
+	// a live run: the deleted volume drops out of the inventory
+	d.defaults.Mock = false
+	run, ok := d.start(triggerSchedule, d.defaults)
+	if !ok {
+		t.Fatalf("run %d is still in progress", run.ID)
+	}
+	if resp := call(t, ts, http.MethodGet, "/resources", "", "", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("while the first run is in flight: want 409, got %d", resp.StatusCode)
+	}
+	d.execute(context.Background(), run, d.defaults)
+
+	var inv inventory
+	call(t, ts, http.MethodGet, "/resources", "", "", &inv)
+	if inv.RunID != 1 || len(inv.Resources) != 1 || inv.Resources[0].VendorID != "vol-used" {
+		t.Fatalf("want only the kept volume, got %+v", inv)
+	}
+	if inv.Clouds != "fake" || inv.Mock || inv.Finished.IsZero() || inv.ExitCode != exitClean || inv.ListingFailures == nil || len(inv.ListingFailures) != 0 {
+		t.Errorf("want the run's metadata, got %+v", inv)
+	}
+	if inv.Resources[0].Decision != policyKeep || inv.Resources[0].Reason != "attached to instance" {
+		t.Errorf("want the classification, got %+v", inv.Resources[0])
+	}
+	call(t, ts, http.MethodGet, "/resources?kind=server", "", "", &inv)
+	if len(inv.Resources) != 0 {
+		t.Errorf("?kind=server: want nothing, got %+v", inv.Resources)
+	}
+	// an empty list must not stand in for a cloud the run never looked at
+	if resp := call(t, ts, http.MethodGet, "/resources?cloud=aws", "", "", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("?cloud=aws after a fake-only run: want 409, got %d", resp.StatusCode)
+	}
+}
+
+// TestAPI_ResourcesNamesListingFailures pins that kinds the run could not
+// list in full are called out next to the resources.
+func TestAPI_ResourcesNamesListingFailures(t *testing.T) {
+	exec := partialExecutor{&fakeExecutor{}}
+	d, ts := apiDaemon(t, exec, "")
+	runScheduled(t, d, context.Background())
+
+	var inv inventory
+	call(t, ts, http.MethodGet, "/resources", "", "", &inv)
+	if inv.ExitCode != exitListingFailed || len(inv.ListingFailures) != 1 {
+		t.Fatalf("want one listing failure, got %+v", inv)
+	}
+	if failure := inv.ListingFailures[0]; failure.Kind != kindVolume || len(failure.FailedRegions) != 1 || failure.FailedRegions[0] != "eu-west-1" {
+		t.Errorf("want the volume listing's failed region, got %+v", failure)
+	}
+}
@@ -0,0 +1,9 @@
+package core
+
+// Account is one set of credentials a cloud pass runs under, e.g. an AWS
+// role assumed via STS. ID labels the pass's output and report records; it
+// is empty when the pass runs under the base credentials unlabelled.
+type Account struct {
+	ID   string
+	Role string // provider role assumed for the pass; empty for base credentials
+}
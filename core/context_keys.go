@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 )
 
 // ctxKey is a package-private typed context key to prevent collisions with
@@ -18,6 +19,20 @@ const (
 	DOPatKey              ctxKey = "JANITOR_DO_PAT"
 	AWSAccessKeyIDKey     ctxKey = "JANITOR_AWS_ACCESS_KEY_ID"
 	AWSSecretAccessKeyKey ctxKey = "JANITOR_AWS_SECRET_ACCESS_KEY"
+	// AWSRegionConcurrencyKey holds the int from --aws-region-concurrency:
+	// how many regions the AWS executor scans at once.
+	AWSRegionConcurrencyKey ctxKey = "JANITOR_AWS_REGION_CONCURRENCY"
+	// AWSRegionsKey holds the --aws-regions string: comma-separated regions
+	// to scan, where a leading "-" excludes a region instead.
+	AWSRegionsKey ctxKey = "JANITOR_AWS_REGIONS"
+	// AWSProfileKey names the shared-config profile for the base AWS
+	// credentials. AWSRoleARNsKey ([]string) lists roles assumed on top of
+	// them, one janitor pass each, with AWSExternalIDKey and
+	// AWSRoleSessionNameKey passed to sts:AssumeRole.
+	AWSProfileKey         ctxKey = "JANITOR_AWS_PROFILE"
+	AWSRoleARNsKey        ctxKey = "JANITOR_AWS_ROLE_ARNS"
+	AWSExternalIDKey      ctxKey = "JANITOR_AWS_EXTERNAL_ID"
+	AWSRoleSessionNameKey ctxKey = "JANITOR_AWS_ROLE_SESSION_NAME"
 	VultrPatKey           ctxKey = "JANITOR_VULTR_PAT"
 	HetznerPatKey         ctxKey = "JANITOR_HETZNER_PAT"
 
@@ -31,6 +46,14 @@ const (
 	// current cloud so helper funcs in main.go can pull it back out.
 	ExecutorKey ctxKey = "executor"
 
+	// CloudKey holds the --clouds token (e.g. "aws") currently being swept so
+	// the policy engine can scope rules per cloud.
+	CloudKey ctxKey = "janitor-cloud"
+
+	// AccountKey holds the Account the current pass runs under. executors
+	// that sweep several accounts (AWS roles) pick credentials by it.
+	AccountKey ctxKey = "janitor-account"
+
 	// WarnWriterKey optionally holds an io.Writer that executors use to surface
 	// non-fatal warnings (e.g. unparseable Created timestamp). populated by
 	// main from the `out` sink; absent in tests → warnings are silently dropped.
@@ -40,6 +63,10 @@ const (
 	// used by mock markers and progress lines. populated by main from the same
 	// `out` sink; tests may set it to a buffer to assert on printed output.
 	OutWriterKey ctxKey = "janitor-out-writer"
+
+	// APIObserverKey optionally holds an APIObserver that executors report
+	// every provider API call to. populated by main when metrics are served.
+	APIObserverKey ctxKey = "janitor-api-observer"
 )
 
 // TagKeyC66Stack is the canonical cloud66 stack tag key. matched
@@ -93,3 +120,23 @@ func Writef(ctx context.Context, format string, args ...interface{}) {
 	// best-effort write — see Warnf comment for rationale.
 	_, _ = fmt.Fprintf(w, format, args...)
 }
+
+// APIObserver receives the latency and outcome of provider API calls.
+// operation is a low-cardinality name such as "EC2 DescribeInstances" or
+// "GET droplets"; err is nil for a successful call.
+type APIObserver interface {
+	ObserveAPICall(cloud, operation string, took time.Duration, err error)
+}
+
+// ObserveAPICall reports a provider API call to the APIObserver stored under
+// APIObserverKey on ctx. like Warnf it is a no-op when none is set.
+func ObserveAPICall(ctx context.Context, cloud, operation string, took time.Duration, err error) {
+	if ctx == nil {
+		return
+	}
+	observer, ok := ctx.Value(APIObserverKey).(APIObserver)
+	if !ok || observer == nil {
+		return
+	}
+	observer.ObserveAPICall(cloud, operation, took, err)
+}
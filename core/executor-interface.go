@@ -8,10 +8,22 @@ type ExecutorInterface interface {
 	ServerDelete(ctx context.Context, server Server) error
 	ServerStop(ctx context.Context, server Server) error
 	ServerStart(ctx context.Context, server Server) error
+	ServerMark(ctx context.Context, server Server, value string) error
 	LoadBalancersGet(ctx context.Context, flagMock bool) ([]LoadBalancer, error)
 	LoadBalancerDelete(ctx context.Context, loadBalancer LoadBalancer) error
+	LoadBalancerMark(ctx context.Context, loadBalancer LoadBalancer, value string) error
 	SshKeysGet(ctx context.Context) ([]SshKey, error)
 	SshKeyDelete(ctx context.Context, sshKey SshKey) error
 	VolumesGet(ctx context.Context) ([]Volume, error)
 	VolumeDelete(ctx context.Context, volume Volume) error
+	VolumeMark(ctx context.Context, volume Volume, value string) error
+	SnapshotsGet(ctx context.Context) ([]Snapshot, error)
+	SnapshotDelete(ctx context.Context, snapshot Snapshot) error
+	IPAddressesGet(ctx context.Context) ([]IPAddress, error)
+	IPAddressRelease(ctx context.Context, ipAddress IPAddress) error
+	TargetGroupsGet(ctx context.Context) ([]TargetGroup, error)
+	TargetGroupMark(ctx context.Context, targetGroup TargetGroup, value string) error
+	TargetGroupDelete(ctx context.Context, targetGroup TargetGroup) error
+	SkippedRegionsGet(ctx context.Context) ([]SkippedRegion, error)
+	AccountsGet(ctx context.Context) ([]Account, error)
 }
@@ -28,6 +28,10 @@ func (e *Executor) ServerStart(ctx context.Context, server Server) error {
 	return ErrUnsupported
 }
 
+func (e *Executor) ServerMark(ctx context.Context, server Server, value string) error {
+	return ErrUnsupported
+}
+
 func (e *Executor) LoadBalancersGet(ctx context.Context, flagMock bool) ([]LoadBalancer, error) {
 	return nil, ErrUnsupported
 }
@@ -36,6 +40,10 @@ func (e *Executor) LoadBalancerDelete(ctx context.Context, loadBalancer LoadBala
 	return ErrUnsupported
 }
 
+func (e *Executor) LoadBalancerMark(ctx context.Context, loadBalancer LoadBalancer, value string) error {
+	return ErrUnsupported
+}
+
 func (e *Executor) SshKeysGet(ctx context.Context) ([]SshKey, error) {
 	return nil, ErrUnsupported
 }
@@ -51,3 +59,43 @@ func (e *Executor) VolumesGet(ctx context.Context) ([]Volume, error) {
 func (e *Executor) VolumeDelete(ctx context.Context, volume Volume) error {
 	return ErrUnsupported
 }
+
+func (e *Executor) VolumeMark(ctx context.Context, volume Volume, value string) error {
+	return ErrUnsupported
+}
+
+func (e *Executor) SnapshotsGet(ctx context.Context) ([]Snapshot, error) {
+	return nil, ErrUnsupported
+}
+
+func (e *Executor) SnapshotDelete(ctx context.Context, snapshot Snapshot) error {
+	return ErrUnsupported
+}
+
+func (e *Executor) IPAddressesGet(ctx context.Context) ([]IPAddress, error) {
+	return nil, ErrUnsupported
+}
+
+func (e *Executor) IPAddressRelease(ctx context.Context, ipAddress IPAddress) error {
+	return ErrUnsupported
+}
+
+func (e *Executor) TargetGroupsGet(ctx context.Context) ([]TargetGroup, error) {
+	return nil, ErrUnsupported
+}
+
+func (e *Executor) TargetGroupMark(ctx context.Context, targetGroup TargetGroup, value string) error {
+	return ErrUnsupported
+}
+
+func (e *Executor) TargetGroupDelete(ctx context.Context, targetGroup TargetGroup) error {
+	return ErrUnsupported
+}
+
+func (e *Executor) SkippedRegionsGet(ctx context.Context) ([]SkippedRegion, error) {
+	return nil, ErrUnsupported
+}
+
+func (e *Executor) AccountsGet(ctx context.Context) ([]Account, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,21 @@
+package core
+
+// IPAddress represents a reserved / floating / elastic public IP. providers
+// without a creation time (AWS, DigitalOcean, Vultr) leave Age at 0.
+type IPAddress struct {
+	VendorID string
+	Address  string
+	Name     string
+	Age      float64 // age in days; 0 when the provider does not expose it
+	Region   string
+	Type     string   // provider-specific flavour, e.g. Hetzner "floating" / "primary"
+	Assigned bool     // true if the address is bound to an instance or interface
+	Tags     []string // normalized as "key=value" strings across all clouds
+}
+
+// IPAddressSorter sorts addresses by age (oldest first)
+type IPAddressSorter []IPAddress
+
+func (s IPAddressSorter) Len() int           { return len(s) }
+func (s IPAddressSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s IPAddressSorter) Less(i, j int) bool { return s[i].Age > s[j].Age }
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarkTagKey is the tag / label key quarantine mode writes on a resource it
+// has scheduled for deletion. the value is produced by SignMark.
+const MarkTagKey = "janitor-scheduled-for-deletion"
+
+// markMACBytes is how much of the HMAC-SHA256 is kept. 16 bytes (32 hex
+// chars) keeps the full value at 43 chars — inside Hetzner's 63-char label
+// limit — while leaving forgery at 2^-128.
+const markMACBytes = 16
+
+// SignMark returns the mark value for a resource of kind with vendorID
+// scheduled at `at`: "<unix seconds>-<hex mac>". the MAC covers the kind and
+// vendor ID as well as the timestamp, so a valid mark copied from one
+// resource onto another does not verify.
+func SignMark(key []byte, kind, vendorID string, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return ts + "-" + hex.EncodeToString(markMAC(key, kind, vendorID, ts))
+}
+
+// VerifyMark checks value against the signing key and returns the time the
+// resource was marked. anything malformed, signed with another key, bound to
+// another resource or dated in the future returns an error — the caller must
+// then treat the resource as unmarked.
+func VerifyMark(key []byte, kind, vendorID, value string, now time.Time) (time.Time, error) {
+	ts, macHex, ok := strings.Cut(value, "-")
+	if !ok {
+		return time.Time{}, fmt.Errorf("malformed mark %q", value)
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || unix <= 0 {
+		return time.Time{}, fmt.Errorf("malformed mark timestamp %q", ts)
+	}
+	got, err := hex.DecodeString(macHex)
+	if err != nil || len(got) != markMACBytes {
+		return time.Time{}, fmt.Errorf("malformed mark signature %q", macHex)
+	}
+	// constant-time compare: the mark value is attacker-writable.
+	if !hmac.Equal(got, markMAC(key, kind, vendorID, ts)) {
+		return time.Time{}, fmt.Errorf("mark signature does not verify")
+	}
+	at := time.Unix(unix, 0)
+	if at.After(now) {
+		return time.Time{}, fmt.Errorf("mark is dated in the future (%s)", at.UTC().Format(time.RFC3339))
+	}
+	return at, nil
+}
+
+// FindMarks returns every mark value in tags. both the normalized
+// "key=value" form and DigitalOcean's "key:value" tag form are recognised; a
+// resource can carry more than one when an earlier mark failed to verify.
+func FindMarks(tags []string) []string {
+	var values []string
+	for _, tag := range tags {
+		for _, sep := range []string{"=", ":"} {
+			if value, ok := strings.CutPrefix(tag, MarkTagKey+sep); ok {
+				values = append(values, value)
+				break
+			}
+		}
+	}
+	return values
+}
+
+func markMAC(key []byte, kind, vendorID, ts string) []byte {
+	mac := hmac.New(sha256.New, key)
+	// NUL separators: none of the fields can contain one, so distinct
+	// (kind, id, ts) tuples can never serialize to the same input.
+	mac.Write([]byte(kind + "\x00" + vendorID + "\x00" + ts))
+	return mac.Sum(nil)[:markMACBytes]
+}
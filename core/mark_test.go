@@ -0,0 +1,77 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMark_SignVerifyRoundTrip pins the value shape and that a fresh mark
+// verifies back to its own timestamp.
+func TestMark_SignVerifyRoundTrip(t *testing.T) {
+	t.Parallel()
+	key := []byte("0123456789abcdef")
+	at := time.Unix(1700000000, 0)
+	value := SignMark(key, "server", "i-123", at)
+
+	if !strings.HasPrefix(value, "1700000000-") || len(value) != 43 {
+		t.Fatalf("unexpected mark shape %q (len %d)", value, len(value))
+	}
+	got, err := VerifyMark(key, "server", "i-123", value, at.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !got.Equal(at) {
+		t.Errorf("want %v, got %v", at, got)
+	}
+}
+
+// TestMark_VerifyRejects covers the forgery cases the old unsigned MARKED.AT
+// tag was open to.
+func TestMark_VerifyRejects(t *testing.T) {
+	t.Parallel()
+	key := []byte("0123456789abcdef")
+	at := time.Unix(1700000000, 0)
+	now := at.Add(48 * time.Hour)
+	valid := SignMark(key, "server", "i-123", at)
+	_, mac, _ := strings.Cut(valid, "-")
+
+	tests := []struct {
+		desc     string
+		key      []byte
+		kind, id string
+		value    string
+	}{
+		{"other key", []byte("another-signing-key"), "server", "i-123", valid},
+		{"copied to another resource", key, "server", "i-456", valid},
+		{"copied to another kind", key, "volume", "i-123", valid},
+		{"backdated timestamp", key, "server", "i-123", "1600000000-" + mac},
+		{"future timestamp", key, "server", "i-123", SignMark(key, "server", "i-123", now.Add(time.Hour))},
+		{"no separator", key, "server", "i-123", "1700000000"},
+		{"non-hex signature", key, "server", "i-123", "1700000000-zz"},
+		{"truncated signature", key, "server", "i-123", valid[:len(valid)-2]},
+		{"bare timestamp like MARKED.AT", key, "server", "i-123", "1700000000-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+			if _, err := VerifyMark(tt.key, tt.kind, tt.id, tt.value, now); err == nil {
+				t.Errorf("want rejection of %q", tt.value)
+			}
+		})
+	}
+}
+
+func TestFindMarks(t *testing.T) {
+	t.Parallel()
+	got := FindMarks([]string{
+		"team=qa",
+		MarkTagKey + "=1-aa",
+		MarkTagKey + ":2-bb", // DigitalOcean tag form
+		MarkTagKey,           // bare tag carries no value
+		"x-" + MarkTagKey + "=3-cc",
+	})
+	if len(got) != 2 || got[0] != "1-aa" || got[1] != "2-bb" {
+		t.Errorf("want [1-aa 2-bb], got %v", got)
+	}
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SkippedRegion is a region an executor left out of the run, e.g. one the
+// account has not opted in to. it is reported once per run instead of as an
+// error from every service scanned in it.
+type SkippedRegion struct {
+	Region string
+	Reason string
+}
+
+// PartialError is returned by a listing that failed in some regions but not
+// all of them, alongside the results of the regions that answered. callers
+// sweep those results and still count the run as incomplete.
+type PartialError struct {
+	// Regions names the regions that failed, in scan order.
+	Regions []string
+	Err     error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d region(s) failed: %s", len(e.Regions), e.Err.Error())
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// IsPartial reports whether err is (or wraps) a *PartialError.
+func IsPartial(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial)
+}
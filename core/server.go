@@ -8,8 +8,18 @@ type Server struct {
 	Tags     []string
 	Region   string
 	State    string // "RUNNING|TERMINATED"
+	// AgeSource names the timestamp Age was measured from, for providers
+	// with more than one candidate (AgeSourceLaunch, AgeSourceAttach).
+	// empty when the provider has a single creation time.
+	AgeSource string
 }
 
+// AgeSource values.
+const (
+	AgeSourceLaunch = "launch time"
+	AgeSourceAttach = "volume attach time"
+)
+
 // ServerSorter sorts servers by age.
 type ServerSorter []Server
 
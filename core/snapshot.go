@@ -0,0 +1,26 @@
+package core
+
+// snapshot types. AWS distinguishes EBS snapshots from the AMIs built on
+// them; every other provider only has one kind of saved image.
+const (
+	SnapshotTypeSnapshot = "snapshot"
+	SnapshotTypeImage    = "image"
+)
+
+// Snapshot represents a disk snapshot or a custom machine image
+type Snapshot struct {
+	VendorID string
+	Name     string
+	Age      float64 // age in days
+	Region   string
+	Type     string   // SnapshotTypeSnapshot or SnapshotTypeImage
+	InUse    bool     // true if a registered image still references it
+	Tags     []string // normalized as "key=value" strings across all clouds
+}
+
+// SnapshotSorter sorts snapshots by age (oldest first)
+type SnapshotSorter []Snapshot
+
+func (s SnapshotSorter) Len() int           { return len(s) }
+func (s SnapshotSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s SnapshotSorter) Less(i, j int) bool { return s[i].Age > s[j].Age }
@@ -1,14 +1,34 @@
 package core
 
+import "time"
+
 type SshKey struct {
 	VendorID string
 	Name     string
+	Region   string    // empty for account-wide keys (DigitalOcean)
+	Created  time.Time // zero when the provider doesn't expose it
+	Age      float64   // days since Created; 0 when Created is unknown
+	Tags     []string  // normalized as "key=value" strings; nil where keys carry none
 }
 
-// SshKeySorter sorts SSH keys by VendorID ascending (lexicographic string compare).
-// B2: this is a lex sort, not a numeric one — "v10" < "v2".
+// SshKeySorter groups SSH keys by Region, then orders each region oldest
+// first: keys without a creation time lead (unknown age is treated as old),
+// timestamped keys follow by Created, and VendorID breaks ties.
+// B2: the VendorID comparison is a lex sort, not a numeric one — "v10" < "v2".
 type SshKeySorter []SshKey
 
-func (s SshKeySorter) Len() int           { return len(s) }
-func (s SshKeySorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-func (s SshKeySorter) Less(i, j int) bool { return s[i].VendorID < s[j].VendorID }
+func (s SshKeySorter) Len() int      { return len(s) }
+func (s SshKeySorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s SshKeySorter) Less(i, j int) bool {
+	if s[i].Region != s[j].Region {
+		return s[i].Region < s[j].Region
+	}
+	iTimed, jTimed := !s[i].Created.IsZero(), !s[j].Created.IsZero()
+	if iTimed != jTimed {
+		return !iTimed
+	}
+	if !s[i].Created.Equal(s[j].Created) {
+		return s[i].Created.Before(s[j].Created)
+	}
+	return s[i].VendorID < s[j].VendorID
+}
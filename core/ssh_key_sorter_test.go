@@ -3,6 +3,7 @@ package core
 import (
 	"sort"
 	"testing"
+	"time"
 )
 
 // TestSshKeySorter_Ascending asserts ascending VendorID (string) sort.
@@ -44,4 +45,22 @@ func TestSshKeySorter_Ascending(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("region then creation time", func(t *testing.T) {
+		t.Parallel()
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		in := []SshKey{
+			{VendorID: "key-a", Region: "us-east-1", Created: base.Add(2 * time.Hour)},
+			{VendorID: "key-b", Region: "eu-west-1", Created: base},
+			{VendorID: "key-c", Region: "us-east-1", Created: base},
+			{VendorID: "key-d", Region: "us-east-1"}, // no timestamp → treated as oldest
+		}
+		sort.Sort(SshKeySorter(in))
+		want := []string{"key-b", "key-d", "key-c", "key-a"}
+		for i, k := range in {
+			if k.VendorID != want[i] {
+				t.Errorf("pos %d: got %q want %q", i, k.VendorID, want[i])
+			}
+		}
+	})
 }
@@ -0,0 +1,27 @@
+package core
+
+// TargetGroup is a load balancer target group no load balancer references.
+// target groups carry no creation time, so janitor only ever deletes one
+// through the quarantine mark: the signed mark is its only clock.
+type TargetGroup struct {
+	VendorID string // the target group ARN
+	Name     string
+	Region   string
+	Tags     []string // normalized as "key=value" strings across all clouds
+}
+
+// TargetGroupSorter groups target groups by Region, then orders each region
+// by Name, VendorID breaking ties.
+type TargetGroupSorter []TargetGroup
+
+func (s TargetGroupSorter) Len() int      { return len(s) }
+func (s TargetGroupSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s TargetGroupSorter) Less(i, j int) bool {
+	if s[i].Region != s[j].Region {
+		return s[i].Region < s[j].Region
+	}
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].VendorID < s[j].VendorID
+}
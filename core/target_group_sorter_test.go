@@ -0,0 +1,26 @@
+package core
+
+import (
+	"sort"
+	"testing"
+)
+
+// TestTargetGroupSorter_RegionThenName asserts region, then name, then
+// VendorID ordering.
+func TestTargetGroupSorter_RegionThenName(t *testing.T) {
+	t.Parallel()
+
+	in := []TargetGroup{
+		{VendorID: "arn:3", Name: "web", Region: "us-east-1"},
+		{VendorID: "arn:2", Name: "api", Region: "us-east-1"},
+		{VendorID: "arn:4", Name: "web", Region: "eu-west-1"},
+		{VendorID: "arn:1", Name: "api", Region: "us-east-1"},
+	}
+	sort.Sort(TargetGroupSorter(in))
+	want := []string{"arn:4", "arn:1", "arn:2", "arn:3"}
+	for i, tg := range in {
+		if tg.VendorID != want[i] {
+			t.Errorf("pos %d: got %q want %q", i, tg.VendorID, want[i])
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloud66/janitor/core"
+	"go.yaml.in/yaml/v2"
+)
+
+// credentialsFile is the --credentials-file document: named provider
+// accounts, selected with --clouds=<cloud>:<name> (e.g. hetzner:ci).
+type credentialsFile struct {
+	Accounts []namedAccount `json:"accounts" yaml:"accounts"`
+}
+
+// namedAccount is one entry of the credentials file. Token serves
+// digitalocean, vultr and hetzner; aws takes a key pair or a profile. the
+// age overrides replace --max-age-regular / --max-age-long /
+// --max-age-snapshot for this account only.
+type namedAccount struct {
+	Cloud              string   `json:"cloud" yaml:"cloud"`
+	Name               string   `json:"name" yaml:"name"`
+	Token              string   `json:"token,omitempty" yaml:"token,omitempty"`
+	AWSAccessKeyID     string   `json:"aws_access_key_id,omitempty" yaml:"aws_access_key_id,omitempty"`
+	AWSSecretAccessKey string   `json:"aws_secret_access_key,omitempty" yaml:"aws_secret_access_key,omitempty"`
+	AWSProfile         string   `json:"aws_profile,omitempty" yaml:"aws_profile,omitempty"`
+	MaxAgeRegular      *float64 `json:"max_age_regular,omitempty" yaml:"max_age_regular,omitempty"`
+	MaxAgeLong         *float64 `json:"max_age_long,omitempty" yaml:"max_age_long,omitempty"`
+	MaxAgeSnapshot     *float64 `json:"max_age_snapshot,omitempty" yaml:"max_age_snapshot,omitempty"`
+}
+
+// cloud names a credentials file entry may use; they mirror the clouds map
+// built in main.
+var credentialClouds = map[string]bool{"aws": true, "digitalocean": true, "vultr": true, "hetzner": true}
+
+// loadCredentials reads and validates a credentials file. like --policy it
+// is YAML unless the name ends in .json, and unknown fields are rejected.
+func loadCredentials(path string) (*credentialsFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c credentialsFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	} else if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *credentialsFile) validate() error {
+	seen := map[string]bool{}
+	for i, account := range c.Accounts {
+		if !credentialClouds[account.Cloud] {
+			return fmt.Errorf("account %d: unknown cloud %q", i+1, account.Cloud)
+		}
+		if account.Name == "" || strings.ContainsAny(account.Name, ":,") {
+			return fmt.Errorf("account %d: name must be non-empty and free of ':' and ','", i+1)
+		}
+		token := account.Cloud + ":" + account.Name
+		if seen[token] {
+			return fmt.Errorf("account %d: duplicate account %s", i+1, token)
+		}
+		seen[token] = true
+		if account.Cloud == "aws" {
+			if account.Token != "" {
+				return fmt.Errorf("account %s: aws takes aws_access_key_id / aws_secret_access_key or aws_profile, not token", token)
+			}
+			if (account.AWSAccessKeyID == "") != (account.AWSSecretAccessKey == "") {
+				return fmt.Errorf("account %s: aws_access_key_id and aws_secret_access_key go together", token)
+			}
+			if account.AWSAccessKeyID != "" && account.AWSProfile != "" {
+				return fmt.Errorf("account %s: aws_profile cannot be combined with an access key", token)
+			}
+		} else {
+			if account.Token == "" {
+				return fmt.Errorf("account %s: token is required", token)
+			}
+			if account.AWSAccessKeyID != "" || account.AWSSecretAccessKey != "" || account.AWSProfile != "" {
+				return fmt.Errorf("account %s: aws_* fields only apply to aws accounts", token)
+			}
+		}
+		for _, age := range []*float64{account.MaxAgeRegular, account.MaxAgeLong, account.MaxAgeSnapshot} {
+			if age != nil && *age <= 0 {
+				return fmt.Errorf("account %s: age overrides must be positive", token)
+			}
+		}
+	}
+	return nil
+}
+
+// account returns the entry for cloud:name.
+func (c *credentialsFile) account(cloud, name string) (namedAccount, bool) {
+	if c != nil {
+		for _, account := range c.Accounts {
+			if account.Cloud == cloud && account.Name == name {
+				return account, true
+			}
+		}
+	}
+	return namedAccount{}, false
+}
+
+// splitCloudToken splits a --clouds entry into the cloud and the optional
+// credentials file account name: "hetzner:ci" → ("hetzner", "ci").
+func splitCloudToken(token string) (cloud, name string) {
+	cloud, name, _ = strings.Cut(token, ":")
+	return cloud, name
+}
+
+// checkCloudAccounts verifies every named --clouds entry has a credentials
+// file account, so a typo fails the run before any pass starts rather than
+// halfway through.
+func checkCloudAccounts(tokens []string, c *credentialsFile) error {
+	for _, token := range tokens {
+		cloud, name := splitCloudToken(token)
+		if name == "" {
+			continue
+		}
+		if c == nil {
+			return fmt.Errorf("%s names an account but no --credentials-file was given", token)
+		}
+		if _, ok := c.account(cloud, name); !ok {
+			return fmt.Errorf("%s is not in the credentials file", token)
+		}
+	}
+	return nil
+}
+
+// withCredentials swaps the account's credentials into ctx in place of the
+// --*-pat / --aws-* flag values.
+func (n namedAccount) withCredentials(ctx context.Context) context.Context {
+	switch n.Cloud {
+	case "digitalocean":
+		ctx = context.WithValue(ctx, core.DOPatKey, n.Token)
+	case "vultr":
+		ctx = context.WithValue(ctx, core.VultrPatKey, n.Token)
+	case "hetzner":
+		ctx = context.WithValue(ctx, core.HetznerPatKey, n.Token)
+	case "aws":
+		ctx = context.WithValue(ctx, core.AWSAccessKeyIDKey, n.AWSAccessKeyID)
+		ctx = context.WithValue(ctx, core.AWSSecretAccessKeyKey, n.AWSSecretAccessKey)
+		ctx = context.WithValue(ctx, core.AWSProfileKey, n.AWSProfile)
+	}
+	return ctx
+}
+
+// ageLimits is base with the account's age overrides applied.
+func (n namedAccount) ageLimits(base ageLimits) ageLimits {
+	if n.MaxAgeRegular != nil {
+		base.Normal = *n.MaxAgeRegular
+	}
+	if n.MaxAgeLong != nil {
+		base.Long = *n.MaxAgeLong
+	}
+	if n.MaxAgeSnapshot != nil {
+		base.Snapshot = *n.MaxAgeSnapshot
+	}
+	return base
+}
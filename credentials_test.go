@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloud66/janitor/core"
+)
+
+func writeCredentials(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCredentials_YAML(t *testing.T) {
+	path := writeCredentials(t, "accounts.yml", `
+accounts:
+  - cloud: hetzner
+    name: ci
+    token: hetzner-ci-token
+    max_age_regular: 0.5
+  - cloud: hetzner
+    name: qa
+    token: hetzner-qa-token
+  - cloud: aws
+    name: demo
+    aws_profile: demo
+`)
+	c, err := loadCredentials(path)
+	if err != nil {
+		t.Fatalf("loadCredentials: %v", err)
+	}
+	ci, ok := c.account("hetzner", "ci")
+	if !ok || ci.Token != "hetzner-ci-token" || ci.MaxAgeRegular == nil || *ci.MaxAgeRegular != 0.5 || ci.MaxAgeLong != nil {
+		t.Errorf("unexpected hetzner:ci entry %+v", ci)
+	}
+	if _, ok := c.account("digitalocean", "ci"); ok {
+		t.Error("accounts are scoped by cloud")
+	}
+}
+
+func TestLoadCredentials_JSONRejectsUnknownFields(t *testing.T) {
+	path := writeCredentials(t, "accounts.json", `{"accounts":[{"cloud":"vultr","name":"ci","token":"t","max_age":1}]}`)
+	if _, err := loadCredentials(path); err == nil {
+		t.Fatal("want unknown fields rejected")
+	}
+}
+
+func TestCredentialsFile_Validate(t *testing.T) {
+	half := 0.5
+	zero := 0.0
+	cases := []struct {
+		name    string
+		account namedAccount
+		wantErr string
+	}{
+		{"valid token account", namedAccount{Cloud: "digitalocean", Name: "demo", Token: "t", MaxAgeLong: &half}, ""},
+		{"valid aws key pair", namedAccount{Cloud: "aws", Name: "ci", AWSAccessKeyID: "AKID", AWSSecretAccessKey: "s"}, ""},
+		{"unknown cloud", namedAccount{Cloud: "linode", Name: "ci", Token: "t"}, "unknown cloud"},
+		{"name with colon", namedAccount{Cloud: "vultr", Name: "c:i", Token: "t"}, "name must be"},
+		{"missing token", namedAccount{Cloud: "hetzner", Name: "ci"}, "token is required"},
+		{"aws with token", namedAccount{Cloud: "aws", Name: "ci", Token: "t"}, "not token"},
+		{"aws half key pair", namedAccount{Cloud: "aws", Name: "ci", AWSAccessKeyID: "AKID"}, "go together"},
+		{"aws fields on hetzner", namedAccount{Cloud: "hetzner", Name: "ci", Token: "t", AWSProfile: "p"}, "only apply to aws"},
+		{"zero age override", namedAccount{Cloud: "hetzner", Name: "ci", Token: "t", MaxAgeRegular: &zero}, "must be positive"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&credentialsFile{Accounts: []namedAccount{tc.account}}).validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("want error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	dup := &credentialsFile{Accounts: []namedAccount{
+		{Cloud: "hetzner", Name: "ci", Token: "a"},
+		{Cloud: "hetzner", Name: "ci", Token: "b"},
+	}}
+	if err := dup.validate(); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("want duplicate accounts rejected, got %v", err)
+	}
+}
+
+func TestCheckCloudAccounts(t *testing.T) {
+	c := &credentialsFile{Accounts: []namedAccount{{Cloud: "hetzner", Name: "ci", Token: "t"}}}
+	if err := checkCloudAccounts([]string{"aws", "hetzner:ci"}, c); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkCloudAccounts([]string{"hetzner:qa"}, c); err == nil {
+		t.Error("want an unknown named account rejected")
+	}
+	if err := checkCloudAccounts([]string{"hetzner:ci"}, nil); err == nil {
+		t.Error("want a named account without a credentials file rejected")
+	}
+	if err := checkCloudAccounts([]string{"aws", "vultr"}, nil); err != nil {
+		t.Errorf("plain providers need no credentials file: %v", err)
+	}
+}
+
+func TestNamedAccount_WithCredentialsAndAgeLimits(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	regular := 0.1
+	named := namedAccount{Cloud: "hetzner", Name: "ci", Token: "ci-token", MaxAgeRegular: &regular}
+	ctx := context.WithValue(context.Background(), core.HetznerPatKey, "flag-token")
+
+	if got, _ := named.withCredentials(ctx).Value(core.HetznerPatKey).(string); got != "ci-token" {
+		t.Errorf("want the account token in ctx, got %q", got)
+	}
+	if limits := named.ageLimits(currentAgeLimits()); limits.Normal != 0.1 || limits.Long != 5.0 {
+		t.Errorf("want only the regular age overridden, got %+v", limits)
+	}
+	if limits := currentAgeLimits(); limits.Normal != 0.38 || limits.Long != 5.0 {
+		t.Errorf("want the flag ages untouched, got %+v", limits)
+	}
+}
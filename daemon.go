@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+	"github.com/robfig/cron/v3"
+)
+
+// status values of a daemon run.
+const (
+	runRunning   = "running"
+	runFinished  = "finished"
+	runCancelled = "cancelled" // SIGTERM stopped it between resources
+)
+
+// shutdownTimeout bounds how long the daemon waits for open HTTP requests
+// once the in-flight run has finished.
+const shutdownTimeout = 10 * time.Second
+
+// parseSchedule reads --schedule: a Go duration ("2h") sweeps on that
+// interval, anything else is a standard five-field cron expression, with
+// descriptors like @hourly, evaluated in local time.
+func parseSchedule(spec string) (cron.Schedule, error) {
+	if spec == "" {
+		return nil, errors.New("--action=daemon requires --schedule")
+	}
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval %s is shorter than a second", spec)
+		}
+		return cron.Every(interval), nil
+	}
+	return cron.ParseStandard(spec)
+}
+
+// runOptions is what one run sweeps: --clouds and --mock for scheduled
+// runs, the request body for POST /runs.
+type runOptions struct {
+	Clouds string
+	Mock   bool
+}
+
+// runReport is one daemon run as served on /runs. Records grow while the
+// run is in flight; Summary is filled in when it ends.
+type runReport struct {
+	ID       int            `json:"id"`
+	Status   string         `json:"status"`
+	Trigger  string         `json:"trigger"`
+	Action   string         `json:"action"`
+	Clouds   string         `json:"clouds"`
+	Mock     bool           `json:"mock"`
+	Started  time.Time      `json:"started"`
+	Finished *time.Time     `json:"finished,omitempty"`
+	ExitCode *int           `json:"exit_code,omitempty"`
+	Summary  []summaryEntry `json:"summary"`
+	Records  []record       `json:"records,omitempty"`
+}
+
+// run triggers.
+const (
+	triggerSchedule = "schedule"
+	triggerAPI      = "api"
+)
+
+// runHistory keeps the last keep run reports, oldest first. it is shared
+// between the sweep goroutine and the HTTP handlers.
+type runHistory struct {
+	mu     sync.Mutex
+	keep   int
+	lastID int
+	runs   []*runReport
+}
+
+// begin records a run starting at started and returns it.
+func (h *runHistory) begin(started time.Time, trigger string, opts runOptions) *runReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	run := &runReport{
+		ID:      h.lastID,
+		Status:  runRunning,
+		Trigger: trigger,
+		Action:  flagAction,
+		Clouds:  opts.Clouds,
+		Mock:    opts.Mock,
+		Started: started,
+		Summary: []summaryEntry{},
+		Records: []record{},
+	}
+	h.runs = append(h.runs, run)
+	if len(h.runs) > h.keep {
+		h.runs = h.runs[len(h.runs)-h.keep:]
+	}
+	return run
+}
+
+// add files rec under the in-flight run.
+func (h *runHistory) add(run *runReport, rec record) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	run.Records = append(run.Records, rec)
+}
+
+// finish stamps run with its outcome and the summary of r, the reporter it
+// ran into.
+func (h *runHistory) finish(run *runReport, r *reporter, code int, status string, finished time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	run.Status = status
+	run.Finished = &finished
+	run.ExitCode = &code
+	run.Summary = r.summary.entries()
+}
+
+// list returns copies of the kept runs, newest first.
+func (h *runHistory) list() []runReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	runs := make([]runReport, 0, len(h.runs))
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		runs = append(runs, *h.runs[i])
+	}
+	return runs
+}
+
+// get returns a copy of run id, if it is still kept.
+func (h *runHistory) get(id int) (runReport, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, run := range h.runs {
+		if run.ID == id {
+			return *run, true
+		}
+	}
+	return runReport{}, false
+}
+
+// latestFinished returns a copy of the newest run that swept to the end.
+func (h *runHistory) latestFinished() (runReport, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		if h.runs[i].Status == runFinished {
+			return *h.runs[i], true
+		}
+	}
+	return runReport{}, false
+}
+
+// daemon sweeps on a schedule and on POST /runs, one run at a time, and
+// serves its state over HTTP.
+type daemon struct {
+	// ctx is cancelled on SIGTERM; runs started over the API sweep under it
+	// like scheduled ones.
+	ctx      context.Context
+	schedule cron.Schedule
+	history  *runHistory
+	metrics  *metrics
+	now      func() time.Time
+	// defaults are the scheduled runs' options, from --clouds and --mock.
+	defaults runOptions
+	// apiToken authorizes live runs over the API; empty disables them.
+	apiToken string
+
+	mu      sync.Mutex
+	running *runReport
+	// inFlight tracks API-started runs so shutdown waits for them.
+	inFlight sync.WaitGroup
+}
+
+// start claims the daemon for a run. when another run is in flight it
+// returns that run and false.
+func (d *daemon) start(trigger string, opts runOptions) (*runReport, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running != nil {
+		return d.running, false
+	}
+	d.running = d.history.begin(d.now(), trigger, opts)
+	return d.running, true
+}
+
+// execute sweeps opts into a fresh report, files the outcome under run and
+// releases the daemon. opts and the report travel on the run's pass, so the
+// flags and the package report stay as main set them.
+func (d *daemon) execute(ctx context.Context, run *runReport, opts runOptions) {
+	defer func() {
+		d.mu.Lock()
+		d.running = nil
+		d.mu.Unlock()
+	}()
+	r := &reporter{
+		format:   flagOutput,
+		w:        os.Stdout,
+		metrics:  d.metrics,
+		progress: func(rec record) { d.history.add(run, rec) },
+	}
+	code := sweep(withPass(ctx, pass{limits: currentAgeLimits(), out: out, run: opts, report: r}))
+	if err := r.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write report: %s\n", err.Error())
+	}
+	d.metrics.finishRun(code)
+	status := runFinished
+	if cancelled(ctx) {
+		status = runCancelled
+	}
+	d.history.finish(run, r, code, status, d.now())
+}
+
+// loop sweeps on the schedule until ctx is cancelled. the next slot is
+// worked out once a run has finished, so slots missed by a long sweep are
+// skipped rather than queued; a slot hit while an API run is in flight is
+// skipped too.
+func (d *daemon) loop(ctx context.Context) {
+	for {
+		next := d.schedule.Next(d.now())
+		fmt.Fprintf(os.Stderr, "next sweep at %s\n", next.Format(time.RFC3339))
+		timer := time.NewTimer(next.Sub(d.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		run, ok := d.start(triggerSchedule, d.defaults)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "skipping scheduled sweep: run %d is still in progress\n", run.ID)
+			continue
+		}
+		d.execute(ctx, run, d.defaults)
+	}
+}
+
+// serveMux adds the daemon's status endpoints and run API to the webserver
+// routes, whose /metrics serves d.metrics.
+func (d *daemon) serveMux() *http.ServeMux {
+	mux := newServeMux(d.metrics)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /runs", d.handleRuns)
+	mux.HandleFunc("POST /runs", d.handleStartRun)
+	mux.HandleFunc("GET /runs/{id}", d.handleRun)
+	mux.HandleFunc("GET /resources", d.handleResources)
+	return mux
+}
+
+// runDaemon serves HTTP and sweeps on schedule until SIGTERM or SIGINT. the
+// signal cancels ctx: the run in flight, scheduled or API-started, stops
+// after the provider call it is making, and the daemon exits once it has. a
+// second signal kills it outright.
+func runDaemon(ctx context.Context, schedule cron.Schedule) int {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	m := runMetrics
+	ctx = context.WithValue(ctx, core.APIObserverKey, core.APIObserver(m))
+	d := &daemon{
+		ctx:      ctx,
+		schedule: schedule,
+		history:  &runHistory{keep: flagKeepRuns},
+		metrics:  m,
+		now:      time.Now,
+		defaults: runOptions{Clouds: flagClouds, Mock: flagMock},
+		apiToken: flagAPIToken,
+	}
+
+	server := &http.Server{Addr: flagListen, Handler: d.serveMux()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	fmt.Fprintf(os.Stderr, "janitor daemon listening on %s, schedule %q\n", flagListen, flagSchedule)
+
+	done := make(chan struct{})
+	go func() {
+		d.loop(ctx)
+		close(done)
+	}()
+
+	select {
+	case err := <-serveErr:
+		fmt.Fprintf(os.Stderr, "Cannot serve HTTP: %s\n", err.Error())
+		stop()
+		<-done
+		d.inFlight.Wait()
+		return 1
+	case <-done:
+	}
+	d.inFlight.Wait()
+	fmt.Fprintln(os.Stderr, "janitor daemon shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+	return exitClean
+}
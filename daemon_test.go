@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2026, 3, 1, 10, 17, 0, 0, time.Local)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"2h", from.Add(2 * time.Hour)},
+		{"0 */2 * * *", time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)},
+		{"@hourly", time.Date(2026, 3, 1, 11, 0, 0, 0, time.Local)},
+		{"30 6 * * 1", time.Date(2026, 3, 2, 6, 30, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		schedule, err := parseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("parseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("parseSchedule(%q).Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+	for _, spec := range []string{"", "every two hours", "0 */2 * *", "500ms", "-1h"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("parseSchedule(%q): want an error", spec)
+		}
+	}
+}
+
+func TestRunHistory_KeepsLastN(t *testing.T) {
+	h := &runHistory{keep: 2}
+	for i := 0; i < 3; i++ {
+		run := h.begin(time.Unix(int64(i), 0), triggerSchedule, runOptions{})
+		h.finish(run, &reporter{}, exitClean, runFinished, time.Unix(int64(i), 1))
+	}
+	runs := h.list()
+	if len(runs) != 2 || runs[0].ID != 3 || runs[1].ID != 2 {
+		t.Fatalf("want runs 3 and 2, newest first, got %+v", runs)
+	}
+	if runs[0].Status != runFinished || runs[0].ExitCode == nil || *runs[0].ExitCode != exitClean {
+		t.Errorf("want a finished run with exit code 0, got %+v", runs[0])
+	}
+}
+
+// withDaemonCloud points the sweep at exec as the only --clouds entry of a
+// delete run.
+func withDaemonCloud(t *testing.T, exec core.ExecutorInterface) {
+	t.Helper()
+	prevClouds, prevFlagClouds, prevAction, prevOutput := clouds, flagClouds, flagAction, flagOutput
+	prevReport := report
+	clouds = map[string]core.ExecutorInterface{"fake": exec}
+	flagClouds, flagAction, flagOutput = "fake", actionDelete, outputText
+	t.Cleanup(func() {
+		clouds, flagClouds, flagAction, flagOutput = prevClouds, prevFlagClouds, prevAction, prevOutput
+		report = prevReport
+	})
+}
+
+// newTestDaemon builds a daemon whose scheduled runs sweep the current
+// --clouds and --mock.
+func newTestDaemon(ctx context.Context, keep int) *daemon {
+	return &daemon{
+		ctx:      ctx,
+		history:  &runHistory{keep: keep},
+		metrics:  newMetrics(),
+		now:      time.Now,
+		defaults: runOptions{Clouds: flagClouds, Mock: flagMock},
+	}
+}
+
+// runScheduled runs one scheduled sweep synchronously.
+func runScheduled(t *testing.T, d *daemon, ctx context.Context) {
+	t.Helper()
+	run, ok := d.start(triggerSchedule, d.defaults)
+	if !ok {
+		t.Fatalf("run %d is still in progress", run.ID)
+	}
+	d.execute(ctx, run, d.defaults)
+}
+
+func TestDaemon_RunFilesReport(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	exec := &volumeExecutor{fakeExecutor: &fakeExecutor{}, volumes: []core.Volume{
+		{VendorID: "vol-old", Name: "old", Age: 3, Region: "nyc1"},
+		{VendorID: "vol-used", Name: "used", Age: 3, Region: "nyc1", Attached: true},
+	}}
+	withDaemonCloud(t, exec)
+	d := newTestDaemon(context.Background(), 5)
+
+	captureOutput(t, func() { runScheduled(t, d, context.Background()) })
+
+	runs := d.history.list()
+	if len(runs) != 1 {
+		t.Fatalf("want one run, got %d", len(runs))
+	}
+	run := runs[0]
+	if run.Status != runFinished || run.ExitCode == nil || *run.ExitCode != exitClean || run.Finished == nil {
+		t.Errorf("want a clean finished run, got %+v", run)
+	}
+	if len(run.Records) != 2 || run.Records[0].Result != resultMock || run.Records[1].Result != resultSkipped {
+		t.Errorf("want a mock delete and a kept attached volume, got %+v", run.Records)
+	}
+	var volumes *summaryEntry
+	for i := range run.Summary {
+		if run.Summary[i].Kind == kindVolume {
+			volumes = &run.Summary[i]
+		}
+	}
+	if volumes == nil || volumes.Scanned != 2 || volumes.Mock != 1 {
+		t.Errorf("want the volume row in the summary, got %+v", run.Summary)
+	}
+	if !strings.Contains(scrape(t, d.metrics), "janitor_last_run_exit_code 0\n") {
+		t.Error("want the run published to /metrics")
+	}
+}
+
+// TestDaemon_ServesItsOwnMetrics pins that /metrics on the daemon's mux
+// publishes the runs that daemon swept, not an empty registry.
+func TestDaemon_ServesItsOwnMetrics(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	exec := &volumeExecutor{fakeExecutor: &fakeExecutor{}, volumes: []core.Volume{
+		{VendorID: "vol-old", Name: "old", Age: 3, Region: "nyc1"},
+	}}
+	withDaemonCloud(t, exec)
+	d := newTestDaemon(context.Background(), 5)
+	d.metrics.now = func() time.Time { return time.Unix(1700000000, 0) }
+	captureOutput(t, func() { runScheduled(t, d, context.Background()) })
+
+	ts := httptest.NewServer(d.serveMux())
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading /metrics: %v", err)
+	}
+	wantLines(t, string(b),
+		`janitor_resources{account="",cloud="fake",decision="delete",kind="volume",region="nyc1"} 1`,
+		`janitor_last_success_timestamp_seconds 1.7e+09`,
+	)
+}
+
+// cancellingExecutor cancels the run from inside its first volume deletion,
+// as a SIGTERM arriving mid-delete would.
+type cancellingExecutor struct {
+	*volumeExecutor
+	cancel  context.CancelFunc
+	callErr []error
+}
+
+func (c *cancellingExecutor) VolumeDelete(ctx context.Context, v core.Volume) error {
+	c.cancel()
+	// the deletion in flight must not see the cancellation
+	c.callErr = append(c.callErr, ctx.Err())
+	return c.volumeExecutor.VolumeDelete(ctx, v)
+}
+
+func TestDaemon_CancelFinishesInFlightDeletion(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec := &cancellingExecutor{cancel: cancel, volumeExecutor: &volumeExecutor{fakeExecutor: &fakeExecutor{}, volumes: []core.Volume{
+		{VendorID: "vol-1", Name: "one", Age: 3, Region: "nyc1"},
+		{VendorID: "vol-2", Name: "two", Age: 3, Region: "nyc1"},
+	}}}
+	withDaemonCloud(t, exec)
+	d := newTestDaemon(ctx, 5)
+
+	text := captureOutput(t, func() { runScheduled(t, d, ctx) })
+
+	if len(exec.deletedVolumes) != 1 || exec.deletedVolumes[0].VendorID != "vol-1" {
+		t.Errorf("want only the in-flight deletion to finish, got %+v", exec.deletedVolumes)
+	}
+	if len(exec.callErr) != 1 || exec.callErr[0] != nil {
+		t.Errorf("want the in-flight call to run uncancelled, got %v", exec.callErr)
+	}
+	if !strings.Contains(text, "[CANCELLED]") {
+		t.Errorf("want the cancellation noted, got:\n%s", text)
+	}
+	if run := d.history.list()[0]; run.Status != runCancelled || len(run.Records) != 1 {
+		t.Errorf("want a cancelled run with one record, got %+v", run)
+	}
+}
+
+// stepSchedule fires every step.
+type stepSchedule struct{ step time.Duration }
+
+func (s stepSchedule) Next(t time.Time) time.Time { return t.Add(s.step) }
+
+// countingExecutor counts sweeps and cancels the daemon after the second.
+type countingExecutor struct {
+	*fakeExecutor
+	mu     sync.Mutex
+	sweeps int
+	cancel context.CancelFunc
+}
+
+func (c *countingExecutor) AccountsGet(ctx context.Context) ([]core.Account, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweeps++
+	if c.sweeps == 2 {
+		c.cancel()
+	}
+	return nil, core.ErrUnsupported
+}
+
+func TestDaemon_LoopSweepsUntilCancelled(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec := &countingExecutor{fakeExecutor: &fakeExecutor{}, cancel: cancel}
+	withDaemonCloud(t, exec)
+	d := newTestDaemon(ctx, 5)
+	d.schedule = stepSchedule{step: time.Millisecond}
+
+	done := make(chan struct{})
+	captureOutput(t, func() {
+		go func() {
+			d.loop(ctx)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("loop did not return after cancellation")
+		}
+	})
+
+	if exec.sweeps != 2 {
+		t.Errorf("want 2 sweeps before the cancel, got %d", exec.sweeps)
+	}
+	runs := d.history.list()
+	if len(runs) != 2 || runs[0].Status != runCancelled || runs[1].Status != runFinished {
+		t.Errorf("want a finished then a cancelled run, got %+v", runs)
+	}
+}
+
+func TestDaemon_ServesRunsAndHealth(t *testing.T) {
+	d := newTestDaemon(context.Background(), 5)
+	run := d.history.begin(time.Unix(1700000000, 0), triggerSchedule, runOptions{})
+	d.history.finish(run, &reporter{}, exitActionsFailed, runFinished, time.Unix(1700000060, 0))
+	d.history.begin(time.Unix(1700003600, 0), triggerAPI, runOptions{})
+	ts := httptest.NewServer(d.serveMux())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/runs")
+	if err != nil {
+		t.Fatalf("GET /runs: %v", err)
+	}
+	defer resp.Body.Close()
+	var runs []runReport
+	if err := json.NewDecoder(resp.Body).Decode(&runs); err != nil {
+		t.Fatalf("decoding /runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Status != runRunning || runs[0].ExitCode != nil {
+		t.Fatalf("want the running run first without an exit code, got %+v", runs)
+	}
+	if runs[1].ExitCode == nil || *runs[1].ExitCode != exitActionsFailed {
+		t.Errorf("want the finished run's exit code, got %+v", runs[1])
+	}
+
+	health, err := ts.Client().Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz: %v", err)
+	}
+	health.Body.Close()
+	if health.StatusCode != 200 {
+		t.Errorf("GET /healthz = %d", health.StatusCode)
+	}
+}
+
+// optionsExecutor records the run options its listing ran under.
+type optionsExecutor struct {
+	*fakeExecutor
+	seen chan runOptions
+}
+
+func (f *optionsExecutor) VolumesGet(ctx context.Context) ([]core.Volume, error) {
+	// long enough for the other run to be listing at the same time
+	time.Sleep(20 * time.Millisecond)
+	f.seen <- passOf(ctx).run
+	return nil, nil
+}
+
+func TestDaemon_ConcurrentRunsKeepTheirOptions(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	seen := make(chan runOptions, 2)
+	withDaemonCloud(t, nil)
+	clouds = map[string]core.ExecutorInterface{
+		"one": &optionsExecutor{fakeExecutor: &fakeExecutor{}, seen: seen},
+		"two": &optionsExecutor{fakeExecutor: &fakeExecutor{}, seen: seen},
+	}
+	packageReport := report
+	opts := []runOptions{{Clouds: "one", Mock: true}, {Clouds: "two", Mock: false}}
+
+	// both runs print at once; a bytes.Buffer would race, io.Discard does not
+	prevOut := out
+	out = io.Discard
+	t.Cleanup(func() { out = prevOut })
+
+	// separate daemons: start's claim cannot keep them apart
+	daemons := []*daemon{newTestDaemon(context.Background(), 5), newTestDaemon(context.Background(), 5)}
+	var wg sync.WaitGroup
+	for i, o := range opts {
+		run, _ := daemons[i].start(triggerAPI, o)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			daemons[i].execute(context.Background(), run, o)
+		}()
+	}
+	wg.Wait()
+	close(seen)
+
+	got := map[string]bool{}
+	for o := range seen {
+		got[o.Clouds] = o.Mock
+	}
+	if len(got) != 2 || got["one"] != true || got["two"] != false {
+		t.Errorf("want each run to list under its own options, got %v", got)
+	}
+	if flagClouds != "fake" || !flagMock || report != packageReport {
+		t.Errorf("want the flags and package report untouched by both runs, got %q mock=%v", flagClouds, flagMock)
+	}
+}
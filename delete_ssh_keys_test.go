@@ -2,20 +2,32 @@ package main
 
 import (
 	"context"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cloud66/janitor/core"
 )
 
 // fakeExecutor is a minimal core.ExecutorInterface impl used by main-package
-// tests. Records *Delete calls so tests can assert on invocation counts —
-// the reviewer panel flagged stdout-only assertions as too weak (a broken
-// skip that still printed the tag would pass).
+// tests. Records *Delete / ServerStop / ServerStart calls so tests can assert
+// on invocation counts — the reviewer panel flagged stdout-only assertions as
+// too weak (a broken skip that still printed the tag would pass).
 type fakeExecutor struct {
 	deletedKeys    []core.SshKey
 	deletedLBs     []core.LoadBalancer
 	deletedVolumes []core.Volume
+	deletedSnaps   []core.Snapshot
+	releasedIPs    []core.IPAddress
+	deletedTGs     []core.TargetGroup
+	stoppedServers []core.Server
+	startedServers []core.Server
+	// deleteErr, when set, is returned by every *Delete after recording.
+	deleteErr error
+	// marks records *Mark calls as "<vendor id>=<value>"; markErr is returned.
+	marks   []string
+	markErr error
 }
 
 func (f *fakeExecutor) ServersGet(ctx context.Context, vendorIDs []string, regions []string) ([]core.Server, error) {
@@ -25,31 +37,84 @@ func (f *fakeExecutor) ServerDelete(ctx context.Context, s core.Server) error {
 	return core.ErrUnsupported
 }
 func (f *fakeExecutor) ServerStop(ctx context.Context, s core.Server) error {
-	return core.ErrUnsupported
+	f.stoppedServers = append(f.stoppedServers, s)
+	return nil
 }
 func (f *fakeExecutor) ServerStart(ctx context.Context, s core.Server) error {
-	return core.ErrUnsupported
+	f.startedServers = append(f.startedServers, s)
+	return nil
+}
+func (f *fakeExecutor) ServerMark(ctx context.Context, s core.Server, value string) error {
+	f.marks = append(f.marks, s.VendorID+"="+value)
+	return f.markErr
 }
 func (f *fakeExecutor) LoadBalancersGet(ctx context.Context, mock bool) ([]core.LoadBalancer, error) {
 	return nil, core.ErrUnsupported
 }
 func (f *fakeExecutor) LoadBalancerDelete(ctx context.Context, lb core.LoadBalancer) error {
 	f.deletedLBs = append(f.deletedLBs, lb)
-	return nil
+	return f.deleteErr
+}
+func (f *fakeExecutor) LoadBalancerMark(ctx context.Context, lb core.LoadBalancer, value string) error {
+	f.marks = append(f.marks, lb.LoadBalancerArn+"="+value)
+	return f.markErr
 }
 func (f *fakeExecutor) SshKeysGet(ctx context.Context) ([]core.SshKey, error) {
 	return nil, core.ErrUnsupported
 }
 func (f *fakeExecutor) SshKeyDelete(ctx context.Context, k core.SshKey) error {
 	f.deletedKeys = append(f.deletedKeys, k)
-	return nil
+	return f.deleteErr
 }
 func (f *fakeExecutor) VolumesGet(ctx context.Context) ([]core.Volume, error) {
 	return nil, core.ErrUnsupported
 }
 func (f *fakeExecutor) VolumeDelete(ctx context.Context, v core.Volume) error {
 	f.deletedVolumes = append(f.deletedVolumes, v)
-	return nil
+	return f.deleteErr
+}
+func (f *fakeExecutor) VolumeMark(ctx context.Context, v core.Volume, value string) error {
+	f.marks = append(f.marks, v.VendorID+"="+value)
+	return f.markErr
+}
+func (f *fakeExecutor) SnapshotsGet(ctx context.Context) ([]core.Snapshot, error) {
+	return nil, core.ErrUnsupported
+}
+func (f *fakeExecutor) SnapshotDelete(ctx context.Context, s core.Snapshot) error {
+	f.deletedSnaps = append(f.deletedSnaps, s)
+	return f.deleteErr
+}
+func (f *fakeExecutor) IPAddressesGet(ctx context.Context) ([]core.IPAddress, error) {
+	return nil, core.ErrUnsupported
+}
+func (f *fakeExecutor) IPAddressRelease(ctx context.Context, ip core.IPAddress) error {
+	f.releasedIPs = append(f.releasedIPs, ip)
+	return f.deleteErr
+}
+func (f *fakeExecutor) TargetGroupsGet(ctx context.Context) ([]core.TargetGroup, error) {
+	return nil, core.ErrUnsupported
+}
+func (f *fakeExecutor) TargetGroupMark(ctx context.Context, tg core.TargetGroup, value string) error {
+	f.marks = append(f.marks, tg.VendorID+"="+value)
+	return f.markErr
+}
+func (f *fakeExecutor) TargetGroupDelete(ctx context.Context, tg core.TargetGroup) error {
+	f.deletedTGs = append(f.deletedTGs, tg)
+	return f.deleteErr
+}
+func (f *fakeExecutor) SkippedRegionsGet(ctx context.Context) ([]core.SkippedRegion, error) {
+	return nil, core.ErrUnsupported
+}
+func (f *fakeExecutor) AccountsGet(ctx context.Context) ([]core.Account, error) {
+	return nil, core.ErrUnsupported
+}
+
+// withSshKeepDays swaps flagSshKeysKeepDays for the test.
+func withSshKeepDays(t *testing.T, days float64) {
+	t.Helper()
+	prev := flagSshKeysKeepDays
+	flagSshKeysKeepDays = days
+	t.Cleanup(func() { flagSshKeysKeepDays = prev })
 }
 
 // withSshKeepCount swaps flagSshKeysKeepCount for the test and restores via
@@ -169,3 +234,89 @@ func TestDeleteSshKeys_MockOutput(t *testing.T) {
 		t.Errorf("mock mode must not call SshKeyDelete, got %d calls", len(fe.deletedKeys))
 	}
 }
+
+// TestDeleteSshKeys_KeepCountPerRegion pins that keep-last-N applies inside
+// each region: three c66 keys in us-east-1 and one in eu-west-1 with keep=1
+// delete the two oldest in us-east-1 and nothing in eu-west-1.
+func TestDeleteSshKeys_KeepCountPerRegion(t *testing.T) {
+	withFlags(t, false, flagMaxAgeNormal, flagMaxAgeLong)
+	withSshKeepCount(t, 1)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	keys := []core.SshKey{
+		{VendorID: "key-z", Name: "c66-new", Region: "us-east-1", Created: base.Add(48 * time.Hour)},
+		{VendorID: "key-a", Name: "c66-old", Region: "us-east-1", Created: base},
+		{VendorID: "key-m", Name: "c66-mid", Region: "us-east-1", Created: base.Add(24 * time.Hour)},
+		{VendorID: "key-e", Name: "c66-only", Region: "eu-west-1", Created: base},
+	}
+	sort.Sort(core.SshKeySorter(keys))
+
+	fe := &fakeExecutor{}
+	got := captureOutput(t, func() { deleteSshKeys(ctxWithExec(fe), keys) })
+	var deleted []string
+	for _, k := range fe.deletedKeys {
+		deleted = append(deleted, k.Region+"/"+k.Name)
+	}
+	want := []string{"us-east-1/c66-old", "us-east-1/c66-mid"}
+	if strings.Join(deleted, ",") != strings.Join(want, ",") {
+		t.Errorf("want deletions %v, got %v\n%s", want, deleted, got)
+	}
+	if !strings.Contains(got, "[key-e] [eu-west-1] [0.00 days old] [c66-only] ▶ skipped (keep last 1)") {
+		t.Errorf("regional key line should carry its region, got:\n%s", got)
+	}
+}
+
+// TestDeleteSshKeys_KeepDays pins "keep N newest AND anything younger than X
+// days": the young key survives although keep-count alone would delete it,
+// and a key without a creation time is judged by keep-count only.
+func TestDeleteSshKeys_KeepDays(t *testing.T) {
+	withFlags(t, false, flagMaxAgeNormal, flagMaxAgeLong)
+	withSshKeepCount(t, 1)
+	withSshKeepDays(t, 2)
+	now := time.Now()
+	keys := []core.SshKey{
+		{VendorID: "1", Name: "c66-untimed"},
+		{VendorID: "2", Name: "c66-old", Created: now.Add(-72 * time.Hour), Age: 3},
+		{VendorID: "3", Name: "c66-young", Created: now.Add(-24 * time.Hour), Age: 1},
+		{VendorID: "4", Name: "c66-newest", Created: now.Add(-time.Hour), Age: 1.0 / 24},
+	}
+	sort.Sort(core.SshKeySorter(keys))
+
+	fe := &fakeExecutor{}
+	got := captureOutput(t, func() { deleteSshKeys(ctxWithExec(fe), keys) })
+	var deleted []string
+	for _, k := range fe.deletedKeys {
+		deleted = append(deleted, k.Name)
+	}
+	if strings.Join(deleted, ",") != "c66-untimed,c66-old" {
+		t.Errorf("want untimed and old deleted, got %v\n%s", deleted, got)
+	}
+	for _, want := range []string{
+		"[1] [age unknown, ordered by ID] [c66-untimed] ▶ Deleted!",
+		"[c66-young] ▶ skipped (younger than 2 days)",
+		"[c66-newest] ▶ skipped (keep last 1)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+// TestDeleteSshKeys_PermanentLabelSparesKey — a c66 key labelled permanent
+// (Hetzner labels and AWS key pair tags flow into Tags) is kept and doesn't
+// use up a keep slot.
+func TestDeleteSshKeys_PermanentLabelSparesKey(t *testing.T) {
+	withFlags(t, false, flagMaxAgeNormal, flagMaxAgeLong)
+	withSshKeepCount(t, 0)
+	fe := &fakeExecutor{}
+	keys := []core.SshKey{
+		{VendorID: "1", Name: "c66-a", Tags: []string{"lifecycle=permanent"}},
+		{VendorID: "2", Name: "c66-b"},
+	}
+	got := captureOutput(t, func() { deleteSshKeys(ctxWithExec(fe), keys) })
+	if len(fe.deletedKeys) != 1 || fe.deletedKeys[0].VendorID != "2" {
+		t.Errorf("want only key 2 deleted, got %v", fe.deletedKeys)
+	}
+	if !strings.Contains(got, "[c66-a] ▶ skipped (permanent)") {
+		t.Errorf("want permanent skip, got:\n%s", got)
+	}
+}
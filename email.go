@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// smtpTimeout bounds one owner email, from dial to QUIT.
+const smtpTimeout = 30 * time.Second
+
+// ownerMailer emails each resource owner the part of a run digest that
+// concerns them. owners come from a tag on the resource; resources without
+// an addressable owner go to Fallback.
+type ownerMailer struct {
+	// Server is the SMTP relay as host:port. STARTTLS is used when the
+	// relay offers it, and Username / Password authenticate with PLAIN.
+	Server   string
+	Username string
+	Password string
+	From     string
+	Fallback string
+	// TagKeys are the tag keys naming an owner, first match wins.
+	TagKeys []string
+	// Domain completes owner values that are not email addresses, so
+	// owner=alice becomes alice@Domain. empty sends them to Fallback.
+	Domain string
+	// now stamps the Date header; tests pin it.
+	now func() time.Time
+}
+
+// ownerMail, when set from --smtp-server, emails owners after every run.
+var ownerMail *ownerMailer
+
+// validate checks the mailer's configuration before anything is swept.
+func (m *ownerMailer) validate() error {
+	if _, _, err := net.SplitHostPort(m.Server); err != nil {
+		return fmt.Errorf("--smtp-server must be host:port: %w", err)
+	}
+	if _, err := mail.ParseAddress(m.From); err != nil {
+		return fmt.Errorf("--smtp-from is not an email address: %w", err)
+	}
+	if _, err := mail.ParseAddress(m.Fallback); err != nil {
+		return fmt.Errorf("--owner-fallback-email is not an email address: %w", err)
+	}
+	if len(m.TagKeys) == 0 {
+		return errors.New("--owner-tag names no tag key")
+	}
+	if strings.Contains(m.Domain, "@") {
+		return fmt.Errorf("--owner-email-domain %q must be a bare domain", m.Domain)
+	}
+	return nil
+}
+
+// parseTagKeys reads the comma-separated --owner-tag value.
+func parseTagKeys(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ownerOf returns the owner tagged on tags and, when it can be mailed, the
+// owner's address. keys match case-insensitively, like policy tags.
+func (m *ownerMailer) ownerOf(tags []string) (owner, address string) {
+	for _, want := range m.TagKeys {
+		for _, tag := range tags {
+			key, value, ok := strings.Cut(tag, "=")
+			value = strings.TrimSpace(value)
+			if !ok || value == "" || !strings.EqualFold(strings.TrimSpace(key), want) {
+				continue
+			}
+			candidate := value
+			if !strings.Contains(candidate, "@") {
+				if m.Domain == "" {
+					return value, ""
+				}
+				candidate += "@" + m.Domain
+			}
+			parsed, err := mail.ParseAddress(candidate)
+			if err != nil {
+				return value, ""
+			}
+			return value, strings.ToLower(parsed.Address)
+		}
+	}
+	return "", ""
+}
+
+// ownerItem is one resource in an owner email. note says why a resource
+// landed in the fallback mailbox.
+type ownerItem struct {
+	rec  record
+	note string
+}
+
+// group splits the digest's acted-on, failed and expiring resources by
+// owner address.
+func (m *ownerMailer) group(d digest) map[string][]ownerItem {
+	groups := map[string][]ownerItem{}
+	var recs []record
+	recs = append(recs, d.Acted...)
+	recs = append(recs, d.Failed...)
+	recs = append(recs, d.Expiring...)
+	for _, rec := range recs {
+		owner, address := m.ownerOf(rec.Tags)
+		item := ownerItem{rec: rec}
+		if address == "" {
+			address = strings.ToLower(m.Fallback)
+			if owner == "" {
+				item.note = "no owner tag"
+			} else {
+				item.note = fmt.Sprintf("owner %q is not an email address", owner)
+			}
+		}
+		groups[address] = append(groups[address], item)
+	}
+	return groups
+}
+
+// message renders the email to the owner at to about items.
+func (m *ownerMailer) message(d digest, to string, items []ownerItem) []byte {
+	acted := strings.ToLower(actedColumn(d.Action))
+	owned := fmt.Sprintf("%d of your resources", len(items))
+	intro := fmt.Sprintf("these resources, which carry you as %s", strings.Join(m.TagKeys, " or "))
+	if to == strings.ToLower(m.Fallback) {
+		owned = fmt.Sprintf("%d resources without an owner", len(items))
+		intro = "these resources, which have no owner janitor can email"
+	}
+	subject := fmt.Sprintf("janitor %s on %s: %s", d.Action, d.Clouds, owned)
+	if d.Mock {
+		subject = "[MOCK] " + subject
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "janitor ran %s on %s and acted on %s, or will soon.\n", d.Action, d.Clouds, intro)
+	if d.Mock {
+		fmt.Fprintf(&body, "This was a mock run: nothing was %s yet; RESULT shows what a live run would do.\n", acted)
+	}
+	fmt.Fprintf(&body, "Age limits: %.2f days (--max-age-regular), %.2f days for long resources (--max-age-long), %.2f days for snapshots (--max-age-snapshot).\n\n", flagMaxAgeNormal, flagMaxAgeLong, flagMaxAgeSnapshot)
+
+	tw := tabwriter.NewWriter(&body, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CLOUD\tKIND\tNAME\tID\tREGION\tAGE (DAYS)\tMAX AGE\tRESULT\tNOTES")
+	for _, item := range items {
+		rec := item.rec
+		where := rec.Cloud
+		if rec.Account != "" {
+			where += "/" + rec.Account
+		}
+		maxAge := "-"
+		if rec.MaxAgeDays > 0 {
+			maxAge = fmt.Sprintf("%.2f", rec.MaxAgeDays)
+		}
+		result := rec.Result
+		if rec.Result == resultMock {
+			result = "would be " + acted
+		}
+		var notes []string
+		if rec.ExpiresAt != nil {
+			// still kept: the reason would only say why it is, for now
+			result = "eligible at " + formatTimestamp(*rec.ExpiresAt)
+			notes = append(notes, expiryHint(rec))
+		} else if rec.Reason != "" {
+			notes = append(notes, rec.Reason)
+		}
+		if rec.Error != "" {
+			notes = append(notes, rec.Error)
+		}
+		if item.note != "" {
+			notes = append(notes, item.note)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.2f\t%s\t%s\t%s\n",
+			where, rec.Kind, rec.Name, rec.VendorID, orDash(rec.Region), rec.AgeDays, maxAge, result, strings.Join(notes, "; "))
+	}
+	_ = tw.Flush()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\n", m.now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\nContent-Type: text/plain; charset=utf-8\nContent-Transfer-Encoding: 8bit\n\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
+
+// orDash prints an empty column as "-".
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// send delivers msg to one recipient through the relay.
+func (m *ownerMailer) send(ctx context.Context, to string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.Server)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	host, _, _ := net.SplitHostPort(m.Server)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+	from, _ := mail.ParseAddress(m.From)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// mailOwners emails every owner with a resource acted on, failed or expiring
+// in d, in address order. it returns how many emails were sent and the
+// failed deliveries; one failure does not stop the rest.
+func (m *ownerMailer) mailOwners(ctx context.Context, d digest) (int, []error) {
+	groups := m.group(d)
+	addresses := make([]string, 0, len(groups))
+	for address := range groups {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	var errs []error
+	for _, address := range addresses {
+		if err := m.send(ctx, address, m.message(d, address, groups[address])); err != nil {
+			errs = append(errs, fmt.Errorf("emailing %s: %w", address, err))
+		}
+	}
+	return len(addresses) - len(errs), errs
+}
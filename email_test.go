@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpMessage is one mail the stand-in relay accepted.
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpStandIn is a local relay speaking just enough SMTP for net/smtp: no
+// STARTTLS, no AUTH.
+type smtpStandIn struct {
+	mu    sync.Mutex
+	inbox []smtpMessage
+}
+
+func (s *smtpStandIn) messages() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.inbox...)
+}
+
+// startSMTP serves a stand-in relay until the test ends and returns its
+// address.
+func startSMTP(t *testing.T) (*smtpStandIn, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	s := &smtpStandIn{}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, l.Addr().String()
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 stand-in ESMTP")
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case verb == "EHLO" || verb == "HELO":
+			reply("250 stand-in")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			msg = smtpMessage{From: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			reply("250 ok")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case verb == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			s.inbox = append(s.inbox, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case verb == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// withOwnerMail routes owner emails to a fresh stand-in relay.
+func withOwnerMail(t *testing.T) *smtpStandIn {
+	t.Helper()
+	relay, addr := startSMTP(t)
+	prev := ownerMail
+	ownerMail = &ownerMailer{
+		Server:   addr,
+		From:     "janitor <janitor@example.com>",
+		Fallback: "ops@example.com",
+		TagKeys:  []string{"owner", "created-by"},
+		Domain:   "example.com",
+		now:      func() time.Time { return time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC) },
+	}
+	t.Cleanup(func() { ownerMail = prev })
+	return relay
+}
+
+// mailedTo returns the message relay accepted for address.
+func mailedTo(t *testing.T, relay *smtpStandIn, address string) string {
+	t.Helper()
+	for _, msg := range relay.messages() {
+		if len(msg.To) == 1 && msg.To[0] == address {
+			return msg.Data
+		}
+	}
+	t.Fatalf("nothing was mailed to %s; got %+v", address, relay.messages())
+	return ""
+}
+
+func TestOwnerOf(t *testing.T) {
+	m := &ownerMailer{TagKeys: []string{"owner", "created-by"}, Domain: "example.com"}
+	for _, tc := range []struct {
+		tags           []string
+		owner, address string
+	}{
+		{[]string{"created-by=bob", "Owner=Alice@Example.com"}, "Alice@Example.com", "alice@example.com"},
+		{[]string{"created-by=bob"}, "bob", "bob@example.com"},
+		{[]string{"owner=", "created-by=carol"}, "carol", "carol@example.com"},
+		{[]string{"owner=not an address"}, "not an address", ""},
+		{[]string{"team=infra", "permanent"}, "", ""},
+	} {
+		owner, address := m.ownerOf(tc.tags)
+		if owner != tc.owner || address != tc.address {
+			t.Errorf("%v: want (%q, %q), got (%q, %q)", tc.tags, tc.owner, tc.address, owner, address)
+		}
+	}
+
+	m.Domain = ""
+	if owner, address := m.ownerOf([]string{"owner=alice"}); owner != "alice" || address != "" {
+		t.Errorf("without a domain a bare owner is not mailable, got (%q, %q)", owner, address)
+	}
+}
+
+func TestOwnerMailerValidate(t *testing.T) {
+	valid := ownerMailer{Server: "localhost:25", From: "janitor@example.com", Fallback: "ops@example.com", TagKeys: []string{"owner"}}
+	if err := valid.validate(); err != nil {
+		t.Fatalf("valid settings: %v", err)
+	}
+	for name, broken := range map[string]func(m *ownerMailer){
+		"server without port": func(m *ownerMailer) { m.Server = "localhost" },
+		"no from":             func(m *ownerMailer) { m.From = "" },
+		"no fallback":         func(m *ownerMailer) { m.Fallback = "" },
+		"no tag keys":         func(m *ownerMailer) { m.TagKeys = parseTagKeys(" , ") },
+		"domain with @":       func(m *ownerMailer) { m.Domain = "@example.com" },
+	} {
+		m := valid
+		broken(&m)
+		if err := m.validate(); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
+
+func TestNotifyRun_EmailsEachOwner(t *testing.T) {
+	relay := withOwnerMail(t)
+	withFlags(t, false, 0.38, 5.0)
+	prevAction, prevClouds := flagAction, flagClouds
+	flagAction, flagClouds = actionDelete, "aws"
+	t.Cleanup(func() { flagAction, flagClouds = prevAction, prevClouds })
+	captureReport(t, outputText)
+
+	report.add(record{Cloud: "aws", Kind: kindServer, VendorID: "i-old", Name: "demo-box", Region: "us-east-1", AgeDays: 0.75, MaxAgeDays: 0.38, Tags: []string{"owner=alice"}, State: "NORM", Decision: policyDelete, Reason: "age", Result: resultDeleted})
+	report.add(record{Cloud: "aws", Kind: kindServer, VendorID: "i-long", Name: "soak", Region: "us-east-1", AgeDays: 6, MaxAgeDays: 5, Tags: []string{"created-by=Alice@example.com", "long"}, State: "LONG", Decision: policyDelete, Reason: "age", Result: resultFailed, Error: "UnauthorizedOperation"})
+	report.add(record{Cloud: "aws", Kind: kindVolume, VendorID: "vol-1", Name: "scratch", Region: "eu-west-1", AgeDays: 2, Tags: []string{"owner=bob"}, State: "LIVE", Decision: policyKeep, Reason: "attached to instance", Result: resultSkipped})
+	report.add(record{Cloud: "aws", Kind: kindVolume, VendorID: "vol-2", Name: "orphan", Region: "eu-west-1", AgeDays: 3, State: "DEAD", Decision: policyDelete, Reason: "unattached", Result: resultDeleted})
+
+	notifyRun(context.Background(), report.summary.exitCode())
+
+	if n := len(relay.messages()); n != 2 {
+		t.Fatalf("want one email for alice and one to the fallback, got %d", n)
+	}
+	alice := mailedTo(t, relay, "alice@example.com")
+	for _, want := range []string{
+		"From: janitor <janitor@example.com>\r\n",
+		"Subject: janitor delete on aws: 2 of your resources\r\n",
+		"Date: Sat, 17 Oct 2026 09:00:00 +0000\r\n",
+		"carry you as owner or created-by",
+		"Age limits: 0.38 days (--max-age-regular), 5.00 days for long resources (--max-age-long), 30.00 days for snapshots (--max-age-snapshot).",
+	} {
+		if !strings.Contains(alice, want) {
+			t.Errorf("alice's email lacks %q:\n%s", want, alice)
+		}
+	}
+	if !containsRow(alice, "aws", "server", "demo-box", "i-old", "us-east-1", "0.75", "0.38", "deleted", "age") ||
+		!containsRow(alice, "aws", "server", "soak", "i-long", "us-east-1", "6.00", "5.00", "failed", "age;", "UnauthorizedOperation") {
+		t.Errorf("alice's email lacks her resources:\n%s", alice)
+	}
+	if strings.Contains(alice, "scratch") || strings.Contains(alice, "orphan") {
+		t.Errorf("alice's email lists other resources:\n%s", alice)
+	}
+
+	ops := mailedTo(t, relay, "ops@example.com")
+	if !strings.Contains(ops, "Subject: janitor delete on aws: 1 resources without an owner\r\n") ||
+		!containsRow(ops, "aws", "volume", "orphan", "vol-2", "eu-west-1", "3.00", "-", "deleted", "unattached;", "no", "owner", "tag") {
+		t.Errorf("want the untagged volume at the fallback address:\n%s", ops)
+	}
+}
+
+func TestNotifyRun_MockRunsEmailNobody(t *testing.T) {
+	relay := withOwnerMail(t)
+	digestRun(t, true)
+	report.add(record{Cloud: "aws", Kind: kindServer, VendorID: "i-bob", Name: "bobs", Region: "us-east-1", AgeDays: 1, MaxAgeDays: 0.38, Tags: []string{"owner=bob"}, State: "NORM", Decision: policyDelete, Reason: "age", Result: resultMock})
+
+	notifyRun(context.Background(), report.summary.exitCode())
+
+	if msgs := relay.messages(); len(msgs) != 0 {
+		t.Errorf("a mock run emailed owners without --email-mock-runs: %+v", msgs)
+	}
+}
+
+func TestNotifyRun_EmailsLabelMockRuns(t *testing.T) {
+	relay := withOwnerMail(t)
+	ownerMail.Domain = ""
+	digestRun(t, true)
+	flagEmailMockRuns = true
+	t.Cleanup(func() { flagEmailMockRuns = false })
+	report.add(record{Cloud: "aws", Kind: kindServer, VendorID: "i-bob", Name: "bobs", Region: "us-east-1", AgeDays: 1, MaxAgeDays: 0.38, Tags: []string{"owner=bob"}, State: "NORM", Decision: policyDelete, Reason: "age", Result: resultMock})
+
+	notifyRun(context.Background(), report.summary.exitCode())
+
+	ops := mailedTo(t, relay, "ops@example.com")
+	for _, want := range []string{
+		"Subject: [MOCK] janitor delete on aws: 3 resources without an owner\r\n",
+		"This was a mock run: nothing was deleted yet",
+		`owner "bob" is not an email address`,
+	} {
+		if !strings.Contains(ops, want) {
+			t.Errorf("fallback email lacks %q:\n%s", want, ops)
+		}
+	}
+	if !containsRow(ops, "aws", "server", "bobs", "i-bob", "us-east-1", "1.00", "0.38", "would", "be", "deleted", "age;", "owner", `"bob"`, "is", "not", "an", "email", "address") {
+		t.Errorf("want the mock deletion spelled out:\n%s", ops)
+	}
+}
+
+func TestMailOwners_ReportsUnreachableRelay(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	m := &ownerMailer{Server: addr, From: "janitor@example.com", Fallback: "ops@example.com", TagKeys: []string{"owner"}, now: time.Now}
+	sent, errs := m.mailOwners(context.Background(), digest{Action: actionDelete, Acted: []record{{Cloud: "aws", Kind: kindServer, Result: resultDeleted}}})
+	if sent != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "emailing ops@example.com") {
+		t.Errorf("want the failed delivery named, got %d sent, %v", sent, errs)
+	}
+}
+
+// containsRow reports whether text has a line made of exactly fields,
+// however the table pads them.
+func containsRow(text string, fields ...string) bool {
+	want := strings.Join(fields, " ")
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Join(strings.Fields(line), " ") == want {
+			return true
+		}
+	}
+	return false
+}
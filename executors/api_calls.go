@@ -0,0 +1,75 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/cloud66/janitor/core"
+)
+
+// apiCallTransport reports every HTTP round trip of the REST providers
+// (DigitalOcean, Vultr, Hetzner) to the ctx APIObserver. the SDKs pass the
+// caller's ctx down to the request, so the observer travels with it.
+type apiCallTransport struct {
+	cloud string
+	base  http.RoundTripper
+}
+
+func (t apiCallTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	callErr := err
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		callErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	core.ObserveAPICall(req.Context(), t.cloud, apiOperation(req), time.Since(start), callErr)
+	return resp, err
+}
+
+// apiOperation names a REST call by its method and first resource segment,
+// e.g. "GET droplets" for /v2/droplets/42. IDs stay out of the name so it is
+// safe as a metric label.
+func apiOperation(req *http.Request) string {
+	for _, segment := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+		if segment == "" || isAPIVersion(segment) {
+			continue
+		}
+		return req.Method + " " + segment
+	}
+	return req.Method
+}
+
+// isAPIVersion matches version path segments like "v1" and "v2".
+func isAPIVersion(segment string) bool {
+	if len(segment) < 2 || segment[0] != 'v' {
+		return false
+	}
+	for _, r := range segment[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// observeAWSCalls is an SDK APIOptions hook reporting every AWS operation,
+// retries included, to the ctx APIObserver as "<service> <operation>".
+func observeAWSCalls(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("janitorObserveAPICall",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			operation := awsmiddleware.GetServiceID(ctx) + " " + awsmiddleware.GetOperationName(ctx)
+			core.ObserveAPICall(ctx, "aws", operation, time.Since(start), err)
+			return out, metadata, err
+		}), middleware.After)
+}
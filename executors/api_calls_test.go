@@ -0,0 +1,116 @@
+package executors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/cloud66/janitor/core"
+)
+
+// apiCall is one call seen by recordingObserver.
+type apiCall struct {
+	Cloud, Operation string
+	Failed           bool
+}
+
+// recordingObserver collects every observed API call.
+type recordingObserver struct {
+	mu    sync.Mutex
+	calls []apiCall
+}
+
+func (o *recordingObserver) ObserveAPICall(cloud, operation string, took time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls = append(o.calls, apiCall{Cloud: cloud, Operation: operation, Failed: err != nil})
+}
+
+func TestAPIOperation(t *testing.T) {
+	cases := []struct {
+		method, url, want string
+	}{
+		{http.MethodGet, "https://api.digitalocean.com/v2/droplets?page=2", "GET droplets"},
+		{http.MethodDelete, "https://api.digitalocean.com/v2/droplets/42", "DELETE droplets"},
+		{http.MethodGet, "https://api.hetzner.cloud/v1/ssh_keys", "GET ssh_keys"},
+		{http.MethodPost, "https://api.vultr.com/v2/instances/abc/halt", "POST instances"},
+		{http.MethodGet, "https://example.com/", "GET"},
+		// only v<digits> counts as a version; "volumes" starts with v too
+		{http.MethodGet, "https://example.com/volumes", "GET volumes"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.url, nil)
+		if got := apiOperation(req); got != c.want {
+			t.Errorf("apiOperation(%s %s) = %q, want %q", c.method, c.url, got, c.want)
+		}
+	}
+}
+
+// TestHetzner_ObservesAPICalls checks the observer rides the executor's ctx
+// through the SDK and that HTTP errors count as failed calls.
+func TestHetzner_ObservesAPICalls(t *testing.T) {
+	body := readFixture(t, "hetzner/ssh_keys_list.json")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/ssh_keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	mux.HandleFunc("/v1/ssh_keys/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"not_found","message":"ssh key not found"}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	observer := &recordingObserver{}
+	ctx := context.WithValue(newHetznerCtx(ts), core.APIObserverKey, core.APIObserver(observer))
+	if _, err := (Hetzner{}).SshKeysGet(ctx); err != nil {
+		t.Fatalf("SshKeysGet: %v", err)
+	}
+	if err := (Hetzner{}).SshKeyDelete(ctx, core.SshKey{VendorID: "101"}); err == nil {
+		t.Fatal("want an error deleting a missing key")
+	}
+
+	want := []apiCall{
+		{Cloud: "hetzner", Operation: "GET ssh_keys"},
+		{Cloud: "hetzner", Operation: "DELETE ssh_keys", Failed: true},
+	}
+	if !reflect.DeepEqual(observer.calls, want) {
+		t.Errorf("observed %+v, want %+v", observer.calls, want)
+	}
+}
+
+// TestObserveAWSCalls drives a real EC2 client at a failing endpoint: the
+// middleware names the call by service and operation and reports the error.
+func TestObserveAWSCalls(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := ec2.New(ec2.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(ts.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		RetryMaxAttempts: 1,
+		APIOptions:       []func(*middleware.Stack) error{observeAWSCalls},
+	})
+	observer := &recordingObserver{}
+	ctx := context.WithValue(context.Background(), core.APIObserverKey, core.APIObserver(observer))
+	if _, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{}); err == nil {
+		t.Fatal("want an error from the failing endpoint")
+	}
+
+	want := []apiCall{{Cloud: "aws", Operation: "EC2 DescribeRegions", Failed: true}}
+	if !reflect.DeepEqual(observer.calls, want) {
+		t.Errorf("observed %+v, want %+v", observer.calls, want)
+	}
+}
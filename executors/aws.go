@@ -1,10 +1,13 @@
 package executors
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,12 +15,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
 	elasticloadbalancingtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elasticloadbalancingv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/cloud66/janitor/core"
 )
 
@@ -27,6 +33,20 @@ type ec2Client interface {
 	DescribeInstances(ctx context.Context, in *ec2.DescribeInstancesInput, opts ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
 	ModifyInstanceAttribute(ctx context.Context, in *ec2.ModifyInstanceAttributeInput, opts ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error)
 	TerminateInstances(ctx context.Context, in *ec2.TerminateInstancesInput, opts ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+	StopInstances(ctx context.Context, in *ec2.StopInstancesInput, opts ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+	StartInstances(ctx context.Context, in *ec2.StartInstancesInput, opts ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+	CreateTags(ctx context.Context, in *ec2.CreateTagsInput, opts ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+	DescribeVolumes(ctx context.Context, in *ec2.DescribeVolumesInput, opts ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+	DeleteVolume(ctx context.Context, in *ec2.DeleteVolumeInput, opts ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error)
+	DescribeKeyPairs(ctx context.Context, in *ec2.DescribeKeyPairsInput, opts ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error)
+	DeleteKeyPair(ctx context.Context, in *ec2.DeleteKeyPairInput, opts ...func(*ec2.Options)) (*ec2.DeleteKeyPairOutput, error)
+	DescribeImages(ctx context.Context, in *ec2.DescribeImagesInput, opts ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error)
+	DeregisterImage(ctx context.Context, in *ec2.DeregisterImageInput, opts ...func(*ec2.Options)) (*ec2.DeregisterImageOutput, error)
+	DescribeSnapshots(ctx context.Context, in *ec2.DescribeSnapshotsInput, opts ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error)
+	DeleteSnapshot(ctx context.Context, in *ec2.DeleteSnapshotInput, opts ...func(*ec2.Options)) (*ec2.DeleteSnapshotOutput, error)
+	DescribeAddresses(ctx context.Context, in *ec2.DescribeAddressesInput, opts ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error)
+	ReleaseAddress(ctx context.Context, in *ec2.ReleaseAddressInput, opts ...func(*ec2.Options)) (*ec2.ReleaseAddressOutput, error)
+	DescribeRegions(ctx context.Context, in *ec2.DescribeRegionsInput, opts ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
 }
 
 // elbClient is the subset of the classic ELB API used by janitor.
@@ -34,6 +54,7 @@ type elbClient interface {
 	DescribeLoadBalancers(ctx context.Context, in *elasticloadbalancing.DescribeLoadBalancersInput, opts ...func(*elasticloadbalancing.Options)) (*elasticloadbalancing.DescribeLoadBalancersOutput, error)
 	DescribeTags(ctx context.Context, in *elasticloadbalancing.DescribeTagsInput, opts ...func(*elasticloadbalancing.Options)) (*elasticloadbalancing.DescribeTagsOutput, error)
 	DeleteLoadBalancer(ctx context.Context, in *elasticloadbalancing.DeleteLoadBalancerInput, opts ...func(*elasticloadbalancing.Options)) (*elasticloadbalancing.DeleteLoadBalancerOutput, error)
+	AddTags(ctx context.Context, in *elasticloadbalancing.AddTagsInput, opts ...func(*elasticloadbalancing.Options)) (*elasticloadbalancing.AddTagsOutput, error)
 }
 
 // albClient is the subset of the ELBv2 (ALB/NLB) API used by janitor.
@@ -50,6 +71,11 @@ type albClient interface {
 	DeleteLoadBalancer(ctx context.Context, in *elasticloadbalancingv2.DeleteLoadBalancerInput, opts ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DeleteLoadBalancerOutput, error)
 }
 
+// stsClient is the subset of the STS API used by janitor.
+type stsClient interface {
+	GetCallerIdentity(ctx context.Context, in *sts.GetCallerIdentityInput, opts ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
 // Aws encapsulates all AWS cloud calls.
 // client factories are injectable so tests can substitute fakes.
 type Aws struct {
@@ -58,7 +84,9 @@ type Aws struct {
 	ec2Factory func(ctx context.Context, region string) ec2Client
 	elbFactory func(ctx context.Context, region string) elbClient
 	albFactory func(ctx context.Context, region string) albClient
-	// regionsOverride allows tests to shrink the region list.
+	stsFactory func(ctx context.Context) stsClient
+	// regionsOverride allows tests to shrink the region list. it stands in
+	// for region discovery; --aws-regions still applies on top.
 	regionsOverride []string
 	// tgDeleteBackoff overrides the per-attempt delay between
 	// DeleteTargetGroup retries. defaults to 1s/2s/4s/8s/16s/32s; tests pass
@@ -74,12 +102,13 @@ func (a Aws) tgDeleteBackoffFor(attempt int) time.Duration {
 	return time.Duration(1<<attempt) * time.Second
 }
 
-// cached AWS credentials provider. config.LoadDefaultConfig walks env /
-// shared / IRSA / IMDS and is expensive; resolve once per process to avoid
-// O(regions × services) IMDS probes (panel round-3 C6).
+// cached AWS credentials providers, one per account (profile + assumed
+// role). config.LoadDefaultConfig walks env / shared / IRSA / IMDS and is
+// expensive; resolve once per account to avoid O(regions × services) IMDS
+// probes (panel round-3 C6).
 var (
-	credsOnce  sync.Once
-	credsCache aws.CredentialsProvider
+	credsMu    sync.Mutex
+	credsCache = map[string]aws.CredentialsProvider{}
 )
 
 // compile-time assertion that Aws satisfies ExecutorInterface. if this ever
@@ -110,12 +139,250 @@ func (a Aws) albFor(ctx context.Context, region string) albClient {
 	return a.albClient(ctx, region)
 }
 
-// regions returns the regions to iterate, honoring a test override.
-func (a Aws) regions() []string {
-	if a.regionsOverride != nil {
-		return a.regionsOverride
+// stsFor returns an stsClient for the ctx account, using the factory if set.
+func (a Aws) stsFor(ctx context.Context) stsClient {
+	if a.stsFactory != nil {
+		return a.stsFactory(ctx)
+	}
+	return a.stsClient(ctx)
+}
+
+// regions returns the regions to iterate: the account's enabled regions (or
+// the test override) narrowed by --aws-regions.
+func (a Aws) regions(ctx context.Context) []string {
+	regions, _ := a.selectedRegions(ctx)
+	return regions
+}
+
+// SkippedRegionsGet returns the regions left out of the run because the
+// account has not opted in to them, or because --aws-regions names a region
+// that does not exist. regions the operator excluded are not reported.
+func (a Aws) SkippedRegionsGet(ctx context.Context) ([]core.SkippedRegion, error) {
+	_, skipped := a.selectedRegions(ctx)
+	return skipped, nil
+}
+
+// AccountsGet returns the accounts to sweep: one per --aws-role-arns role,
+// else the base credentials alone. IDs come from sts:GetCallerIdentity,
+// which needs no permissions. the bare default chain is left unlabelled so
+// single-account runs read as they always have. a role that cannot be
+// assumed is warned about and skipped.
+func (a Aws) AccountsGet(ctx context.Context) ([]core.Account, error) {
+	roles, _ := ctx.Value(core.AWSRoleARNsKey).([]string)
+	if len(roles) == 0 {
+		profile, _ := ctx.Value(core.AWSProfileKey).(string)
+		if profile == "" {
+			return []core.Account{{}}, nil
+		}
+		id, err := a.accountID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", profile, err)
+		}
+		return []core.Account{{ID: id}}, nil
+	}
+
+	var accounts []core.Account
+	var accountErrs []error
+	for _, role := range roles {
+		id, err := a.accountID(context.WithValue(ctx, core.AccountKey, core.Account{Role: role}))
+		if err != nil {
+			core.Warnf(ctx, "skipping AWS role %s: %v", role, err)
+			accountErrs = append(accountErrs, fmt.Errorf("%s: %w", role, err))
+			continue
+		}
+		accounts = append(accounts, core.Account{ID: id, Role: role})
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("all accounts failed: %w", errors.Join(accountErrs...))
+	}
+	return accounts, nil
+}
+
+// accountID returns the account the ctx credentials belong to.
+func (a Aws) accountID(ctx context.Context) (string, error) {
+	identity, err := a.stsFor(ctx).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	if aws.ToString(identity.Account) == "" {
+		return "", errors.New("GetCallerIdentity returned no account")
+	}
+	return aws.ToString(identity.Account), nil
+}
+
+func (a Aws) selectedRegions(ctx context.Context) ([]string, []core.SkippedRegion) {
+	enabled, unreachable := a.regionsOverride, []string(nil)
+	if enabled == nil {
+		enabled, unreachable = a.accountRegions(ctx)
+	}
+	spec, _ := ctx.Value(core.AWSRegionsKey).(string)
+	return selectRegions(enabled, unreachable, spec)
+}
+
+// discovered regions are cached per account, since opt-ins differ between
+// accounts; every *Get would otherwise repeat DescribeRegions. entries expire
+// after regionsCacheTTL so a long-running daemon sees new opt-ins.
+type discoveredRegions struct {
+	enabled, unreachable []string
+	at                   time.Time
+}
+
+// regionsCacheTTL outlives the *Get calls of one sweep but not the gap
+// between scheduled ones.
+const regionsCacheTTL = 15 * time.Minute
+
+var (
+	regionsMu    sync.Mutex
+	regionsCache = map[string]discoveredRegions{}
+)
+
+// accountRegions returns the account's enabled and not-opted-in regions,
+// falling back to the default-enabled set when discovery fails. the fallback
+// is not cached: the next call tries DescribeRegions again.
+func (a Aws) accountRegions(ctx context.Context) (enabled, unreachable []string) {
+	key := accountCacheKey(ctx)
+	regionsMu.Lock()
+	found, ok := regionsCache[key]
+	regionsMu.Unlock()
+	if ok && time.Since(found.at) < regionsCacheTTL {
+		return found.enabled, found.unreachable
+	}
+	// concurrent misses may both discover; the later write wins, and both
+	// see an equally fresh answer.
+	enabled, unreachable, err := a.discoverRegions(ctx)
+	if err != nil {
+		core.Warnf(ctx, "AWS DescribeRegions failed: %v — falling back to the default regions", err)
+		return defaultRegions(), nil
+	}
+	regionsMu.Lock()
+	regionsCache[key] = discoveredRegions{enabled: enabled, unreachable: unreachable, at: time.Now()}
+	regionsMu.Unlock()
+	return enabled, unreachable
+}
+
+// discoveryRegion is the endpoint DescribeRegions is sent to. it is enabled
+// for every commercial account.
+const discoveryRegion = "us-east-1"
+
+// discoverRegions asks EC2 which regions exist for the account. regions it
+// has not opted in to are returned separately: every call into them fails
+// with AuthFailure / OptInRequired. both lists are sorted.
+func (a Aws) discoverRegions(ctx context.Context) (enabled, unreachable []string, err error) {
+	resp, err := a.ec2For(ctx, discoveryRegion).DescribeRegions(ctx, &ec2.DescribeRegionsInput{
+		AllRegions: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, region := range resp.Regions {
+		name := aws.ToString(region.RegionName)
+		if name == "" {
+			continue
+		}
+		if aws.ToString(region.OptInStatus) == "not-opted-in" {
+			unreachable = append(unreachable, name)
+			continue
+		}
+		enabled = append(enabled, name)
+	}
+	sort.Strings(enabled)
+	sort.Strings(unreachable)
+	return enabled, unreachable, nil
+}
+
+// selectRegions narrows the enabled regions by an --aws-regions spec. named
+// regions restrict the scan to themselves and "-<region>" drops one; an
+// empty spec keeps everything. unreachable regions, and named regions that
+// exist nowhere, come back as skips unless the spec leaves them out anyway.
+func selectRegions(enabled, unreachable []string, spec string) ([]string, []core.SkippedRegion) {
+	include := map[string]bool{}
+	exclude := map[string]bool{}
+	var named []string
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "" || entry == "-":
+		case strings.HasPrefix(entry, "-"):
+			exclude[entry[1:]] = true
+		case !include[entry]:
+			include[entry] = true
+			named = append(named, entry)
+		}
+	}
+	wanted := func(region string) bool {
+		return !exclude[region] && (len(include) == 0 || include[region])
+	}
+
+	known := map[string]bool{}
+	regions := make([]string, 0, len(enabled))
+	for _, region := range enabled {
+		known[region] = true
+		if wanted(region) {
+			regions = append(regions, region)
+		}
+	}
+	var skipped []core.SkippedRegion
+	for _, region := range unreachable {
+		known[region] = true
+		if wanted(region) {
+			skipped = append(skipped, core.SkippedRegion{Region: region, Reason: "not opted in"})
+		}
+	}
+	for _, region := range named {
+		if !known[region] && !exclude[region] {
+			skipped = append(skipped, core.SkippedRegion{Region: region, Reason: "unknown region"})
+		}
+	}
+	return regions, skipped
+}
+
+// defaultRegionConcurrency bounds parallel region scans when main did not
+// set core.AWSRegionConcurrencyKey.
+const defaultRegionConcurrency = 8
+
+// regionConcurrency is the number of regions scanned at once (at least 1).
+func regionConcurrency(ctx context.Context) int {
+	if n, ok := ctx.Value(core.AWSRegionConcurrencyKey).(int); ok && n > 0 {
+		return n
+	}
+	return defaultRegionConcurrency
+}
+
+// eachRegion calls scan for every region with at most regionConcurrency
+// scans in flight. scan gets the region's index so it can fill a per-region
+// slot; callers aggregate the slots in region order afterwards, which keeps
+// results deterministic whatever order the regions finish in. each scan's
+// Warnf / Writef output is buffered and replayed in region order as well, so
+// lines from concurrent regions never interleave.
+func (a Aws) eachRegion(ctx context.Context, regions []string, scan func(ctx context.Context, i int, region string)) {
+	outs := make([]bytes.Buffer, len(regions))
+	warns := make([]bytes.Buffer, len(regions))
+	sem := make(chan struct{}, regionConcurrency(ctx))
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			regionCtx := context.WithValue(ctx, core.OutWriterKey, io.Writer(&outs[i]))
+			regionCtx = context.WithValue(regionCtx, core.WarnWriterKey, io.Writer(&warns[i]))
+			scan(regionCtx, i, region)
+		}()
+	}
+	wg.Wait()
+
+	outW, _ := ctx.Value(core.OutWriterKey).(io.Writer)
+	warnW, _ := ctx.Value(core.WarnWriterKey).(io.Writer)
+	for i := range regions {
+		// best-effort writes — see core.Warnf.
+		if warnW != nil {
+			_, _ = warnW.Write(warns[i].Bytes())
+		}
+		if outW != nil {
+			_, _ = outW.Write(outs[i].Bytes())
+		}
 	}
-	return a.allRegions()
 }
 
 // mapEC2State canonicalises the EC2 InstanceStateName (e.g. "running",
@@ -149,335 +416,417 @@ func mapEC2State(name string) string {
 func (a Aws) ServersGet(ctx context.Context, vendorIDs []string, regions []string) ([]core.Server, error) {
 	results := make([]core.Server, 0, 0)
 	if regions == nil {
-		regions = a.regions()
+		regions = a.regions(ctx)
 	}
-	// collect per-region errors so we surface "all regions failed" rather than
-	// silently returning (nil, nil) like the old code did (B6).
+	regionServers := make([][]core.Server, len(regions))
+	regionScanErrs := make([]error, len(regions))
+	a.eachRegion(ctx, regions, func(ctx context.Context, i int, region string) {
+		regionServers[i], regionScanErrs[i] = a.regionServers(ctx, region, vendorIDs)
+	})
+	// pages read before a failure are kept, as they always were.
+	for i := range regions {
+		results = append(results, regionServers[i]...)
+	}
+	err := regionScanError(regions, regionScanErrs)
+	if err != nil && !core.IsPartial(err) {
+		return nil, err
+	}
+	return results, err
+}
+
+// regionScanError aggregates the per-region errors of a scan: nil when every
+// region answered, a *core.PartialError when only some did (callers return
+// it alongside the results they have), and "all regions failed" otherwise
+// rather than silently returning (nil, nil) like the old code did (B6).
+func regionScanError(regions []string, scanErrs []error) error {
+	var failed []string
 	var regionErrs []error
-	okCount := 0
-	for _, region := range regions {
-		client := a.ec2For(ctx, region)
-		// paginate over DescribeInstances via NextToken (B8).
-		var nextToken *string
-		regionOK := true
-		for {
-			out, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{NextToken: nextToken})
-			if err != nil {
-				regionErrs = append(regionErrs, fmt.Errorf("%s: %w", region, err))
-				regionOK = false
-				break
-			}
-			for _, reservation := range out.Reservations {
-				for _, instance := range reservation.Instances {
-					// guard: InstanceId may be nil on malformed responses (B7/nil-ptr).
-					if instance.InstanceId == nil {
-						core.Warnf(ctx, "skipping instance with nil InstanceId in %s", region)
-						continue
-					}
-					vendorID := *instance.InstanceId
-					if vendorIDs != nil {
-						found := false
-						for _, desiredVendor := range vendorIDs {
-							if vendorID == desiredVendor {
-								found = true
-								break
-							}
-						}
-						if !found {
-							continue
-						}
-					}
+	for i, region := range regions {
+		if scanErrs[i] != nil {
+			failed = append(failed, region)
+			regionErrs = append(regionErrs, fmt.Errorf("%s: %w", region, scanErrs[i]))
+		}
+	}
+	switch {
+	case len(regionErrs) == 0:
+		return nil
+	case len(regionErrs) == len(regions):
+		return fmt.Errorf("all regions failed: %w", errors.Join(regionErrs...))
+	}
+	return &core.PartialError{Regions: failed, Err: errors.Join(regionErrs...)}
+}
 
-					if len(instance.BlockDeviceMappings) == 0 {
-						continue
+// regionServers lists the live instances of one region, optionally filtered
+// to vendorIDs. on error it returns the pages read so far alongside it.
+func (a Aws) regionServers(ctx context.Context, region string, vendorIDs []string) ([]core.Server, error) {
+	var results []core.Server
+	client := a.ec2For(ctx, region)
+	// paginate over DescribeInstances via NextToken (B8).
+	var nextToken *string
+	for {
+		out, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{NextToken: nextToken})
+		if err != nil {
+			return results, err
+		}
+		for _, reservation := range out.Reservations {
+			for _, instance := range reservation.Instances {
+				// guard: InstanceId may be nil on malformed responses (B7/nil-ptr).
+				if instance.InstanceId == nil {
+					core.Warnf(ctx, "skipping instance with nil InstanceId in %s", region)
+					continue
+				}
+				vendorID := *instance.InstanceId
+				if vendorIDs != nil {
+					found := false
+					for _, desiredVendor := range vendorIDs {
+						if vendorID == desiredVendor {
+							found = true
+							break
+						}
 					}
-					// guard: Ebs or AttachTime may be nil; skip rather than panic.
-					if instance.BlockDeviceMappings[0].Ebs == nil || instance.BlockDeviceMappings[0].Ebs.AttachTime == nil {
-						core.Warnf(ctx, "skipping instance %s: nil Ebs/AttachTime", vendorID)
+					if !found {
 						continue
 					}
+				}
 
-					attachTime := *instance.BlockDeviceMappings[0].Ebs.AttachTime
-					age := time.Since(attachTime).Hours() / 24.0
-					name := vendorID
-					for _, tag := range instance.Tags {
-						// guard: tag Key/Value pointers may be nil.
-						if tag.Key == nil || tag.Value == nil {
-							continue
-						}
-						if *tag.Key == "Name" {
-							name = *tag.Value
-						}
-					}
-
-					// guard: State may be nil on some malformed responses.
-					if instance.State == nil {
-						core.Warnf(ctx, "skipping instance %s: nil State", vendorID)
+				// a zero age (no timestamp at all) is kept with a warning
+				// by the policy, like a missing Created elsewhere.
+				var age float64
+				since, ageSource := instanceSince(instance)
+				if !since.IsZero() {
+					age = time.Since(since).Hours() / 24.0
+				} else {
+					core.Warnf(ctx, "instance %s has no launch or attach time", vendorID)
+				}
+				name := vendorID
+				for _, tag := range instance.Tags {
+					// guard: tag Key/Value pointers may be nil.
+					if tag.Key == nil || tag.Value == nil {
 						continue
 					}
-					// map real EC2 state so downstream code (e.g. classic ELB
-					// InstanceCount) can exclude terminated/shutting-down.
-					state := mapEC2State(string(instance.State.Name))
-					if state == "UNKNOWN" {
-						core.Warnf(ctx, "instance %s in %s has unknown EC2 state %q — counting as live for safety", vendorID, region, instance.State.Name)
-					}
-					if state != "TERMINATED" && state != "SHUTTING-DOWN" {
-						tags := awsTagsToStrings(instance.Tags)
-						results = append(results, core.Server{VendorID: vendorID, Name: name, Age: age, Region: region, State: state, Tags: tags})
+					if *tag.Key == "Name" {
+						name = *tag.Value
 					}
 				}
+
+				// guard: State may be nil on some malformed responses.
+				if instance.State == nil {
+					core.Warnf(ctx, "skipping instance %s: nil State", vendorID)
+					continue
+				}
+				// map real EC2 state so downstream code (e.g. classic ELB
+				// InstanceCount) can exclude terminated/shutting-down.
+				state := mapEC2State(string(instance.State.Name))
+				if state == "UNKNOWN" {
+					core.Warnf(ctx, "instance %s in %s has unknown EC2 state %q — counting as live for safety", vendorID, region, instance.State.Name)
+				}
+				if state != "TERMINATED" && state != "SHUTTING-DOWN" {
+					tags := awsTagsToStrings(instance.Tags)
+					results = append(results, core.Server{VendorID: vendorID, Name: name, Age: age, AgeSource: ageSource, Region: region, State: state, Tags: tags})
+				}
 			}
-			// NextToken-based pagination; nil or empty ends the loop.
-			if out.NextToken == nil || *out.NextToken == "" {
-				break
-			}
-			nextToken = out.NextToken
 		}
-		if regionOK {
-			okCount++
+		// NextToken-based pagination; nil or empty ends the loop.
+		if out.NextToken == nil || *out.NextToken == "" {
+			break
 		}
-	}
-	// if we had at least one region succeed, return partial results (best-effort).
-	// if every region failed, surface an aggregated error (B6).
-	if okCount == 0 && len(regionErrs) > 0 {
-		return nil, fmt.Errorf("all regions failed: %w", errors.Join(regionErrs...))
+		nextToken = out.NextToken
 	}
 	return results, nil
 }
 
+// instanceSince is the earliest of an instance's launch time and the attach
+// times of its EBS volumes, and which of them it was. LaunchTime moves on
+// every stop and start, an attach time whenever a volume is swapped, so
+// neither alone dates the instance; instance-store instances only have the
+// launch time.
+func instanceSince(instance ec2types.Instance) (time.Time, string) {
+	var since time.Time
+	var source string
+	if instance.LaunchTime != nil {
+		since, source = *instance.LaunchTime, core.AgeSourceLaunch
+	}
+	for _, mapping := range instance.BlockDeviceMappings {
+		// guard: Ebs or AttachTime may be nil.
+		if mapping.Ebs == nil || mapping.Ebs.AttachTime == nil {
+			continue
+		}
+		if since.IsZero() || mapping.Ebs.AttachTime.Before(since) {
+			since, source = *mapping.Ebs.AttachTime, core.AgeSourceAttach
+		}
+	}
+	return since, source
+}
+
 // LoadBalancersGet return all load balancers in account
 func (a Aws) LoadBalancersGet(ctx context.Context, flagMock bool) ([]core.LoadBalancer, error) {
 	results := make([]core.LoadBalancer, 0, 0)
+	regions := a.regions(ctx)
+	regionLBs := make([][]core.LoadBalancer, len(regions))
+	regionScanErrs := make([][]error, len(regions))
+	regionOK := make([]bool, len(regions))
+	a.eachRegion(ctx, regions, func(ctx context.Context, i int, region string) {
+		regionLBs[i], regionScanErrs[i], regionOK[i] = a.regionLoadBalancers(ctx, region)
+	})
 	// track per-region errors so all-fail surfaces as an error (B6).
 	var regionErrs []error
+	var failed []string
 	okCount := 0
-	for _, region := range a.regions() {
-		// per-region granularity: a region counts OK if EITHER the classic ELB
-		// scan OR the ALB scan returned data. it only fails when BOTH calls
-		// errored. previously a single-service failure flipped `regionFailed`
-		// for the whole region, so a partial AWS outage was falsely aggregated
-		// as "all regions failed".
-		elbOK := true
-		albOK := true
-
-		elb := a.elbFor(ctx, region)
-		// paginate classic ELB DescribeLoadBalancers via Marker (B8).
-		var elbMarker *string
-		for {
-			elbOut, err := elb.DescribeLoadBalancers(ctx, &elasticloadbalancing.DescribeLoadBalancersInput{Marker: elbMarker})
-			if err != nil {
-				regionErrs = append(regionErrs, fmt.Errorf("%s elb: %w", region, err))
-				elbOK = false
-				break
+	for i, region := range regions {
+		results = append(results, regionLBs[i]...)
+		regionErrs = append(regionErrs, regionScanErrs[i]...)
+		if len(regionScanErrs[i]) > 0 {
+			failed = append(failed, region)
+		}
+		if regionOK[i] {
+			okCount++
+		}
+	}
+	// aggregate errors if no region succeeded (B6 + panel round-3 C4).
+	// also error on okCount==0 with empty regionErrs (no regions configured
+	// or every region returned an empty success that yielded no data) so we
+	// never silently return (nil, nil) again.
+	if okCount == 0 {
+		if len(regionErrs) > 0 {
+			return nil, fmt.Errorf("all regions failed: %w", errors.Join(regionErrs...))
+		}
+		return nil, errors.New("no regions returned a successful AWS response")
+	}
+	// a region where only one of ELB / ALB answered still failed in part:
+	// its load balancers of the other kind were not swept.
+	if len(regionErrs) > 0 {
+		return results, &core.PartialError{Regions: failed, Err: errors.Join(regionErrs...)}
+	}
+	return results, nil
+}
+
+// regionLoadBalancers lists the classic and v2 load balancers of one region.
+// ok reports whether the region answered at all; errs holds the per-service
+// failures either way.
+func (a Aws) regionLoadBalancers(ctx context.Context, region string) (results []core.LoadBalancer, regionErrs []error, ok bool) {
+	// per-region granularity: a region counts OK if EITHER the classic ELB
+	// scan OR the ALB scan returned data. it only fails when BOTH calls
+	// errored. previously a single-service failure flipped `regionFailed`
+	// for the whole region, so a partial AWS outage was falsely aggregated
+	// as "all regions failed".
+	elbOK := true
+	albOK := true
+
+	elb := a.elbFor(ctx, region)
+	// classic ELBs are collected first; members[i] holds the instance IDs
+	// registered with classicLBs[i].
+	var classicLBs []core.LoadBalancer
+	var members [][]string
+	var allMembers []string
+	// paginate classic ELB DescribeLoadBalancers via Marker (B8).
+	var elbMarker *string
+	for {
+		elbOut, err := elb.DescribeLoadBalancers(ctx, &elasticloadbalancing.DescribeLoadBalancersInput{Marker: elbMarker})
+		if err != nil {
+			regionErrs = append(regionErrs, fmt.Errorf("%s elb: %w", region, err))
+			elbOK = false
+			break
+		}
+		for idx := range elbOut.LoadBalancerDescriptions {
+			loadBalancer := elbOut.LoadBalancerDescriptions[idx]
+			// guard: CreatedTime or LoadBalancerName may be nil.
+			if loadBalancer.CreatedTime == nil || loadBalancer.LoadBalancerName == nil {
+				core.Warnf(ctx, "skipping classic LB with nil CreatedTime/Name in %s", region)
+				continue
 			}
-			for idx := range elbOut.LoadBalancerDescriptions {
-				loadBalancer := elbOut.LoadBalancerDescriptions[idx]
-				// guard: CreatedTime or LoadBalancerName may be nil.
-				if loadBalancer.CreatedTime == nil || loadBalancer.LoadBalancerName == nil {
-					core.Warnf(ctx, "skipping classic LB with nil CreatedTime/Name in %s", region)
+			age := time.Since(*loadBalancer.CreatedTime).Hours() / 24.0
+			name := loadBalancer.LoadBalancerName
+			var vendorIDs []string
+			for _, instance := range loadBalancer.Instances {
+				if instance.InstanceId == nil {
 					continue
 				}
-				age := time.Since(*loadBalancer.CreatedTime).Hours() / 24.0
-				name := loadBalancer.LoadBalancerName
-				var vendorIDs []string
-				for _, instance := range loadBalancer.Instances {
-					if instance.InstanceId == nil {
-						continue
-					}
-					vendorIDs = append(vendorIDs, *instance.InstanceId)
-				}
-				// determine InstanceCount with a fail-safe contract:
-				//   >= 0 → known live-member count
-				//   -1   → unknown (treat as "skip" downstream, never DEAD)
-				// previously this called ServersGet unconditionally and
-				// discarded the error, which let a transient EC2 outage
-				// flip a live ELB to InstanceCount=0 → DEAD → deletion.
-				// also: an empty vendorIDs slice would trigger a full-region
-				// DescribeInstances scan and miscount membership — short-
-				// circuit to 0 in that case.
-				var instanceCount int
-				if len(vendorIDs) == 0 {
-					instanceCount = 0
-				} else {
-					servers, sErr := a.ServersGet(ctx, vendorIDs, []string{region})
-					if sErr != nil {
-						// log + mark unknown so deleteLoadBalancers skips
-						// rather than treating zero servers as DEAD.
-						core.Warnf(ctx, "ServersGet failed for ELB %s in %s: %v — marking instance count unknown", *name, region, sErr)
-						instanceCount = -1
-					} else {
-						// ServersGet excludes terminated/shutting-down via
-						// mapEC2State, so each returned server is a live member.
-						instanceCount = len(servers)
-					}
-				}
-				// fetch tags for this classic ELB so isPermanent /
-				// hasSampleTag can match on them. previously v1 LBs were
-				// constructed with empty Tags, so a permanent-tagged ELB
-				// could be deleted on name alone (panel finding C1).
-				// DescribeTags errors are non-fatal: emit a Warnf and proceed
-				// with empty tags rather than aborting the region.
-				var lbTags []string
-				tagsOut, tagsErr := elb.DescribeTags(ctx, &elasticloadbalancing.DescribeTagsInput{LoadBalancerNames: []string{*name}})
-				if tagsErr != nil {
-					core.Warnf(ctx, "DescribeTags failed for classic ELB %s in %s: %v", *name, region, tagsErr)
-				} else {
-					for _, td := range tagsOut.TagDescriptions {
-						lbTags = append(lbTags, awsClassicTagsToStrings(td.Tags)...)
-					}
+				vendorIDs = append(vendorIDs, *instance.InstanceId)
+			}
+			members = append(members, vendorIDs)
+			allMembers = append(allMembers, vendorIDs...)
+			// fetch tags for this classic ELB so isPermanent /
+			// hasSampleTag can match on them. previously v1 LBs were
+			// constructed with empty Tags, so a permanent-tagged ELB
+			// could be deleted on name alone (panel finding C1).
+			// DescribeTags errors are non-fatal: emit a Warnf and proceed
+			// with empty tags rather than aborting the region.
+			var lbTags []string
+			tagsOut, tagsErr := elb.DescribeTags(ctx, &elasticloadbalancing.DescribeTagsInput{LoadBalancerNames: []string{*name}})
+			if tagsErr != nil {
+				core.Warnf(ctx, "DescribeTags failed for classic ELB %s in %s: %v", *name, region, tagsErr)
+			} else {
+				for _, td := range tagsOut.TagDescriptions {
+					lbTags = append(lbTags, awsClassicTagsToStrings(td.Tags)...)
 				}
-				results = append(results, core.LoadBalancer{Name: *name, Age: age, InstanceCount: instanceCount, Region: region, Type: "elb", Tags: lbTags})
 			}
-			if elbOut.NextMarker == nil || *elbOut.NextMarker == "" {
-				break
+			classicLBs = append(classicLBs, core.LoadBalancer{Name: *name, Age: age, Region: region, Type: "elb", Tags: lbTags})
+		}
+		if elbOut.NextMarker == nil || *elbOut.NextMarker == "" {
+			break
+		}
+		elbMarker = elbOut.NextMarker
+	}
+	// determine InstanceCount with a fail-safe contract:
+	//   >= 0 → known live-member count
+	//   -1   → unknown (treat as "skip" downstream, never DEAD)
+	// previously this called ServersGet unconditionally and discarded the
+	// error, which let a transient EC2 outage flip a live ELB to
+	// InstanceCount=0 → DEAD → deletion. an ELB without members
+	// short-circuits to 0 — an empty vendorIDs filter would match the whole
+	// region. one ServersGet covers every ELB of the region rather than
+	// rescanning DescribeInstances per ELB.
+	if len(allMembers) > 0 {
+		servers, sErr := a.ServersGet(ctx, allMembers, []string{region})
+		// ServersGet excludes terminated/shutting-down via mapEC2State, so
+		// each returned server is a live member.
+		live := make(map[string]bool, len(servers))
+		for _, server := range servers {
+			live[server.VendorID] = true
+		}
+		for i := range classicLBs {
+			if len(members[i]) == 0 {
+				continue
+			}
+			if sErr != nil {
+				// log + mark unknown so deleteLoadBalancers skips rather
+				// than treating zero servers as DEAD.
+				core.Warnf(ctx, "ServersGet failed for ELB %s in %s: %v — marking instance count unknown", classicLBs[i].Name, region, sErr)
+				classicLBs[i].InstanceCount = -1
+				continue
+			}
+			for _, vendorID := range members[i] {
+				if live[vendorID] {
+					classicLBs[i].InstanceCount++
+				}
 			}
-			elbMarker = elbOut.NextMarker
 		}
+	}
+	results = append(results, classicLBs...)
 
-		// elastic load balancing v2 (ALB/NLB)
-		alb := a.albFor(ctx, region)
-		// paginate DescribeLoadBalancers via Marker (B8).
-		var albMarker *string
-		for {
-			albOut, err := alb.DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{Marker: albMarker})
-			if err != nil {
-				regionErrs = append(regionErrs, fmt.Errorf("%s alb: %w", region, err))
-				albOK = false
-				break
+	// elastic load balancing v2 (ALB/NLB)
+	alb := a.albFor(ctx, region)
+	// paginate DescribeLoadBalancers via Marker (B8).
+	var albMarker *string
+	for {
+		albOut, err := alb.DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{Marker: albMarker})
+		if err != nil {
+			regionErrs = append(regionErrs, fmt.Errorf("%s alb: %w", region, err))
+			albOK = false
+			break
+		}
+		for idx := range albOut.LoadBalancers {
+			loadBalancer := albOut.LoadBalancers[idx]
+			// guard against nil CreatedTime / ARN / Name.
+			if loadBalancer.CreatedTime == nil || loadBalancer.LoadBalancerArn == nil || loadBalancer.LoadBalancerName == nil {
+				core.Warnf(ctx, "skipping ALB with nil CreatedTime/ARN/Name in %s", region)
+				continue
 			}
-			for idx := range albOut.LoadBalancers {
-				loadBalancer := albOut.LoadBalancers[idx]
-				// guard against nil CreatedTime / ARN / Name.
-				if loadBalancer.CreatedTime == nil || loadBalancer.LoadBalancerArn == nil || loadBalancer.LoadBalancerName == nil {
-					core.Warnf(ctx, "skipping ALB with nil CreatedTime/ARN/Name in %s", region)
-					continue
-				}
-				age := time.Since(*loadBalancer.CreatedTime).Hours() / 24.0
-				name := loadBalancer.LoadBalancerName
-				loadBalancerArn := loadBalancer.LoadBalancerArn
-
-				var lbTags []string
-				tagsOutput, err := alb.DescribeTags(ctx, &elasticloadbalancingv2.DescribeTagsInput{ResourceArns: []string{*loadBalancerArn}})
-				if err == nil {
-					for _, tagDescription := range tagsOutput.TagDescriptions {
-						lbTags = awsAlbTagsToStrings(tagDescription.Tags)
-						for _, tag := range tagDescription.Tags {
-							if tag.Key == nil {
-								continue
-							}
-							if *tag.Key == "C66-STACK" && tag.Value != nil {
-								name = tag.Value
-							}
-						}
-					}
-				}
+			age := time.Since(*loadBalancer.CreatedTime).Hours() / 24.0
+			name := loadBalancer.LoadBalancerName
+			loadBalancerArn := loadBalancer.LoadBalancerArn
 
-				// paginate DescribeListeners (B8) — surface errors via Warnf
-				// instead of silently swallowing them.
-				var listenerArns []string
-				var lstMarker *string
-				for {
-					listenerOutput, err := alb.DescribeListeners(ctx, &elasticloadbalancingv2.DescribeListenersInput{LoadBalancerArn: loadBalancerArn, Marker: lstMarker})
-					if err != nil {
-						core.Warnf(ctx, "DescribeListeners failed for %s: %s", *loadBalancerArn, err.Error())
-						break
-					}
-					for _, listener := range listenerOutput.Listeners {
-						if listener.ListenerArn == nil {
+			var lbTags []string
+			tagsOutput, err := alb.DescribeTags(ctx, &elasticloadbalancingv2.DescribeTagsInput{ResourceArns: []string{*loadBalancerArn}})
+			if err == nil {
+				for _, tagDescription := range tagsOutput.TagDescriptions {
+					lbTags = awsAlbTagsToStrings(tagDescription.Tags)
+					for _, tag := range tagDescription.Tags {
+						if tag.Key == nil {
 							continue
 						}
-						listenerArns = append(listenerArns, *listener.ListenerArn)
-					}
-					if listenerOutput.NextMarker == nil || *listenerOutput.NextMarker == "" {
-						break
+						if *tag.Key == "C66-STACK" && tag.Value != nil {
+							name = tag.Value
+						}
 					}
-					lstMarker = listenerOutput.NextMarker
 				}
+			}
 
-				// paginate per-LB DescribeTargetGroups (B8) — surface errors.
-				var targetGroupArns []string
-				var tgMarker *string
-				for {
-					targetGroupOutput, err := alb.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{LoadBalancerArn: loadBalancerArn, Marker: tgMarker})
-					if err != nil {
-						core.Warnf(ctx, "DescribeTargetGroups failed for %s: %s", *loadBalancerArn, err.Error())
-						break
-					}
-					for _, targetGroup := range targetGroupOutput.TargetGroups {
-						if targetGroup.TargetGroupArn == nil {
-							continue
-						}
-						targetGroupArns = append(targetGroupArns, *targetGroup.TargetGroupArn)
-					}
-					if targetGroupOutput.NextMarker == nil || *targetGroupOutput.NextMarker == "" {
-						break
+			// paginate DescribeListeners (B8) — surface errors via Warnf
+			// instead of silently swallowing them.
+			var listenerArns []string
+			var lstMarker *string
+			for {
+				listenerOutput, err := alb.DescribeListeners(ctx, &elasticloadbalancingv2.DescribeListenersInput{LoadBalancerArn: loadBalancerArn, Marker: lstMarker})
+				if err != nil {
+					core.Warnf(ctx, "DescribeListeners failed for %s: %s", *loadBalancerArn, err.Error())
+					break
+				}
+				for _, listener := range listenerOutput.Listeners {
+					if listener.ListenerArn == nil {
+						continue
 					}
-					tgMarker = targetGroupOutput.NextMarker
+					listenerArns = append(listenerArns, *listener.ListenerArn)
+				}
+				if listenerOutput.NextMarker == nil || *listenerOutput.NextMarker == "" {
+					break
 				}
+				lstMarker = listenerOutput.NextMarker
+			}
 
-				// count unique instances across all target groups; on error,
-				// assume instances exist to avoid accidental deletion.
-				seenInstances := make(map[string]bool)
-				healthCheckFailed := false
-				for _, tgArn := range targetGroupArns {
-					tgArnCopy := tgArn
-					healthOutput, err := alb.DescribeTargetHealth(ctx, &elasticloadbalancingv2.DescribeTargetHealthInput{
-						TargetGroupArn: &tgArnCopy,
-					})
-					if err != nil {
-						healthCheckFailed = true
-						break
-					}
-					for _, thd := range healthOutput.TargetHealthDescriptions {
-						if thd.Target != nil && thd.Target.Id != nil {
-							seenInstances[*thd.Target.Id] = true
-						}
+			// paginate per-LB DescribeTargetGroups (B8) — surface errors.
+			var targetGroupArns []string
+			var tgMarker *string
+			for {
+				targetGroupOutput, err := alb.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{LoadBalancerArn: loadBalancerArn, Marker: tgMarker})
+				if err != nil {
+					core.Warnf(ctx, "DescribeTargetGroups failed for %s: %s", *loadBalancerArn, err.Error())
+					break
+				}
+				for _, targetGroup := range targetGroupOutput.TargetGroups {
+					if targetGroup.TargetGroupArn == nil {
+						continue
 					}
+					targetGroupArns = append(targetGroupArns, *targetGroup.TargetGroupArn)
 				}
-				instanceCount := len(seenInstances)
-				if healthCheckFailed {
-					instanceCount = -1
+				if targetGroupOutput.NextMarker == nil || *targetGroupOutput.NextMarker == "" {
+					break
 				}
-				results = append(results, core.LoadBalancer{
-					Name:            *name,
-					Age:             age,
-					InstanceCount:   instanceCount,
-					Region:          region,
-					Type:            "alb",
-					Tags:            lbTags,
-					LoadBalancerArn: *loadBalancerArn,
-					ListenerArns:    listenerArns,
-					TargetGroupArns: targetGroupArns,
+				tgMarker = targetGroupOutput.NextMarker
+			}
+
+			// count unique instances across all target groups; on error,
+			// assume instances exist to avoid accidental deletion.
+			seenInstances := make(map[string]bool)
+			healthCheckFailed := false
+			for _, tgArn := range targetGroupArns {
+				tgArnCopy := tgArn
+				healthOutput, err := alb.DescribeTargetHealth(ctx, &elasticloadbalancingv2.DescribeTargetHealthInput{
+					TargetGroupArn: &tgArnCopy,
 				})
+				if err != nil {
+					healthCheckFailed = true
+					break
+				}
+				for _, thd := range healthOutput.TargetHealthDescriptions {
+					if thd.Target != nil && thd.Target.Id != nil {
+						seenInstances[*thd.Target.Id] = true
+					}
+				}
 			}
-			if albOut.NextMarker == nil || *albOut.NextMarker == "" {
-				break
+			instanceCount := len(seenInstances)
+			if healthCheckFailed {
+				instanceCount = -1
 			}
-			albMarker = albOut.NextMarker
+			results = append(results, core.LoadBalancer{
+				Name:            *name,
+				Age:             age,
+				InstanceCount:   instanceCount,
+				Region:          region,
+				Type:            "alb",
+				Tags:            lbTags,
+				LoadBalancerArn: *loadBalancerArn,
+				ListenerArns:    listenerArns,
+				TargetGroupArns: targetGroupArns,
+			})
 		}
-
-		// orphan-TG sweep removed (panel round-3): the prior tag-based
-		// MARKED.AT design was attacker-forgeable via ec2:CreateTags. A
-		// proper redesign (state in SSM/DDB or HMAC-signed marks gated by an
-		// operator-provided signing key) is tracked as a follow-up PR.
-		// We still surface orphan TGs in logs but do not mark or delete them.
-		_ = flagMock
-		// region counts as OK if either scan returned data.
-		if elbOK || albOK {
-			okCount++
+		if albOut.NextMarker == nil || *albOut.NextMarker == "" {
+			break
 		}
+		albMarker = albOut.NextMarker
 	}
-	// aggregate errors if no region succeeded (B6 + panel round-3 C4).
-	// also error on okCount==0 with empty regionErrs (no regions configured
-	// or every region returned an empty success that yielded no data) so we
-	// never silently return (nil, nil) again.
-	if okCount == 0 {
-		if len(regionErrs) > 0 {
-			return nil, fmt.Errorf("all regions failed: %w", errors.Join(regionErrs...))
-		}
-		return nil, errors.New("no regions returned a successful AWS response")
-	}
-	return results, nil
+	// region counts as OK if either scan returned data.
+	return results, regionErrs, elbOK || albOK
 }
 
 // ServerDelete remove the specified server
@@ -562,41 +911,507 @@ func (a Aws) LoadBalancerDelete(ctx context.Context, loadBalancer core.LoadBalan
 	return errors.New("unrecognised LB type")
 }
 
-// ServerStop is not implemented for AWS; janitor only deletes.
+// TargetGroupsGet returns the ELBv2 target groups of every region that no
+// load balancer references. groups a load balancer still uses are deleted
+// along with it by LoadBalancerDelete; these have nothing left to go with.
+func (a Aws) TargetGroupsGet(ctx context.Context) ([]core.TargetGroup, error) {
+	results := make([]core.TargetGroup, 0)
+	regions := a.regions(ctx)
+	regionGroups := make([][]core.TargetGroup, len(regions))
+	regionScanErrs := make([]error, len(regions))
+	a.eachRegion(ctx, regions, func(ctx context.Context, i int, region string) {
+		regionGroups[i], regionScanErrs[i] = a.regionTargetGroups(ctx, region)
+	})
+	for i := range regions {
+		results = append(results, regionGroups[i]...)
+	}
+	err := regionScanError(regions, regionScanErrs)
+	if err != nil && !core.IsPartial(err) {
+		return nil, err
+	}
+	return results, err
+}
+
+// describeTagsBatch is the most resource ARNs one ELBv2 DescribeTags call
+// accepts.
+const describeTagsBatch = 20
+
+// regionTargetGroups lists the orphan target groups of one region with their
+// tags. it returns nothing unless the tags were read as well: without them
+// neither a deletion mark nor a permanent marker can be seen.
+func (a Aws) regionTargetGroups(ctx context.Context, region string) ([]core.TargetGroup, error) {
+	alb := a.albFor(ctx, region)
+	var results []core.TargetGroup
+	var marker *string
+	for {
+		out, err := alb.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{Marker: marker})
+		if err != nil {
+			return nil, err
+		}
+		for _, targetGroup := range out.TargetGroups {
+			if targetGroup.TargetGroupArn == nil || len(targetGroup.LoadBalancerArns) > 0 {
+				continue
+			}
+			results = append(results, core.TargetGroup{
+				VendorID: *targetGroup.TargetGroupArn,
+				Name:     aws.ToString(targetGroup.TargetGroupName),
+				Region:   region,
+			})
+		}
+		if out.NextMarker == nil || *out.NextMarker == "" {
+			break
+		}
+		marker = out.NextMarker
+	}
+
+	byArn := make(map[string]int, len(results))
+	for i, targetGroup := range results {
+		byArn[targetGroup.VendorID] = i
+	}
+	for start := 0; start < len(results); start += describeTagsBatch {
+		arns := make([]string, 0, describeTagsBatch)
+		for _, targetGroup := range results[start:min(start+describeTagsBatch, len(results))] {
+			arns = append(arns, targetGroup.VendorID)
+		}
+		out, err := alb.DescribeTags(ctx, &elasticloadbalancingv2.DescribeTagsInput{ResourceArns: arns})
+		if err != nil {
+			return nil, fmt.Errorf("DescribeTags for target groups: %w", err)
+		}
+		for _, tagDescription := range out.TagDescriptions {
+			if i, ok := byArn[aws.ToString(tagDescription.ResourceArn)]; ok {
+				results[i].Tags = append(results[i].Tags, awsAlbTagsToStrings(tagDescription.Tags)...)
+			}
+		}
+	}
+	return results, nil
+}
+
+// TargetGroupMark tags the target group with a deletion mark. ELBv2 tag keys
+// are unique, so this replaces any mark that failed verification.
+func (a Aws) TargetGroupMark(ctx context.Context, targetGroup core.TargetGroup, value string) error {
+	_, err := a.albFor(ctx, targetGroup.Region).AddTags(ctx, &elasticloadbalancingv2.AddTagsInput{
+		ResourceArns: []string{targetGroup.VendorID},
+		Tags:         []elasticloadbalancingv2types.Tag{{Key: aws.String(core.MarkTagKey), Value: aws.String(value)}},
+	})
+	return err
+}
+
+// TargetGroupDelete deletes an orphan target group. one a load balancer
+// picked up since the scan is rejected with ResourceInUse.
+func (a Aws) TargetGroupDelete(ctx context.Context, targetGroup core.TargetGroup) error {
+	_, err := a.albFor(ctx, targetGroup.Region).DeleteTargetGroup(ctx, &elasticloadbalancingv2.DeleteTargetGroupInput{
+		TargetGroupArn: aws.String(targetGroup.VendorID),
+	})
+	return err
+}
+
+// ServerStop powers off the instance. EBS-backed roots survive the stop;
+// instance-store-backed instances cannot be stopped and AWS rejects the call.
 func (a Aws) ServerStop(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	client := a.ec2For(ctx, server.Region)
+	_, err := client.StopInstances(ctx, &ec2.StopInstancesInput{
+		InstanceIds: []string{server.VendorID},
+		DryRun:      aws.Bool(false),
+	})
+	return err
 }
 
-// ServerStart is not implemented for AWS; janitor only deletes.
+// ServerStart powers a stopped instance back on.
 func (a Aws) ServerStart(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	client := a.ec2For(ctx, server.Region)
+	_, err := client.StartInstances(ctx, &ec2.StartInstancesInput{
+		InstanceIds: []string{server.VendorID},
+		DryRun:      aws.Bool(false),
+	})
+	return err
+}
+
+// ServerMark sets the quarantine mark tag on the instance. CreateTags
+// overwrites an existing value for the same key, so a re-mark replaces a mark
+// that failed verification.
+func (a Aws) ServerMark(ctx context.Context, server core.Server, value string) error {
+	client := a.ec2For(ctx, server.Region)
+	_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{server.VendorID},
+		Tags:      []ec2types.Tag{{Key: aws.String(core.MarkTagKey), Value: aws.String(value)}},
+		DryRun:    aws.Bool(false),
+	})
+	return err
+}
+
+// LoadBalancerMark sets the quarantine mark tag on a classic ELB (by name) or
+// an ALB/NLB (by ARN). both AddTags calls overwrite an existing key.
+func (a Aws) LoadBalancerMark(ctx context.Context, loadBalancer core.LoadBalancer, value string) error {
+	switch loadBalancer.Type {
+	case "elb":
+		_, err := a.elbFor(ctx, loadBalancer.Region).AddTags(ctx, &elasticloadbalancing.AddTagsInput{
+			LoadBalancerNames: []string{loadBalancer.Name},
+			Tags:              []elasticloadbalancingtypes.Tag{{Key: aws.String(core.MarkTagKey), Value: aws.String(value)}},
+		})
+		return err
+	case "alb":
+		_, err := a.albFor(ctx, loadBalancer.Region).AddTags(ctx, &elasticloadbalancingv2.AddTagsInput{
+			ResourceArns: []string{loadBalancer.LoadBalancerArn},
+			Tags:         []elasticloadbalancingv2types.Tag{{Key: aws.String(core.MarkTagKey), Value: aws.String(value)}},
+		})
+		return err
+	}
+	return errors.New("unrecognised LB type")
 }
 
-// SshKeysGet is not implemented for AWS; account-wide key-pair management
-// isn't something janitor manages yet.
+// SshKeysGet returns the EC2 key pairs of every region. key pairs are
+// regional, so each carries its Region and main applies keep-last-N per
+// region. DescribeKeyPairs is not paginated.
 func (a Aws) SshKeysGet(ctx context.Context) ([]core.SshKey, error) {
-	return nil, core.ErrUnsupported
+	results := make([]core.SshKey, 0)
+	regions := a.regions(ctx)
+	regionKeys := make([][]core.SshKey, len(regions))
+	regionScanErrs := make([]error, len(regions))
+	a.eachRegion(ctx, regions, func(ctx context.Context, i int, region string) {
+		regionKeys[i], regionScanErrs[i] = a.regionSshKeys(ctx, region)
+	})
+	for i := range regions {
+		results = append(results, regionKeys[i]...)
+	}
+	err := regionScanError(regions, regionScanErrs)
+	if err != nil && !core.IsPartial(err) {
+		return nil, err
+	}
+	return results, err
+}
+
+// regionSshKeys lists the key pairs of one region.
+func (a Aws) regionSshKeys(ctx context.Context, region string) ([]core.SshKey, error) {
+	out, err := a.ec2For(ctx, region).DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{})
+	if err != nil {
+		return nil, err
+	}
+	var results []core.SshKey
+	for _, keyPair := range out.KeyPairs {
+		// delete goes by KeyPairId; a key without one can't be targeted
+		// unambiguously, so don't offer it up for deletion at all.
+		if keyPair.KeyPairId == nil || keyPair.KeyName == nil {
+			core.Warnf(ctx, "skipping key pair with nil KeyPairId/KeyName in %s", region)
+			continue
+		}
+		key := core.SshKey{VendorID: *keyPair.KeyPairId, Name: *keyPair.KeyName, Region: region, Tags: awsTagsToStrings(keyPair.Tags)}
+		if keyPair.CreateTime != nil {
+			key.Created = *keyPair.CreateTime
+			key.Age = time.Since(key.Created).Hours() / 24.0
+		}
+		results = append(results, key)
+	}
+	return results, nil
 }
 
-// SshKeyDelete is not implemented for AWS.
+// SshKeyDelete deletes the key pair by ID in its region. instances already
+// launched with the key keep working; only new launches lose it.
 func (a Aws) SshKeyDelete(ctx context.Context, sshKey core.SshKey) error {
-	return core.ErrUnsupported
+	client := a.ec2For(ctx, sshKey.Region)
+	_, err := client.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{
+		KeyPairId: aws.String(sshKey.VendorID),
+		DryRun:    aws.Bool(false),
+	})
+	return err
 }
 
-// VolumesGet is not implemented for AWS yet.
+// VolumesGet returns every EBS volume in every region. a volume is Attached
+// unless its state is "available" — in-use, creating and deleting volumes
+// are all left alone by the default policy.
 func (a Aws) VolumesGet(ctx context.Context) ([]core.Volume, error) {
-	return nil, core.ErrUnsupported
+	results := make([]core.Volume, 0)
+	regions := a.regions(ctx)
+	regionVolumes := make([][]core.Volume, len(regions))
+	regionScanErrs := make([]error, len(regions))
+	a.eachRegion(ctx, regions, func(ctx context.Context, i int, region string) {
+		regionVolumes[i], regionScanErrs[i] = a.regionVolumes(ctx, region)
+	})
+	// same best-effort aggregation as ServersGet: partial results when at
+	// least one region answered, an error only when all of them failed.
+	for i := range regions {
+		results = append(results, regionVolumes[i]...)
+	}
+	err := regionScanError(regions, regionScanErrs)
+	if err != nil && !core.IsPartial(err) {
+		return nil, err
+	}
+	return results, err
+}
+
+// regionVolumes lists the EBS volumes of one region. pages read before a
+// failure are returned with the error.
+func (a Aws) regionVolumes(ctx context.Context, region string) ([]core.Volume, error) {
+	client := a.ec2For(ctx, region)
+	var results []core.Volume
+	var nextToken *string
+	for {
+		out, err := client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{NextToken: nextToken})
+		if err != nil {
+			return results, err
+		}
+		for _, volume := range out.Volumes {
+			if volume.VolumeId == nil {
+				core.Warnf(ctx, "skipping volume with nil VolumeId in %s", region)
+				continue
+			}
+			vendorID := *volume.VolumeId
+			// missing CreateTime → Age=0, which the policy treats as too
+			// new to delete (fail-safe, like the Hetzner zero-time guard).
+			var age float64
+			if volume.CreateTime != nil {
+				age = time.Since(*volume.CreateTime).Hours() / 24.0
+			} else {
+				core.Warnf(ctx, "missing CreateTime for volume %s in %s", vendorID, region)
+			}
+			name := vendorID
+			for _, tag := range volume.Tags {
+				if tag.Key != nil && tag.Value != nil && *tag.Key == "Name" {
+					name = *tag.Value
+				}
+			}
+			results = append(results, core.Volume{
+				VendorID: vendorID,
+				Name:     name,
+				Age:      age,
+				Region:   region,
+				Attached: volume.State != ec2types.VolumeStateAvailable,
+				Tags:     awsTagsToStrings(volume.Tags),
+			})
+		}
+		if out.NextToken == nil || *out.NextToken == "" {
+			return results, nil
+		}
+		nextToken = out.NextToken
+	}
 }
 
-// VolumeDelete is not implemented for AWS yet.
+// VolumeDelete deletes the EBS volume in its region. AWS rejects the call for
+// a volume that was attached since it was listed (VolumeInUse).
 func (a Aws) VolumeDelete(ctx context.Context, volume core.Volume) error {
-	return core.ErrUnsupported
+	client := a.ec2For(ctx, volume.Region)
+	_, err := client.DeleteVolume(ctx, &ec2.DeleteVolumeInput{
+		VolumeId: aws.String(volume.VendorID),
+		DryRun:   aws.Bool(false),
+	})
+	return err
+}
+
+// VolumeMark sets the quarantine mark tag on the EBS volume
+func (a Aws) VolumeMark(ctx context.Context, volume core.Volume, value string) error {
+	client := a.ec2For(ctx, volume.Region)
+	_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{volume.VendorID},
+		Tags:      []ec2types.Tag{{Key: aws.String(core.MarkTagKey), Value: aws.String(value)}},
+		DryRun:    aws.Bool(false),
+	})
+	return err
+}
+
+// SnapshotsGet returns the AMIs and EBS snapshots owned by the account in
+// every region. a snapshot backing one of the listed AMIs is InUse. if the
+// AMI listing of a region fails its snapshots are not listed either — without
+// the references every snapshot would look free to delete.
+func (a Aws) SnapshotsGet(ctx context.Context) ([]core.Snapshot, error) {
+	results := make([]core.Snapshot, 0)
+	regions := a.regions(ctx)
+	regionSnapshots := make([][]core.Snapshot, len(regions))
+	regionScanErrs := make([]error, len(regions))
+	a.eachRegion(ctx, regions, func(ctx context.Context, i int, region string) {
+		regionSnapshots[i], regionScanErrs[i] = a.regionSnapshots(ctx, region)
+	})
+	for i := range regions {
+		results = append(results, regionSnapshots[i]...)
+	}
+	err := regionScanError(regions, regionScanErrs)
+	if err != nil && !core.IsPartial(err) {
+		return nil, err
+	}
+	return results, err
+}
+
+// regionSnapshots lists the self-owned AMIs and EBS snapshots of one region.
+// it returns nothing unless both listings completed.
+func (a Aws) regionSnapshots(ctx context.Context, region string) ([]core.Snapshot, error) {
+	client := a.ec2For(ctx, region)
+	var results []core.Snapshot
+	referenced := map[string]bool{}
+
+	var nextToken *string
+	for {
+		out, err := client.DescribeImages(ctx, &ec2.DescribeImagesInput{Owners: []string{"self"}, NextToken: nextToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range out.Images {
+			for _, mapping := range image.BlockDeviceMappings {
+				if mapping.Ebs != nil && mapping.Ebs.SnapshotId != nil {
+					referenced[*mapping.Ebs.SnapshotId] = true
+				}
+			}
+			if image.ImageId == nil {
+				core.Warnf(ctx, "skipping image with nil ImageId in %s", region)
+				continue
+			}
+			imageID := *image.ImageId
+			// CreationDate is an ISO-8601 string, unlike every other
+			// timestamp in the EC2 API. unparseable → Age=0 (WARN by policy).
+			var age float64
+			if createdAt, err := time.Parse(time.RFC3339, aws.ToString(image.CreationDate)); err == nil {
+				age = time.Since(createdAt).Hours() / 24.0
+			} else {
+				core.Warnf(ctx, "unparseable CreationDate %q for image %s in %s", aws.ToString(image.CreationDate), imageID, region)
+			}
+			name := aws.ToString(image.Name)
+			if name == "" {
+				name = imageID
+			}
+			results = append(results, core.Snapshot{
+				VendorID: imageID,
+				Name:     name,
+				Age:      age,
+				Region:   region,
+				Type:     core.SnapshotTypeImage,
+				Tags:     awsTagsToStrings(image.Tags),
+			})
+		}
+		if out.NextToken == nil || *out.NextToken == "" {
+			break
+		}
+		nextToken = out.NextToken
+	}
+
+	nextToken = nil
+	for {
+		out, err := client.DescribeSnapshots(ctx, &ec2.DescribeSnapshotsInput{OwnerIds: []string{"self"}, NextToken: nextToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range out.Snapshots {
+			if snapshot.SnapshotId == nil {
+				core.Warnf(ctx, "skipping snapshot with nil SnapshotId in %s", region)
+				continue
+			}
+			snapshotID := *snapshot.SnapshotId
+			var age float64
+			if snapshot.StartTime != nil {
+				age = time.Since(*snapshot.StartTime).Hours() / 24.0
+			} else {
+				core.Warnf(ctx, "missing StartTime for snapshot %s in %s", snapshotID, region)
+			}
+			name := snapshotID
+			for _, tag := range snapshot.Tags {
+				if tag.Key != nil && tag.Value != nil && *tag.Key == "Name" {
+					name = *tag.Value
+				}
+			}
+			results = append(results, core.Snapshot{
+				VendorID: snapshotID,
+				Name:     name,
+				Age:      age,
+				Region:   region,
+				Type:     core.SnapshotTypeSnapshot,
+				InUse:    referenced[snapshotID],
+				Tags:     awsTagsToStrings(snapshot.Tags),
+			})
+		}
+		if out.NextToken == nil || *out.NextToken == "" {
+			break
+		}
+		nextToken = out.NextToken
+	}
+	return results, nil
+}
+
+// SnapshotDelete deregisters an AMI or deletes an EBS snapshot in its region.
+// deregistering leaves the AMI's snapshots behind; they are no longer
+// referenced and come up for deletion on a later run.
+func (a Aws) SnapshotDelete(ctx context.Context, snapshot core.Snapshot) error {
+	client := a.ec2For(ctx, snapshot.Region)
+	if snapshot.Type == core.SnapshotTypeImage {
+		_, err := client.DeregisterImage(ctx, &ec2.DeregisterImageInput{
+			ImageId: aws.String(snapshot.VendorID),
+			DryRun:  aws.Bool(false),
+		})
+		return err
+	}
+	_, err := client.DeleteSnapshot(ctx, &ec2.DeleteSnapshotInput{
+		SnapshotId: aws.String(snapshot.VendorID),
+		DryRun:     aws.Bool(false),
+	})
+	return err
+}
+
+// IPAddressesGet returns the Elastic IPs of every region. EC2 records no
+// allocation time, so Age stays 0 and main ages them from their first
+// sighting instead. DescribeAddresses is not paginated.
+func (a Aws) IPAddressesGet(ctx context.Context) ([]core.IPAddress, error) {
+	results := make([]core.IPAddress, 0)
+	regions := a.regions(ctx)
+	regionAddresses := make([][]core.IPAddress, len(regions))
+	regionScanErrs := make([]error, len(regions))
+	a.eachRegion(ctx, regions, func(ctx context.Context, i int, region string) {
+		regionAddresses[i], regionScanErrs[i] = a.regionIPAddresses(ctx, region)
+	})
+	for i := range regions {
+		results = append(results, regionAddresses[i]...)
+	}
+	err := regionScanError(regions, regionScanErrs)
+	if err != nil && !core.IsPartial(err) {
+		return nil, err
+	}
+	return results, err
+}
+
+// regionIPAddresses lists the Elastic IPs of one region.
+func (a Aws) regionIPAddresses(ctx context.Context, region string) ([]core.IPAddress, error) {
+	out, err := a.ec2For(ctx, region).DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, err
+	}
+	var results []core.IPAddress
+	for _, address := range out.Addresses {
+		// release goes by AllocationId; EC2-Classic addresses without one
+		// no longer exist, so anything missing it is left alone.
+		if address.AllocationId == nil {
+			core.Warnf(ctx, "skipping address %s with nil AllocationId in %s", aws.ToString(address.PublicIp), region)
+			continue
+		}
+		name := aws.ToString(address.PublicIp)
+		for _, tag := range address.Tags {
+			if tag.Key != nil && tag.Value != nil && *tag.Key == "Name" {
+				name = *tag.Value
+			}
+		}
+		results = append(results, core.IPAddress{
+			VendorID: *address.AllocationId,
+			Address:  aws.ToString(address.PublicIp),
+			Name:     name,
+			Region:   region,
+			// service-managed addresses (NAT gateways, ALBs) can't be
+			// released by the account; treat them as assigned.
+			Assigned: address.AssociationId != nil || address.NetworkInterfaceId != nil || address.InstanceId != nil || address.ServiceManaged != "",
+			Tags:     awsTagsToStrings(address.Tags),
+		})
+	}
+	return results, nil
+}
+
+// IPAddressRelease releases the Elastic IP by allocation ID in its region.
+// AWS rejects the call for an address that was associated since it was listed.
+func (a Aws) IPAddressRelease(ctx context.Context, ipAddress core.IPAddress) error {
+	client := a.ec2For(ctx, ipAddress.Region)
+	_, err := client.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{
+		AllocationId: aws.String(ipAddress.VendorID),
+		DryRun:       aws.Bool(false),
+	})
+	return err
 }
 
 func (a Aws) ec2Client(ctx context.Context, region string) *ec2.Client {
 	return ec2.New(ec2.Options{
 		Region:      region,
 		Credentials: a.credentials(ctx),
+		APIOptions:  []func(*middleware.Stack) error{observeAWSCalls},
 	})
 }
 
@@ -604,6 +1419,7 @@ func (a Aws) elbClient(ctx context.Context, region string) *elasticloadbalancing
 	return elasticloadbalancing.New(elasticloadbalancing.Options{
 		Region:      region,
 		Credentials: a.credentials(ctx),
+		APIOptions:  []func(*middleware.Stack) error{observeAWSCalls},
 	})
 }
 
@@ -611,30 +1427,106 @@ func (a Aws) albClient(ctx context.Context, region string) *elasticloadbalancing
 	return elasticloadbalancingv2.New(elasticloadbalancingv2.Options{
 		Region:      region,
 		Credentials: a.credentials(ctx),
+		APIOptions:  []func(*middleware.Stack) error{observeAWSCalls},
 	})
 }
 
-// credentials returns a process-wide cached CredentialsProvider. resolved
-// once per process via sync.Once so we don't pay the LoadDefaultConfig cost
-// (which probes env / shared / IRSA / IMDS) per region client (panel C6).
-// static keys from ctx take precedence over the default chain.
+func (a Aws) stsClient(ctx context.Context) *sts.Client {
+	return sts.New(sts.Options{
+		Region:      discoveryRegion,
+		Credentials: a.credentials(ctx),
+		APIOptions:  []func(*middleware.Stack) error{observeAWSCalls},
+	})
+}
+
+// defaultRoleSessionName is the sts:AssumeRole session name when main did
+// not set core.AWSRoleSessionNameKey.
+const defaultRoleSessionName = "janitor"
+
+// baseCacheKey identifies the base credentials in ctx: the static access key
+// or the profile, several of which may be swept in one run.
+func baseCacheKey(ctx context.Context) string {
+	accessKey, _ := ctx.Value(core.AWSAccessKeyIDKey).(string)
+	profile, _ := ctx.Value(core.AWSProfileKey).(string)
+	return accessKey + "|" + profile
+}
+
+// accountCacheKey identifies the ctx account's credentials: the base
+// credentials and the role assumed on top of them.
+func accountCacheKey(ctx context.Context) string {
+	account, _ := ctx.Value(core.AccountKey).(core.Account)
+	return baseCacheKey(ctx) + "|" + account.Role
+}
+
+// credentials returns the CredentialsProvider of the ctx account, cached per
+// account so we don't pay the LoadDefaultConfig cost (which probes env /
+// shared / IRSA / IMDS) per region client (panel C6). static keys from ctx
+// take precedence over the default chain; an account role is assumed on
+// top of whichever base credentials apply.
 func (a Aws) credentials(ctx context.Context) aws.CredentialsProvider {
-	credsOnce.Do(func() {
-		accessKey, _ := ctx.Value(core.AWSAccessKeyIDKey).(string)
-		secretKey, _ := ctx.Value(core.AWSSecretAccessKeyKey).(string)
-		if accessKey != "" && secretKey != "" {
-			credsCache = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""))
-			return
-		}
-		cfg, err := config.LoadDefaultConfig(ctx)
+	account, _ := ctx.Value(core.AccountKey).(core.Account)
+	credsMu.Lock()
+	defer credsMu.Unlock()
+	if account.Role == "" {
+		provider, _ := baseCredentials(ctx)
+		return provider
+	}
+	key := accountCacheKey(ctx)
+	if provider, ok := credsCache[key]; ok {
+		return provider
+	}
+	sessionName, _ := ctx.Value(core.AWSRoleSessionNameKey).(string)
+	if sessionName == "" {
+		sessionName = defaultRoleSessionName
+	}
+	externalID, _ := ctx.Value(core.AWSExternalIDKey).(string)
+	base, resolved := baseCredentials(ctx)
+	client := sts.New(sts.Options{
+		Region:      discoveryRegion,
+		Credentials: base,
+		APIOptions:  []func(*middleware.Stack) error{observeAWSCalls},
+	})
+	provider := aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(client, account.Role, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if externalID != "" {
+			o.ExternalID = aws.String(externalID)
+		}
+	}))
+	if resolved {
+		credsCache[key] = provider
+	}
+	return provider
+}
+
+// baseCredentials resolves (and caches) the credentials roles are assumed
+// from. callers hold credsMu. resolved is false when the default chain could
+// not be loaded: the empty credentials returned then are not cached, so a
+// transient IMDS/IRSA error is retried on the next call instead of failing
+// every later daemon run.
+func baseCredentials(ctx context.Context) (provider aws.CredentialsProvider, resolved bool) {
+	key := baseCacheKey(ctx)
+	if provider, ok := credsCache[key]; ok {
+		return provider, true
+	}
+	profile, _ := ctx.Value(core.AWSProfileKey).(string)
+	accessKey, _ := ctx.Value(core.AWSAccessKeyIDKey).(string)
+	secretKey, _ := ctx.Value(core.AWSSecretAccessKeyKey).(string)
+	if accessKey != "" && secretKey != "" {
+		provider = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""))
+	} else {
+		var opts []func(*config.LoadOptions) error
+		if profile != "" {
+			opts = append(opts, config.WithSharedConfigProfile(profile))
+		}
+		cfg, err := config.LoadDefaultConfig(ctx, opts...)
 		if err != nil {
 			core.Warnf(ctx, "AWS LoadDefaultConfig failed: %v — requests will likely fail with 403", err)
-			credsCache = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider("", "", ""))
-			return
+			return aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider("", "", "")), false
 		}
-		credsCache = cfg.Credentials
-	})
-	return credsCache
+		provider = cfg.Credentials
+	}
+	credsCache[key] = provider
+	return provider, true
 }
 
 // isResourceInUse returns true when an SDK error indicates the target
@@ -649,15 +1541,14 @@ func isResourceInUse(err error) bool {
 	return strings.Contains(s, "ResourceInUse") || strings.Contains(s, "is currently in use")
 }
 
-func (a Aws) allRegions() []string {
+// defaultRegions is the fallback when DescribeRegions fails: the regions
+// every account has enabled without opting in.
+func defaultRegions() []string {
 	return []string{
-		"af-south-1", "ap-east-1", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
-		"ap-south-1", "ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-4",
-		"ap-southeast-5", "ap-southeast-7", "ca-central-1", "ca-west-1", "eu-central-1",
-		"eu-central-2", "eu-north-1", "eu-south-1", "eu-south-2", "eu-west-1",
-		"eu-west-2", "eu-west-3", "il-central-1", "me-central-1", "me-south-1",
-		"mx-central-1", "sa-east-1", "us-east-1", "us-east-2", "us-gov-east-1",
-		"us-gov-west-1", "us-west-1", "us-west-2",
+		"ap-northeast-1", "ap-northeast-2", "ap-northeast-3", "ap-south-1", "ap-southeast-1",
+		"ap-southeast-2", "ca-central-1", "eu-central-1", "eu-north-1", "eu-west-1",
+		"eu-west-2", "eu-west-3", "sa-east-1", "us-east-1", "us-east-2",
+		"us-west-1", "us-west-2",
 	}
 }
 
@@ -5,7 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,6 +22,7 @@ import (
 	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/cloud66/janitor/core"
 )
 
@@ -23,12 +30,32 @@ import (
 
 // callLog captures ordered calls across all fakes.
 // tests assert call-order invariants (e.g. the ALB delete ordering B5) by
-// inspecting the slice after the operation under test completes.
+// inspecting the slice after the operation under test completes. regions are
+// scanned concurrently, so add is locked; order is only meaningful within a
+// region.
 type callLog struct {
+	mu    sync.Mutex
 	calls []string
 }
 
-func (c *callLog) add(s string) { c.calls = append(c.calls, s) }
+func (c *callLog) add(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, s)
+}
+
+// count returns how many times s was logged.
+func (c *callLog) count(s string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, call := range c.calls {
+		if call == s {
+			n++
+		}
+	}
+	return n
+}
 
 // fakeEC2 is a record-and-replay EC2 fake.
 // describePages drives pagination for DescribeInstances (one page per element).
@@ -38,6 +65,40 @@ type fakeEC2 struct {
 	describeErr   error
 	modifyErr     error
 	terminateErr  error
+	stopErr       error
+	startErr      error
+	// stopped / started record the instance IDs passed to Stop/StartInstances.
+	stopped []string
+	started []string
+	// tagged records CreateTags calls as "<id>:<key>=<value>".
+	tagged []string
+	// volumePages drives DescribeVolumes pagination the same way
+	// describePages does for instances; deletedVolumes records DeleteVolume.
+	volumePages     []*ec2.DescribeVolumesOutput
+	volumesErr      error
+	deleteVolumeErr error
+	deletedVolumes  []string
+	// keyPairs is returned by DescribeKeyPairs keyed by region;
+	// deletedKeyPairs records DeleteKeyPair as "<region>/<id>".
+	keyPairs        map[string][]ec2types.KeyPairInfo
+	keyPairsErr     error
+	deletedKeyPairs []string
+	// images / snapshots are returned by DescribeImages / DescribeSnapshots
+	// (single page); deletedSnapshots records both deregistrations and
+	// snapshot deletions as "<kind>:<id>".
+	images           []ec2types.Image
+	imagesErr        error
+	snapshots        []ec2types.Snapshot
+	deletedSnapshots []string
+	// addresses is returned by DescribeAddresses keyed by region;
+	// releasedAddresses records ReleaseAddress as "<region>/<allocation id>".
+	addresses         map[string][]ec2types.Address
+	releasedAddresses []string
+	// accountRegions / regionsErr drive DescribeRegions.
+	accountRegions []ec2types.Region
+	regionsErr     error
+	// region is set by regionalEC2 so calls can be attributed to a region.
+	region string
 }
 
 func (f *fakeEC2) DescribeInstances(ctx context.Context, in *ec2.DescribeInstancesInput, opts ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
@@ -67,6 +128,117 @@ func (f *fakeEC2) TerminateInstances(ctx context.Context, in *ec2.TerminateInsta
 	return &ec2.TerminateInstancesOutput{}, f.terminateErr
 }
 
+func (f *fakeEC2) StopInstances(ctx context.Context, in *ec2.StopInstancesInput, opts ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	f.log.add("ec2.StopInstances")
+	f.stopped = append(f.stopped, in.InstanceIds...)
+	return &ec2.StopInstancesOutput{}, f.stopErr
+}
+
+func (f *fakeEC2) StartInstances(ctx context.Context, in *ec2.StartInstancesInput, opts ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	f.log.add("ec2.StartInstances")
+	f.started = append(f.started, in.InstanceIds...)
+	return &ec2.StartInstancesOutput{}, f.startErr
+}
+
+func (f *fakeEC2) CreateTags(ctx context.Context, in *ec2.CreateTagsInput, opts ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	f.log.add("ec2.CreateTags")
+	for _, id := range in.Resources {
+		for _, tag := range in.Tags {
+			f.tagged = append(f.tagged, id+":"+aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+		}
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeVolumes(ctx context.Context, in *ec2.DescribeVolumesInput, opts ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	f.log.add("ec2.DescribeVolumes")
+	if f.volumesErr != nil {
+		return nil, f.volumesErr
+	}
+	idx := 0
+	if in.NextToken != nil {
+		fmt.Sscanf(*in.NextToken, "page%d", &idx)
+	}
+	if idx >= len(f.volumePages) {
+		return &ec2.DescribeVolumesOutput{}, nil
+	}
+	return f.volumePages[idx], nil
+}
+
+func (f *fakeEC2) DeleteVolume(ctx context.Context, in *ec2.DeleteVolumeInput, opts ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error) {
+	f.log.add("ec2.DeleteVolume")
+	f.deletedVolumes = append(f.deletedVolumes, aws.ToString(in.VolumeId))
+	return &ec2.DeleteVolumeOutput{}, f.deleteVolumeErr
+}
+
+func (f *fakeEC2) DescribeKeyPairs(ctx context.Context, in *ec2.DescribeKeyPairsInput, opts ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error) {
+	f.log.add("ec2.DescribeKeyPairs")
+	if f.keyPairsErr != nil {
+		return nil, f.keyPairsErr
+	}
+	return &ec2.DescribeKeyPairsOutput{KeyPairs: f.keyPairs[f.region]}, nil
+}
+
+func (f *fakeEC2) DeleteKeyPair(ctx context.Context, in *ec2.DeleteKeyPairInput, opts ...func(*ec2.Options)) (*ec2.DeleteKeyPairOutput, error) {
+	f.log.add("ec2.DeleteKeyPair")
+	f.deletedKeyPairs = append(f.deletedKeyPairs, f.region+"/"+aws.ToString(in.KeyPairId))
+	return &ec2.DeleteKeyPairOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeImages(ctx context.Context, in *ec2.DescribeImagesInput, opts ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	f.log.add("ec2.DescribeImages")
+	if f.imagesErr != nil {
+		return nil, f.imagesErr
+	}
+	return &ec2.DescribeImagesOutput{Images: f.images}, nil
+}
+
+func (f *fakeEC2) DeregisterImage(ctx context.Context, in *ec2.DeregisterImageInput, opts ...func(*ec2.Options)) (*ec2.DeregisterImageOutput, error) {
+	f.log.add("ec2.DeregisterImage")
+	f.deletedSnapshots = append(f.deletedSnapshots, "image:"+aws.ToString(in.ImageId))
+	return &ec2.DeregisterImageOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeSnapshots(ctx context.Context, in *ec2.DescribeSnapshotsInput, opts ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error) {
+	f.log.add("ec2.DescribeSnapshots")
+	return &ec2.DescribeSnapshotsOutput{Snapshots: f.snapshots}, nil
+}
+
+func (f *fakeEC2) DeleteSnapshot(ctx context.Context, in *ec2.DeleteSnapshotInput, opts ...func(*ec2.Options)) (*ec2.DeleteSnapshotOutput, error) {
+	f.log.add("ec2.DeleteSnapshot")
+	f.deletedSnapshots = append(f.deletedSnapshots, "snapshot:"+aws.ToString(in.SnapshotId))
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeAddresses(ctx context.Context, in *ec2.DescribeAddressesInput, opts ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	f.log.add("ec2.DescribeAddresses")
+	return &ec2.DescribeAddressesOutput{Addresses: f.addresses[f.region]}, nil
+}
+
+func (f *fakeEC2) ReleaseAddress(ctx context.Context, in *ec2.ReleaseAddressInput, opts ...func(*ec2.Options)) (*ec2.ReleaseAddressOutput, error) {
+	f.log.add("ec2.ReleaseAddress")
+	f.releasedAddresses = append(f.releasedAddresses, f.region+"/"+aws.ToString(in.AllocationId))
+	return &ec2.ReleaseAddressOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeRegions(ctx context.Context, in *ec2.DescribeRegionsInput, opts ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	f.log.add("ec2.DescribeRegions")
+	if f.regionsErr != nil {
+		return nil, f.regionsErr
+	}
+	return &ec2.DescribeRegionsOutput{Regions: f.accountRegions}, nil
+}
+
+// regionalEC2 returns an ec2Factory that hands out f with its region field
+// set to the requested region, so per-region fixtures and call attribution
+// work through a single fake.
+func regionalEC2(f *fakeEC2) func(ctx context.Context, region string) ec2Client {
+	return func(ctx context.Context, region string) ec2Client {
+		f.region = region
+		return f
+	}
+}
+
 // fakeELB is a record-and-replay classic ELB fake.
 // lbPages drives Marker-based pagination (one output per page); lbs remains
 // as the simple single-page helper when pagination isn't under test.
@@ -76,7 +248,8 @@ type fakeELB struct {
 	deleteErr   error
 	lbs         []elbtypes.LoadBalancerDescription
 	lbPages     []*elasticloadbalancing.DescribeLoadBalancersOutput
-	// tags keyed by classic LB name; DescribeTags returns these.
+	// tags keyed by classic LB name; DescribeTags returns these and AddTags
+	// appends to them.
 	tags    map[string][]elbtypes.Tag
 	tagsErr error
 }
@@ -119,9 +292,20 @@ func (f *fakeELB) DescribeTags(ctx context.Context, in *elasticloadbalancing.Des
 	return &elasticloadbalancing.DescribeTagsOutput{TagDescriptions: descs}, nil
 }
 
+func (f *fakeELB) AddTags(ctx context.Context, in *elasticloadbalancing.AddTagsInput, opts ...func(*elasticloadbalancing.Options)) (*elasticloadbalancing.AddTagsOutput, error) {
+	f.log.add("elb.AddTags")
+	if f.tags == nil {
+		f.tags = map[string][]elbtypes.Tag{}
+	}
+	for _, name := range in.LoadBalancerNames {
+		f.tags[name] = append(f.tags[name], in.Tags...)
+	}
+	return &elasticloadbalancing.AddTagsOutput{}, nil
+}
+
 // fakeALB is the biggest fake — most ALB behaviors live here.
 // tgPages lets tests drive paginated DescribeTargetGroups (top-level orphan
-// scan) via multiple outputs; lbPages does the same for DescribeLoadBalancers.
+// listing) via multiple outputs; lbPages does the same for DescribeLoadBalancers.
 type fakeALB struct {
 	log *callLog
 
@@ -129,8 +313,10 @@ type fakeALB struct {
 	lbPages []*elasticloadbalancingv2.DescribeLoadBalancersOutput
 	lbErr   error
 
-	// paginated outputs for the top-level (no LoadBalancerArn) TG scan.
+	// paginated outputs for the top-level (no LoadBalancerArn) TG scan;
+	// tgErr fails it.
 	tgPages []*elasticloadbalancingv2.DescribeTargetGroupsOutput
+	tgErr   error
 
 	// per-LB describe outputs (keyed by LB ARN). single-page helpers.
 	perLBListeners    map[string]*elasticloadbalancingv2.DescribeListenersOutput
@@ -152,6 +338,8 @@ type fakeALB struct {
 	// synthetic ResourceInUse error before succeeding on call N+1. drives
 	// the retry-on-ResourceInUse coverage (round-3 C7).
 	deleteTGFailUntil int
+	// deletedTGs records the ARN of every successful DeleteTargetGroup.
+	deletedTGs []string
 
 	// health describe — keyed by TG arn.
 	health map[string]*elasticloadbalancingv2.DescribeTargetHealthOutput
@@ -237,6 +425,9 @@ func (f *fakeALB) DescribeTargetGroups(ctx context.Context, in *elasticloadbalan
 		return &elasticloadbalancingv2.DescribeTargetGroupsOutput{}, nil
 	}
 	// top-level (orphan scan) query — paginate via tgPages.
+	if f.tgErr != nil {
+		return nil, f.tgErr
+	}
 	idx := 0
 	if in.Marker != nil {
 		fmt.Sscanf(*in.Marker, "page%d", &idx)
@@ -304,6 +495,7 @@ func (f *fakeALB) DeleteTargetGroup(ctx context.Context, in *elasticloadbalancin
 		f.deleteTGFailUntil--
 		return nil, errors.New("ResourceInUseException: target group is currently in use")
 	}
+	f.deletedTGs = append(f.deletedTGs, aws.ToString(in.TargetGroupArn))
 	return &elasticloadbalancingv2.DeleteTargetGroupOutput{}, nil
 }
 
@@ -460,10 +652,118 @@ func TestAws_ServersGet_AllRegionsFail_MultiRegion_B6(t *testing.T) {
 	}
 }
 
-// orphan-TG sweep removed in this PR (panel round-3); related tests
-// (TestAws_OrphanTG_AddTagsError_B7, TestAws_OrphanTG_PaginationFindsOwningLB_B8)
-// will return when the sweep is reintroduced with HMAC-signed marks or
-// out-of-band state in a follow-up PR.
+// --- orphan target groups ---------------------------------------------------
+
+// orphanTGs returns one DescribeTargetGroups page holding the given groups;
+// a group named "attached-*" is given an owning load balancer.
+func orphanTGs(names ...string) *elasticloadbalancingv2.DescribeTargetGroupsOutput {
+	out := &elasticloadbalancingv2.DescribeTargetGroupsOutput{}
+	for _, name := range names {
+		tg := elbv2types.TargetGroup{TargetGroupArn: aws.String("arn:tg/" + name), TargetGroupName: aws.String(name)}
+		if strings.HasPrefix(name, "attached-") {
+			tg.LoadBalancerArns = []string{"arn:lb/owner"}
+		}
+		out.TargetGroups = append(out.TargetGroups, tg)
+	}
+	return out
+}
+
+// TestAws_TargetGroupsGet_ListsOrphansWithTags asserts only groups without a
+// load balancer are listed, across pages, each with its tags.
+func TestAws_TargetGroupsGet_ListsOrphansWithTags(t *testing.T) {
+	log := &callLog{}
+	alb := newFakeALB(log)
+	page1 := orphanTGs("attached-web", "orphan")
+	page1.NextMarker = aws.String("page1")
+	alb.tgPages = []*elasticloadbalancingv2.DescribeTargetGroupsOutput{page1, orphanTGs("late-orphan")}
+	alb.tags["arn:tg/orphan"] = []elbv2types.Tag{{Key: aws.String("lifecycle"), Value: aws.String("permanent")}}
+	a := newTestAws(&fakeEC2{log: log}, &fakeELB{log: log}, alb)
+
+	groups, err := a.TargetGroupsGet(context.Background())
+	if err != nil {
+		t.Fatalf("TargetGroupsGet: %v", err)
+	}
+	want := []core.TargetGroup{
+		{VendorID: "arn:tg/orphan", Name: "orphan", Region: "us-east-1", Tags: []string{"lifecycle=permanent"}},
+		{VendorID: "arn:tg/late-orphan", Name: "late-orphan", Region: "us-east-1"},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("want %+v, got %+v", want, groups)
+	}
+	for _, call := range log.calls {
+		if call == "alb.AddTags" || call == "alb.DeleteTargetGroup" {
+			t.Errorf("listing must not call %s", call)
+		}
+	}
+}
+
+// TestAws_TargetGroupsGet_TagBatches asserts DescribeTags is called with at
+// most 20 ARNs at a time, the ELBv2 limit.
+func TestAws_TargetGroupsGet_TagBatches(t *testing.T) {
+	log := &callLog{}
+	alb := newFakeALB(log)
+	names := make([]string, 45)
+	for i := range names {
+		names[i] = fmt.Sprintf("orphan-%02d", i)
+	}
+	alb.tgPages = []*elasticloadbalancingv2.DescribeTargetGroupsOutput{orphanTGs(names...)}
+	a := newTestAws(&fakeEC2{log: log}, &fakeELB{log: log}, alb)
+
+	groups, err := a.TargetGroupsGet(context.Background())
+	if err != nil || len(groups) != 45 {
+		t.Fatalf("want 45 groups, got %d (%v)", len(groups), err)
+	}
+	calls := 0
+	for _, call := range log.calls {
+		if call == "alb.DescribeTags" {
+			calls++
+		}
+	}
+	if calls != 3 {
+		t.Errorf("want 3 DescribeTags batches for 45 groups, got %d", calls)
+	}
+}
+
+// TestAws_TargetGroupsGet_AllRegionsFail asserts a failed scan is an error,
+// never an empty listing.
+func TestAws_TargetGroupsGet_AllRegionsFail(t *testing.T) {
+	log := &callLog{}
+	alb := newFakeALB(log)
+	alb.tgErr = errors.New("AccessDenied")
+	a := newTestAws(&fakeEC2{log: log}, &fakeELB{log: log}, alb)
+	if _, err := a.TargetGroupsGet(context.Background()); err == nil || !strings.Contains(err.Error(), "all regions failed") {
+		t.Errorf("want aggregated error, got %v", err)
+	}
+}
+
+func TestAws_TargetGroupMarkAndDelete(t *testing.T) {
+	log := &callLog{}
+	alb := newFakeALB(log)
+	var gotRegion string
+	a := newTestAws(&fakeEC2{log: log}, &fakeELB{log: log}, alb)
+	a.albFactory = func(ctx context.Context, region string) albClient {
+		gotRegion = region
+		return alb
+	}
+	targetGroup := core.TargetGroup{VendorID: "arn:tg/orphan", Name: "orphan", Region: "eu-west-1"}
+
+	if err := a.TargetGroupMark(context.Background(), targetGroup, "v1.mark"); err != nil {
+		t.Fatalf("mark: %v", err)
+	}
+	if marks := core.FindMarks(awsAlbTagsToStrings(alb.tags["arn:tg/orphan"])); !sliceEq(marks, []string{"v1.mark"}) || gotRegion != "eu-west-1" {
+		t.Errorf("want the mark tagged in eu-west-1, got %v in %q", marks, gotRegion)
+	}
+	if err := a.TargetGroupDelete(context.Background(), targetGroup); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if !sliceEq(alb.deletedTGs, []string{"arn:tg/orphan"}) {
+		t.Errorf("want arn:tg/orphan deleted, got %v", alb.deletedTGs)
+	}
+	alb.addTagsErr = errors.New("AccessDenied")
+	if err := a.TargetGroupMark(context.Background(), targetGroup, "v1.mark"); err == nil {
+		t.Error("want AddTags error to propagate, got nil")
+	}
+}
 
 // classic ELB DescribeTags must populate core.LoadBalancer.Tags so the
 // downstream isPermanent / hasSampleTag predicates can match on tags
@@ -630,11 +930,11 @@ func TestAws_ServersGet_Pagination_B8(t *testing.T) {
 
 // --- P5-T7: nil-pointer guards ---------------------------------------------
 
-func TestAws_ServersGet_NilEbs_SkipsWithWarning(t *testing.T) {
+func TestAws_ServersGet_NilEbs_FallsBackToLaunchTime(t *testing.T) {
 	log := &callLog{}
 	id1 := "i-nil"
-	id2 := "i-ok"
-	now := time.Now().Add(-24 * time.Hour)
+	id2 := "i-none"
+	launched := time.Now().Add(-24 * time.Hour)
 	ec2f := &fakeEC2{
 		log: log,
 		describePages: []*ec2.DescribeInstancesOutput{
@@ -644,13 +944,13 @@ func TestAws_ServersGet_NilEbs_SkipsWithWarning(t *testing.T) {
 					{
 						InstanceId:          &id1,
 						State:               &ec2types.InstanceState{Name: "running"},
+						LaunchTime:          &launched,
 						BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{{Ebs: nil}},
 					},
-					// normal instance still gets included.
+					// no timestamp at all: listed with an unknown age.
 					{
-						InstanceId:          &id2,
-						State:               &ec2types.InstanceState{Name: "running"},
-						BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{{Ebs: &ec2types.EbsInstanceBlockDevice{AttachTime: &now}}},
+						InstanceId: &id2,
+						State:      &ec2types.InstanceState{Name: "running"},
 					},
 				}}},
 			},
@@ -663,11 +963,95 @@ func TestAws_ServersGet_NilEbs_SkipsWithWarning(t *testing.T) {
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if len(servers) != 1 {
-		t.Fatalf("expected only the well-formed instance, got %d", len(servers))
+	if len(servers) != 2 {
+		t.Fatalf("expected both instances, got %d", len(servers))
+	}
+	if servers[0].AgeSource != core.AgeSourceLaunch || servers[0].Age < 0.99 || servers[0].Age > 1.01 {
+		t.Errorf("want i-nil aged 1 day by launch time, got %+v", servers[0])
+	}
+	if servers[1].Age != 0 || servers[1].AgeSource != "" {
+		t.Errorf("want i-none with an unknown age, got %+v", servers[1])
 	}
-	if !strings.Contains(buf.String(), "nil Ebs/AttachTime") {
-		t.Fatalf("expected WARN for nil Ebs, got %q", buf.String())
+	if !strings.Contains(buf.String(), "instance i-none has no launch or attach time") {
+		t.Fatalf("expected WARN for i-none, got %q", buf.String())
+	}
+}
+
+// --- server age: launch time and attach times together -------------------
+
+func TestAws_ServersGet_AgeSources(t *testing.T) {
+	ago := func(days float64) *time.Time {
+		at := time.Now().Add(-time.Duration(days * 24 * float64(time.Hour)))
+		return &at
+	}
+	ebs := func(attached *time.Time) ec2types.InstanceBlockDeviceMapping {
+		return ec2types.InstanceBlockDeviceMapping{Ebs: &ec2types.EbsInstanceBlockDevice{AttachTime: attached}}
+	}
+	for _, tc := range []struct {
+		name     string
+		instance ec2types.Instance
+		age      float64
+		source   string
+	}{
+		{
+			// instance-store: no block device mappings at all.
+			name:     "instance store",
+			instance: ec2types.Instance{LaunchTime: ago(3)},
+			age:      3,
+			source:   core.AgeSourceLaunch,
+		},
+		{
+			// the root volume was swapped a day ago; the launch is older.
+			name:     "re-attached root",
+			instance: ec2types.Instance{LaunchTime: ago(5), BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{ebs(ago(1))}},
+			age:      5,
+			source:   core.AgeSourceLaunch,
+		},
+		{
+			// stopped and started today; the root has been attached since
+			// the first launch.
+			name:     "restarted",
+			instance: ec2types.Instance{LaunchTime: ago(0.1), BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{ebs(ago(4))}},
+			age:      4,
+			source:   core.AgeSourceAttach,
+		},
+		{
+			// the earliest of several volumes counts, not the first listed.
+			name:     "later root, earlier data volume",
+			instance: ec2types.Instance{LaunchTime: ago(0.5), BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{ebs(ago(1)), ebs(nil), ebs(ago(2))}},
+			age:      2,
+			source:   core.AgeSourceAttach,
+		},
+		{
+			name:     "attach time only",
+			instance: ec2types.Instance{BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{ebs(ago(6))}},
+			age:      6,
+			source:   core.AgeSourceAttach,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			log := &callLog{}
+			tc.instance.InstanceId = aws.String("i-1")
+			tc.instance.State = &ec2types.InstanceState{Name: "running"}
+			ec2f := &fakeEC2{
+				log: log,
+				describePages: []*ec2.DescribeInstancesOutput{
+					{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{tc.instance}}}},
+				},
+			}
+			a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+
+			servers, err := a.ServersGet(context.Background(), nil, nil)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if len(servers) != 1 {
+				t.Fatalf("expected the instance listed, got %d", len(servers))
+			}
+			if got := servers[0]; got.AgeSource != tc.source || got.Age < tc.age-0.01 || got.Age > tc.age+0.01 {
+				t.Errorf("want %.2f days by %s, got %.2f days by %s", tc.age, tc.source, got.Age, got.AgeSource)
+			}
+		})
 	}
 }
 
@@ -686,6 +1070,255 @@ func TestAws_ServerDelete_CallsModifyThenTerminate(t *testing.T) {
 	}
 }
 
+// --- stop/start wire StopInstances / StartInstances in the server's region --
+
+func TestAws_ServerStopStart(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log}
+	var gotRegions []string
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+	a.ec2Factory = func(ctx context.Context, region string) ec2Client {
+		gotRegions = append(gotRegions, region)
+		return ec2f
+	}
+	server := core.Server{VendorID: "i-1", Region: "eu-west-1"}
+	if err := a.ServerStop(context.Background(), server); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := a.ServerStart(context.Background(), server); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if !sliceEq(ec2f.stopped, []string{"i-1"}) || !sliceEq(ec2f.started, []string{"i-1"}) {
+		t.Errorf("want i-1 stopped and started, got stopped=%v started=%v", ec2f.stopped, ec2f.started)
+	}
+	if !sliceEq(gotRegions, []string{"eu-west-1", "eu-west-1"}) {
+		t.Errorf("calls must target the server's region, got %v", gotRegions)
+	}
+	// an instance-store root cannot be stopped; the API error must surface.
+	ec2f.stopErr = errors.New("UnsupportedOperation")
+	if err := a.ServerStop(context.Background(), server); err == nil {
+		t.Error("want StopInstances error to propagate, got nil")
+	}
+}
+
+func TestAws_Mark(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log}
+	elbf := &fakeELB{log: log}
+	albf := newFakeALB(log)
+	a := newTestAws(ec2f, elbf, albf)
+	ctx := context.Background()
+
+	if err := a.ServerMark(ctx, core.Server{VendorID: "i-1", Region: "us-east-1"}, "1-aa"); err != nil {
+		t.Fatalf("server mark: %v", err)
+	}
+	if !sliceEq(ec2f.tagged, []string{"i-1:" + core.MarkTagKey + "=1-aa"}) {
+		t.Errorf("unexpected CreateTags calls %v", ec2f.tagged)
+	}
+	if err := a.LoadBalancerMark(ctx, core.LoadBalancer{Name: "classic", Type: "elb", Region: "us-east-1"}, "2-bb"); err != nil {
+		t.Fatalf("elb mark: %v", err)
+	}
+	if got := elbf.tags["classic"]; len(got) != 1 || aws.ToString(got[0].Key) != core.MarkTagKey || aws.ToString(got[0].Value) != "2-bb" {
+		t.Errorf("classic ELB must be tagged by name, got %+v", got)
+	}
+	arn := "arn:aws:elasticloadbalancing:us-east-1:1:loadbalancer/app/x/1"
+	if err := a.LoadBalancerMark(ctx, core.LoadBalancer{LoadBalancerArn: arn, Type: "alb", Region: "us-east-1"}, "3-cc"); err != nil {
+		t.Fatalf("alb mark: %v", err)
+	}
+	if got := albf.tags[arn]; len(got) != 1 || aws.ToString(got[0].Value) != "3-cc" {
+		t.Errorf("ALB must be tagged by ARN, got %+v", got)
+	}
+	if err := a.LoadBalancerMark(ctx, core.LoadBalancer{Type: "nlb?"}, "4-dd"); err == nil {
+		t.Error("unknown LB type must error rather than silently not marking")
+	}
+}
+
+// TestAws_VolumesGet_PaginatesAndMapsState covers the EBS listing: two pages,
+// available → unattached, everything else attached, tags normalized so the
+// permanent / sample checks apply, and a nil VolumeId skipped.
+func TestAws_VolumesGet_PaginatesAndMapsState(t *testing.T) {
+	log := &callLog{}
+	created := time.Now().Add(-48 * time.Hour)
+	ec2f := &fakeEC2{log: log, volumePages: []*ec2.DescribeVolumesOutput{
+		{
+			Volumes: []ec2types.Volume{
+				{VolumeId: aws.String("vol-1"), State: ec2types.VolumeStateAvailable, CreateTime: &created,
+					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("orphan")}, {Key: aws.String("C66-STACK"), Value: aws.String("sample")}}},
+				{VolumeId: nil, State: ec2types.VolumeStateAvailable},
+			},
+			NextToken: aws.String("page1"),
+		},
+		{Volumes: []ec2types.Volume{
+			{VolumeId: aws.String("vol-2"), State: ec2types.VolumeStateInUse, CreateTime: &created},
+			{VolumeId: aws.String("vol-3"), State: ec2types.VolumeStateCreating},
+		}},
+	}}
+	var warn bytes.Buffer
+	ctx := context.WithValue(context.Background(), core.WarnWriterKey, &warn)
+	volumes, err := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log)).VolumesGet(ctx)
+	if err != nil {
+		t.Fatalf("VolumesGet: %v", err)
+	}
+	if len(volumes) != 3 {
+		t.Fatalf("want 3 volumes, got %d: %+v", len(volumes), volumes)
+	}
+	v1 := volumes[0]
+	if v1.VendorID != "vol-1" || v1.Name != "orphan" || v1.Attached || v1.Region != "us-east-1" {
+		t.Errorf("unexpected vol-1 %+v", v1)
+	}
+	if v1.Age < 1.9 || v1.Age > 2.1 {
+		t.Errorf("want ~2 days, got %f", v1.Age)
+	}
+	if !sliceEq(v1.Tags, []string{"Name=orphan", "C66-STACK=sample"}) {
+		t.Errorf("tags must be normalized to key=value, got %v", v1.Tags)
+	}
+	if !volumes[1].Attached || !volumes[2].Attached {
+		t.Errorf("in-use and creating volumes must count as attached, got %+v", volumes[1:])
+	}
+	if volumes[2].Name != "vol-3" || volumes[2].Age != 0 {
+		t.Errorf("untagged volume without CreateTime: want name=id age=0, got %+v", volumes[2])
+	}
+	if !strings.Contains(warn.String(), "nil VolumeId") || !strings.Contains(warn.String(), "missing CreateTime") {
+		t.Errorf("expected warnings, got %q", warn.String())
+	}
+}
+
+func TestAws_VolumesGet_AllRegionsFail(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log, volumesErr: errors.New("UnauthorizedOperation")}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+	a.regionsOverride = []string{"us-east-1", "eu-west-1"}
+	if _, err := a.VolumesGet(context.Background()); err == nil || !strings.Contains(err.Error(), "all regions failed") {
+		t.Errorf("want aggregated error, got %v", err)
+	}
+}
+
+// TestAws_RegionFailureIsPartial asserts a listing that failed in one region
+// returns the other region's results with a *core.PartialError naming the
+// failed region, instead of warning and reporting success.
+func TestAws_RegionFailureIsPartial(t *testing.T) {
+	log := &callLog{}
+	created := time.Now().Add(-48 * time.Hour)
+	healthy := &fakeEC2{
+		log: log,
+		volumePages: []*ec2.DescribeVolumesOutput{
+			{Volumes: []ec2types.Volume{{VolumeId: aws.String("vol-1"), CreateTime: &created}}},
+		},
+		snapshots: []ec2types.Snapshot{{SnapshotId: aws.String("snap-1"), StartTime: &created}},
+	}
+	broken := &fakeEC2{
+		log:        log,
+		volumesErr: errors.New("UnauthorizedOperation"),
+		imagesErr:  errors.New("UnauthorizedOperation"),
+		snapshots:  []ec2types.Snapshot{{SnapshotId: aws.String("snap-2"), StartTime: &created}},
+	}
+	a := newTestAws(healthy, &fakeELB{log: log}, newFakeALB(log))
+	a.regionsOverride = []string{"us-east-1", "eu-west-1"}
+	a.ec2Factory = func(ctx context.Context, region string) ec2Client {
+		if region == "eu-west-1" {
+			return broken
+		}
+		return healthy
+	}
+
+	assertPartial := func(t *testing.T, err error) {
+		t.Helper()
+		var partial *core.PartialError
+		if !errors.As(err, &partial) {
+			t.Fatalf("want a partial error, got %v", err)
+		}
+		if !sliceEq(partial.Regions, []string{"eu-west-1"}) {
+			t.Errorf("want failed regions [eu-west-1], got %v", partial.Regions)
+		}
+	}
+	t.Run("VolumesGet", func(t *testing.T) {
+		volumes, err := a.VolumesGet(context.Background())
+		assertPartial(t, err)
+		if len(volumes) != 1 || volumes[0].VendorID != "vol-1" {
+			t.Errorf("want the healthy region's volume, got %+v", volumes)
+		}
+	})
+	t.Run("SnapshotsGet AMI failure", func(t *testing.T) {
+		snapshots, err := a.SnapshotsGet(context.Background())
+		assertPartial(t, err)
+		// snap-2 sits in the region whose AMIs could not be listed: it
+		// must not come back as free to delete.
+		if len(snapshots) != 1 || snapshots[0].VendorID != "snap-1" {
+			t.Errorf("want only the healthy region's snapshot, got %+v", snapshots)
+		}
+	})
+}
+
+func TestAws_VolumeDelete_UsesVolumeRegion(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log}
+	var gotRegion string
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+	a.ec2Factory = func(ctx context.Context, region string) ec2Client {
+		gotRegion = region
+		return ec2f
+	}
+	if err := a.VolumeDelete(context.Background(), core.Volume{VendorID: "vol-9", Region: "ap-south-1"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if gotRegion != "ap-south-1" || !sliceEq(ec2f.deletedVolumes, []string{"vol-9"}) {
+		t.Errorf("want vol-9 deleted in ap-south-1, got %v in %q", ec2f.deletedVolumes, gotRegion)
+	}
+	ec2f.deleteVolumeErr = errors.New("VolumeInUse")
+	if err := a.VolumeDelete(context.Background(), core.Volume{VendorID: "vol-9", Region: "ap-south-1"}); err == nil {
+		t.Error("want DeleteVolume error to propagate, got nil")
+	}
+}
+
+func TestAws_SshKeysGet_PerRegion(t *testing.T) {
+	log := &callLog{}
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ec2f := &fakeEC2{log: log, keyPairs: map[string][]ec2types.KeyPairInfo{
+		"us-east-1": {
+			{KeyPairId: aws.String("key-1"), KeyName: aws.String("c66-a"), CreateTime: &created},
+			{KeyPairId: nil, KeyName: aws.String("broken")},
+		},
+		"eu-west-1": {{KeyPairId: aws.String("key-2"), KeyName: aws.String("alice"),
+			Tags: []ec2types.Tag{{Key: aws.String("lifecycle"), Value: aws.String("permanent")}}}},
+	}}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+	a.regionsOverride = []string{"us-east-1", "eu-west-1"}
+	a.ec2Factory = regionalEC2(ec2f)
+
+	keys, err := a.SshKeysGet(context.Background())
+	if err != nil {
+		t.Fatalf("SshKeysGet: %v", err)
+	}
+	want := []core.SshKey{
+		{VendorID: "key-1", Name: "c66-a", Region: "us-east-1", Created: created, Tags: []string{}},
+		{VendorID: "key-2", Name: "alice", Region: "eu-west-1", Tags: []string{"lifecycle=permanent"}},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("want %d keys, got %+v", len(want), keys)
+	}
+	if keys[0].Age <= 0 || keys[1].Age != 0 {
+		t.Errorf("want Age derived from CreateTime only, got %f / %f", keys[0].Age, keys[1].Age)
+	}
+	for i := range want {
+		want[i].Age = keys[i].Age
+		if !reflect.DeepEqual(keys[i], want[i]) {
+			t.Errorf("key %d: want %+v, got %+v", i, want[i], keys[i])
+		}
+	}
+
+	if err := a.SshKeyDelete(context.Background(), keys[1]); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if !sliceEq(ec2f.deletedKeyPairs, []string{"eu-west-1/key-2"}) {
+		t.Errorf("delete must target the key's region by ID, got %v", ec2f.deletedKeyPairs)
+	}
+
+	ec2f.keyPairsErr = errors.New("UnauthorizedOperation")
+	if _, err := a.SshKeysGet(context.Background()); err == nil {
+		t.Error("want aggregated error when every region fails")
+	}
+}
+
 // dummy use of aws.String to stop unused-import when edits churn.
 var _ = aws.String
 
@@ -694,7 +1327,8 @@ var _ = aws.String
 // TestAws_LoadBalancersGet_ELBFailALBSuccess asserts that a region where the
 // classic ELB call errors but the ALB call succeeds is NOT counted as "failed"
 // — the old code flipped regionFailed on any single-service error, which
-// propagated to "all regions failed" aggregation downstream.
+// propagated to "all regions failed" aggregation downstream. the ALBs come
+// back with a partial error naming the region, so the run is still flagged.
 func TestAws_LoadBalancersGet_ELBFailALBSuccess(t *testing.T) {
 	log := &callLog{}
 	elb := &fakeELB{log: log, describeErr: errors.New("AccessDenied")}
@@ -711,8 +1345,12 @@ func TestAws_LoadBalancersGet_ELBFailALBSuccess(t *testing.T) {
 	a := newTestAws(&fakeEC2{log: log}, elb, alb)
 
 	lbs, err := a.LoadBalancersGet(context.Background(), true)
-	if err != nil {
-		t.Fatalf("unexpected error — ELB-fail+ALB-success must count as region OK: %v", err)
+	var partial *core.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("want a partial error for ELB-fail+ALB-success, got %v", err)
+	}
+	if len(partial.Regions) != 1 || partial.Regions[0] != "us-east-1" {
+		t.Errorf("want failed regions [us-east-1], got %v", partial.Regions)
 	}
 	if len(lbs) != 1 {
 		t.Fatalf("expected ALB to be returned despite ELB failure, got %d", len(lbs))
@@ -904,3 +1542,663 @@ func TestAws_ELB_Pagination_B8(t *testing.T) {
 		t.Fatalf("expected 2 DescribeLoadBalancers calls (page1 + page2), got %d", describeCount)
 	}
 }
+
+func TestAws_SnapshotsGet_MarksAMIBackedSnapshotsInUse(t *testing.T) {
+	log := &callLog{}
+	started := time.Now().Add(-48 * time.Hour)
+	ec2f := &fakeEC2{
+		log: log,
+		images: []ec2types.Image{{
+			ImageId:      aws.String("ami-1"),
+			Name:         aws.String("golden"),
+			CreationDate: aws.String(started.UTC().Format("2006-01-02T15:04:05.000Z")),
+			BlockDeviceMappings: []ec2types.BlockDeviceMapping{
+				{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2types.EbsBlockDevice{SnapshotId: aws.String("snap-used")}},
+				{DeviceName: aws.String("/dev/xvdb")}, // ephemeral, no EBS
+			},
+		}},
+		snapshots: []ec2types.Snapshot{
+			{SnapshotId: aws.String("snap-used"), StartTime: &started},
+			{SnapshotId: aws.String("snap-free"), StartTime: &started, Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("nightly")}}},
+		},
+	}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+
+	snapshots, err := a.SnapshotsGet(context.Background())
+	if err != nil {
+		t.Fatalf("SnapshotsGet: %v", err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("want AMI + 2 snapshots, got %+v", snapshots)
+	}
+	image, used, free := snapshots[0], snapshots[1], snapshots[2]
+	if image.Type != core.SnapshotTypeImage || image.Name != "golden" || image.Age < 1.9 || image.InUse {
+		t.Errorf("unexpected AMI mapping %+v", image)
+	}
+	if used.Type != core.SnapshotTypeSnapshot || !used.InUse || used.Name != "snap-used" {
+		t.Errorf("AMI-backed snapshot must be InUse, got %+v", used)
+	}
+	if free.InUse || free.Name != "nightly" || free.Region != "us-east-1" {
+		t.Errorf("unexpected free snapshot %+v", free)
+	}
+
+	for _, s := range []core.Snapshot{image, free} {
+		if err := a.SnapshotDelete(context.Background(), s); err != nil {
+			t.Fatalf("delete %s: %v", s.VendorID, err)
+		}
+	}
+	if !sliceEq(ec2f.deletedSnapshots, []string{"image:ami-1", "snapshot:snap-free"}) {
+		t.Errorf("want AMI deregistered and snapshot deleted, got %v", ec2f.deletedSnapshots)
+	}
+}
+
+// TestAws_SnapshotsGet_ImageListingFailureHidesSnapshots pins the fail-safe:
+// without the AMI references every snapshot would look unreferenced.
+func TestAws_SnapshotsGet_ImageListingFailureHidesSnapshots(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{
+		log:       log,
+		imagesErr: errors.New("RequestLimitExceeded"),
+		snapshots: []ec2types.Snapshot{{SnapshotId: aws.String("snap-1")}},
+	}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+
+	snapshots, err := a.SnapshotsGet(context.Background())
+	if err == nil || len(snapshots) != 0 {
+		t.Errorf("want error and no snapshots, got %+v (%v)", snapshots, err)
+	}
+	for _, call := range log.calls {
+		if call == "ec2.DescribeSnapshots" {
+			t.Error("snapshots must not be listed when images could not be")
+		}
+	}
+}
+
+func TestAws_IPAddressesGet_PerRegion(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log, addresses: map[string][]ec2types.Address{
+		"us-east-1": {
+			{AllocationId: aws.String("eipalloc-1"), PublicIp: aws.String("3.3.3.3")},
+			{AllocationId: aws.String("eipalloc-2"), PublicIp: aws.String("4.4.4.4"), AssociationId: aws.String("eipassoc-1"), InstanceId: aws.String("i-1")},
+			{PublicIp: aws.String("5.5.5.5")}, // no AllocationId → skipped
+		},
+		"eu-west-1": {
+			{AllocationId: aws.String("eipalloc-3"), PublicIp: aws.String("6.6.6.6"), ServiceManaged: ec2types.ServiceManagedAlb},
+			{AllocationId: aws.String("eipalloc-4"), PublicIp: aws.String("7.7.7.7"), Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("bastion")}}},
+		},
+	}}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+	a.regionsOverride = []string{"us-east-1", "eu-west-1"}
+	a.ec2Factory = regionalEC2(ec2f)
+
+	ips, err := a.IPAddressesGet(context.Background())
+	if err != nil {
+		t.Fatalf("IPAddressesGet: %v", err)
+	}
+	want := []core.IPAddress{
+		{VendorID: "eipalloc-1", Address: "3.3.3.3", Name: "3.3.3.3", Region: "us-east-1", Tags: []string{}},
+		{VendorID: "eipalloc-2", Address: "4.4.4.4", Name: "4.4.4.4", Region: "us-east-1", Assigned: true, Tags: []string{}},
+		{VendorID: "eipalloc-3", Address: "6.6.6.6", Name: "6.6.6.6", Region: "eu-west-1", Assigned: true, Tags: []string{}},
+		{VendorID: "eipalloc-4", Address: "7.7.7.7", Name: "bastion", Region: "eu-west-1", Tags: []string{"Name=bastion"}},
+	}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("want %+v\ngot  %+v", want, ips)
+	}
+
+	if err := a.IPAddressRelease(context.Background(), ips[3]); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if !sliceEq(ec2f.releasedAddresses, []string{"eu-west-1/eipalloc-4"}) {
+		t.Errorf("release must target the address's region by allocation ID, got %v", ec2f.releasedAddresses)
+	}
+}
+
+// --- concurrent region scanning -------------------------------------------
+
+// slowEC2 delays DescribeInstances and records how many calls overlap, so
+// tests can observe the region worker limit.
+type slowEC2 struct {
+	*fakeEC2
+	delay             time.Duration
+	inFlight, maxSeen *atomic.Int32
+}
+
+// track counts the call as in flight for s.delay, noting the peak.
+func (s slowEC2) track() {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		seen := s.maxSeen.Load()
+		if n <= seen || s.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(s.delay)
+}
+
+func (s slowEC2) DescribeInstances(ctx context.Context, in *ec2.DescribeInstancesInput, opts ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	s.track()
+	return s.fakeEC2.DescribeInstances(ctx, in, opts...)
+}
+
+func (s slowEC2) DescribeKeyPairs(ctx context.Context, in *ec2.DescribeKeyPairsInput, opts ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error) {
+	s.track()
+	return s.fakeEC2.DescribeKeyPairs(ctx, in, opts...)
+}
+
+func (s slowEC2) DescribeVolumes(ctx context.Context, in *ec2.DescribeVolumesInput, opts ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	s.track()
+	return s.fakeEC2.DescribeVolumes(ctx, in, opts...)
+}
+
+func (s slowEC2) DescribeImages(ctx context.Context, in *ec2.DescribeImagesInput, opts ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	s.track()
+	return s.fakeEC2.DescribeImages(ctx, in, opts...)
+}
+
+func (s slowEC2) DescribeAddresses(ctx context.Context, in *ec2.DescribeAddressesInput, opts ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	s.track()
+	return s.fakeEC2.DescribeAddresses(ctx, in, opts...)
+}
+
+// slowRegions builds an Aws over n regions whose DescribeInstances each
+// return one instance named after the region. earlier regions answer slower,
+// so completion order is the reverse of region order.
+func slowRegions(n int, delay time.Duration) (Aws, *atomic.Int32) {
+	attach := time.Now().Add(-48 * time.Hour)
+	var inFlight, maxSeen atomic.Int32
+	log := &callLog{}
+	clients := map[string]ec2Client{}
+	var regions []string
+	for i := 0; i < n; i++ {
+		region := fmt.Sprintf("region-%02d", i)
+		regions = append(regions, region)
+		clients[region] = slowEC2{
+			fakeEC2: &fakeEC2{log: log, describePages: []*ec2.DescribeInstancesOutput{{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{
+				{InstanceId: aws.String("i-" + region), State: &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+					BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{{Ebs: &ec2types.EbsInstanceBlockDevice{AttachTime: &attach}}}},
+				{InstanceId: nil}, // drives one warning per region
+			}}}}}},
+			delay:    delay * time.Duration(n-i),
+			inFlight: &inFlight,
+			maxSeen:  &maxSeen,
+		}
+	}
+	a := newTestAws(&fakeEC2{log: log}, &fakeELB{log: log}, newFakeALB(log))
+	a.regionsOverride = regions
+	a.ec2Factory = func(ctx context.Context, region string) ec2Client { return clients[region] }
+	return a, &maxSeen
+}
+
+// TestAws_ServersGet_ConcurrentRegionsKeepOrder asserts results and warnings
+// come back in region order even though later regions finish first, and that
+// no more than the configured number of regions is scanned at once.
+func TestAws_ServersGet_ConcurrentRegionsKeepOrder(t *testing.T) {
+	a, maxSeen := slowRegions(6, 2*time.Millisecond)
+	buf, ctx := captureOut(t)
+	ctx = context.WithValue(ctx, core.AWSRegionConcurrencyKey, 3)
+
+	servers, err := a.ServersGet(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("ServersGet: %v", err)
+	}
+	var got []string
+	for _, server := range servers {
+		got = append(got, server.Region)
+	}
+	if want := a.regionsOverride; !sliceEq(got, want) {
+		t.Errorf("want servers in region order %v, got %v", want, got)
+	}
+	var warned []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		warned = append(warned, strings.TrimPrefix(line, "[WARN] skipping instance with nil InstanceId in "))
+	}
+	if !sliceEq(warned, a.regionsOverride) {
+		t.Errorf("want one warning per region in region order, got %q", buf.String())
+	}
+	if n := maxSeen.Load(); n > 3 || n < 2 {
+		t.Errorf("want between 2 and 3 regions in flight, saw %d", n)
+	}
+}
+
+// TestAws_RegionalListersScanConcurrently asserts every per-region lister,
+// not only ServersGet, fans out through eachRegion.
+func TestAws_RegionalListersScanConcurrently(t *testing.T) {
+	listers := map[string]func(a Aws, ctx context.Context) error{
+		"SshKeysGet": func(a Aws, ctx context.Context) error { _, err := a.SshKeysGet(ctx); return err },
+		"VolumesGet": func(a Aws, ctx context.Context) error { _, err := a.VolumesGet(ctx); return err },
+		"SnapshotsGet": func(a Aws, ctx context.Context) error {
+			_, err := a.SnapshotsGet(ctx)
+			return err
+		},
+		"IPAddressesGet": func(a Aws, ctx context.Context) error {
+			_, err := a.IPAddressesGet(ctx)
+			return err
+		},
+	}
+	for name, list := range listers {
+		t.Run(name, func(t *testing.T) {
+			a, maxSeen := slowRegions(6, 2*time.Millisecond)
+			ctx := context.WithValue(context.Background(), core.AWSRegionConcurrencyKey, 3)
+			if err := list(a, ctx); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if n := maxSeen.Load(); n > 3 || n < 2 {
+				t.Errorf("want between 2 and 3 regions in flight, saw %d", n)
+			}
+		})
+	}
+}
+
+func TestAws_ServersGet_ConcurrencyOneIsSequential(t *testing.T) {
+	a, maxSeen := slowRegions(4, time.Millisecond)
+	ctx := context.WithValue(context.Background(), core.AWSRegionConcurrencyKey, 1)
+
+	if _, err := a.ServersGet(ctx, nil, nil); err != nil {
+		t.Fatalf("ServersGet: %v", err)
+	}
+	if n := maxSeen.Load(); n != 1 {
+		t.Errorf("want strictly sequential scans, saw %d in flight", n)
+	}
+}
+
+// TestAws_ClassicELB_OneInstanceScanPerRegion asserts classic ELB member
+// counts come from a single DescribeInstances pass per region, not one per
+// ELB.
+func TestAws_ClassicELB_OneInstanceScanPerRegion(t *testing.T) {
+	log := &callLog{}
+	created := time.Now().Add(-48 * time.Hour)
+	attach := created
+	ec2f := &fakeEC2{log: log, describePages: []*ec2.DescribeInstancesOutput{{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{
+		{InstanceId: aws.String("i-1"), State: &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{{Ebs: &ec2types.EbsInstanceBlockDevice{AttachTime: &attach}}}},
+		{InstanceId: aws.String("i-2"), State: &ec2types.InstanceState{Name: ec2types.InstanceStateNameTerminated},
+			BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{{Ebs: &ec2types.EbsInstanceBlockDevice{AttachTime: &attach}}}},
+	}}}}}}
+	elb := &fakeELB{log: log, lbs: []elbtypes.LoadBalancerDescription{
+		{LoadBalancerName: aws.String("web"), CreatedTime: &created, Instances: []elbtypes.Instance{{InstanceId: aws.String("i-1")}, {InstanceId: aws.String("i-2")}}},
+		{LoadBalancerName: aws.String("api"), CreatedTime: &created, Instances: []elbtypes.Instance{{InstanceId: aws.String("i-1")}}},
+		{LoadBalancerName: aws.String("empty"), CreatedTime: &created},
+	}}
+	a := newTestAws(ec2f, elb, newFakeALB(log))
+
+	lbs, err := a.LoadBalancersGet(context.Background(), false)
+	if err != nil {
+		t.Fatalf("LoadBalancersGet: %v", err)
+	}
+	if n := log.count("ec2.DescribeInstances"); n != 1 {
+		t.Errorf("want one DescribeInstances for the region, got %d", n)
+	}
+	counts := map[string]int{}
+	for _, lb := range lbs {
+		counts[lb.Name] = lb.InstanceCount
+	}
+	// the terminated member no longer counts
+	if counts["web"] != 1 || counts["api"] != 1 || counts["empty"] != 0 {
+		t.Errorf("unexpected instance counts %v", counts)
+	}
+}
+
+// BenchmarkAws_ServersGet_Regions scans 33 fake regions with a simulated
+// 2ms API latency at several worker limits.
+func BenchmarkAws_ServersGet_Regions(b *testing.B) {
+	for _, concurrency := range []int{1, 4, 8, 16} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			a, _ := slowRegions(33, 0)
+			clients := map[string]ec2Client{}
+			for _, region := range a.regionsOverride {
+				slow := a.ec2Factory(context.Background(), region).(slowEC2)
+				slow.delay = 2 * time.Millisecond
+				clients[region] = slow
+			}
+			a.ec2Factory = func(ctx context.Context, region string) ec2Client { return clients[region] }
+			ctx := context.WithValue(context.Background(), core.AWSRegionConcurrencyKey, concurrency)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := a.ServersGet(ctx, nil, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// --- region discovery --------------------------------------------------------
+
+func TestAws_DiscoverRegions_SplitsNotOptedIn(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log, accountRegions: []ec2types.Region{
+		{RegionName: aws.String("us-west-2"), OptInStatus: aws.String("opt-in-not-required")},
+		{RegionName: aws.String("af-south-1"), OptInStatus: aws.String("not-opted-in")},
+		{RegionName: aws.String("eu-south-1"), OptInStatus: aws.String("opted-in")},
+		{RegionName: aws.String("us-east-1"), OptInStatus: aws.String("opt-in-not-required")},
+		{RegionName: aws.String("il-central-1"), OptInStatus: aws.String("not-opted-in")},
+	}}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+
+	enabled, unreachable, err := a.discoverRegions(context.Background())
+	if err != nil {
+		t.Fatalf("discoverRegions: %v", err)
+	}
+	if want := []string{"eu-south-1", "us-east-1", "us-west-2"}; !sliceEq(enabled, want) {
+		t.Errorf("enabled: want %v, got %v", want, enabled)
+	}
+	if want := []string{"af-south-1", "il-central-1"}; !sliceEq(unreachable, want) {
+		t.Errorf("unreachable: want %v, got %v", want, unreachable)
+	}
+}
+
+func TestAws_DiscoverRegions_Error(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log, regionsErr: errors.New("UnauthorizedOperation")}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+
+	if _, _, err := a.discoverRegions(context.Background()); err == nil {
+		t.Fatal("want the DescribeRegions error surfaced so the caller can fall back")
+	}
+}
+
+func TestAws_AccountRegions_CachesOnlyDiscoveredRegions(t *testing.T) {
+	log := &callLog{}
+	ec2f := &fakeEC2{log: log, regionsErr: errors.New("RequestLimitExceeded"), accountRegions: []ec2types.Region{
+		{RegionName: aws.String("eu-south-1"), OptInStatus: aws.String("opted-in")},
+	}}
+	a := newTestAws(ec2f, &fakeELB{log: log}, newFakeALB(log))
+	buf, ctx := captureOut(t)
+	ctx = context.WithValue(ctx, core.AWSAccessKeyIDKey, "AKIA-REGIONS-TEST")
+	key := accountCacheKey(ctx)
+	t.Cleanup(func() {
+		regionsMu.Lock()
+		delete(regionsCache, key)
+		regionsMu.Unlock()
+	})
+
+	if enabled, _ := a.accountRegions(ctx); !sliceEq(enabled, defaultRegions()) {
+		t.Errorf("want the default regions while discovery fails, got %v", enabled)
+	}
+	if !strings.Contains(buf.String(), "falling back to the default regions") {
+		t.Errorf("want the fallback warned about, got %q", buf.String())
+	}
+	ec2f.regionsErr = nil
+	if enabled, _ := a.accountRegions(ctx); !sliceEq(enabled, []string{"eu-south-1"}) {
+		t.Errorf("want the fallback left uncached and discovery retried, got %v", enabled)
+	}
+	a.accountRegions(ctx)
+	if n := log.count("ec2.DescribeRegions"); n != 2 {
+		t.Errorf("want the discovered regions cached, saw %d DescribeRegions", n)
+	}
+
+	// an entry past its TTL is rediscovered
+	regionsMu.Lock()
+	entry := regionsCache[key]
+	entry.at = entry.at.Add(-regionsCacheTTL)
+	regionsCache[key] = entry
+	regionsMu.Unlock()
+	a.accountRegions(ctx)
+	if n := log.count("ec2.DescribeRegions"); n != 3 {
+		t.Errorf("want an expired entry rediscovered, saw %d DescribeRegions", n)
+	}
+}
+
+func TestSelectRegions(t *testing.T) {
+	enabled := []string{"eu-west-1", "us-east-1", "us-west-2"}
+	unreachable := []string{"af-south-1", "me-south-1"}
+	cases := []struct {
+		name        string
+		spec        string
+		wantRegions []string
+		wantSkipped []core.SkippedRegion
+	}{
+		{
+			name:        "empty spec scans every enabled region and reports the rest",
+			wantRegions: enabled,
+			wantSkipped: []core.SkippedRegion{{Region: "af-south-1", Reason: "not opted in"}, {Region: "me-south-1", Reason: "not opted in"}},
+		},
+		{
+			name:        "includes narrow the scan and silence unrelated regions",
+			spec:        "us-west-2, eu-west-1",
+			wantRegions: []string{"eu-west-1", "us-west-2"},
+		},
+		{
+			name:        "excludes drop enabled and unreachable regions alike",
+			spec:        "-us-east-1,-af-south-1",
+			wantRegions: []string{"eu-west-1", "us-west-2"},
+			wantSkipped: []core.SkippedRegion{{Region: "me-south-1", Reason: "not opted in"}},
+		},
+		{
+			name:        "an included region the account cannot reach is reported",
+			spec:        "us-east-1,af-south-1",
+			wantRegions: []string{"us-east-1"},
+			wantSkipped: []core.SkippedRegion{{Region: "af-south-1", Reason: "not opted in"}},
+		},
+		{
+			name:        "an included region that does not exist is reported once",
+			spec:        "us-east-1,us-esat-2,us-esat-2",
+			wantRegions: []string{"us-east-1"},
+			wantSkipped: []core.SkippedRegion{{Region: "us-esat-2", Reason: "unknown region"}},
+		},
+		{
+			name:        "exclude wins over include",
+			spec:        "us-east-1,-us-east-1,eu-west-1",
+			wantRegions: []string{"eu-west-1"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			regions, skipped := selectRegions(enabled, unreachable, tc.spec)
+			if !sliceEq(regions, tc.wantRegions) {
+				t.Errorf("regions: want %v, got %v", tc.wantRegions, regions)
+			}
+			if len(skipped) != len(tc.wantSkipped) {
+				t.Fatalf("skipped: want %v, got %v", tc.wantSkipped, skipped)
+			}
+			for i := range skipped {
+				if skipped[i] != tc.wantSkipped[i] {
+					t.Errorf("skipped[%d]: want %v, got %v", i, tc.wantSkipped[i], skipped[i])
+				}
+			}
+		})
+	}
+}
+
+// TestAws_RegionsSpecAppliesToOverride asserts --aws-regions narrows the
+// test override the same way it narrows discovered regions, and that the
+// override never triggers DescribeRegions.
+func TestAws_RegionsSpecAppliesToOverride(t *testing.T) {
+	log := &callLog{}
+	a := newTestAws(&fakeEC2{log: log}, &fakeELB{log: log}, newFakeALB(log))
+	a.regionsOverride = []string{"eu-west-1", "us-east-1", "us-west-2"}
+	var scanned []string
+	var mu sync.Mutex
+	a.ec2Factory = func(ctx context.Context, region string) ec2Client {
+		mu.Lock()
+		defer mu.Unlock()
+		scanned = append(scanned, region)
+		return &fakeEC2{log: log}
+	}
+	ctx := context.WithValue(context.Background(), core.AWSRegionsKey, "-us-east-1,eu-west-1,ap-east-9")
+
+	if _, err := a.ServersGet(ctx, nil, nil); err != nil {
+		t.Fatalf("ServersGet: %v", err)
+	}
+	if !sliceEq(scanned, []string{"eu-west-1"}) {
+		t.Errorf("want only eu-west-1 scanned, got %v", scanned)
+	}
+	skipped, err := a.SkippedRegionsGet(ctx)
+	if err != nil {
+		t.Fatalf("SkippedRegionsGet: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0] != (core.SkippedRegion{Region: "ap-east-9", Reason: "unknown region"}) {
+		t.Errorf("want ap-east-9 reported as unknown, got %v", skipped)
+	}
+	if n := log.count("ec2.DescribeRegions"); n != 0 {
+		t.Errorf("override must bypass discovery, saw %d DescribeRegions", n)
+	}
+}
+
+// --- accounts ----------------------------------------------------------------
+
+// fakeSTS answers GetCallerIdentity with the account of the role in ctx;
+// roles missing from accounts fail as an AccessDenied AssumeRole would.
+type fakeSTS struct {
+	log      *callLog
+	accounts map[string]string
+}
+
+func (f *fakeSTS) GetCallerIdentity(ctx context.Context, in *sts.GetCallerIdentityInput, opts ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	f.log.add("sts.GetCallerIdentity")
+	account, _ := ctx.Value(core.AccountKey).(core.Account)
+	id, ok := f.accounts[account.Role]
+	if !ok {
+		return nil, errors.New("AccessDenied: not authorized to perform sts:AssumeRole")
+	}
+	return &sts.GetCallerIdentityOutput{Account: aws.String(id)}, nil
+}
+
+func accountsAws(log *callLog, accounts map[string]string) Aws {
+	a := newTestAws(&fakeEC2{log: log}, &fakeELB{log: log}, newFakeALB(log))
+	fake := &fakeSTS{log: log, accounts: accounts}
+	a.stsFactory = func(ctx context.Context) stsClient { return fake }
+	return a
+}
+
+func TestAws_AccountsGet_OnePerRole(t *testing.T) {
+	log := &callLog{}
+	a := accountsAws(log, map[string]string{
+		"arn:aws:iam::111111111111:role/janitor": "111111111111",
+		"arn:aws:iam::333333333333:role/janitor": "333333333333",
+	})
+	buf, ctx := captureOut(t)
+	ctx = context.WithValue(ctx, core.AWSRoleARNsKey, []string{
+		"arn:aws:iam::111111111111:role/janitor",
+		"arn:aws:iam::222222222222:role/janitor",
+		"arn:aws:iam::333333333333:role/janitor",
+	})
+
+	accounts, err := a.AccountsGet(ctx)
+	if err != nil {
+		t.Fatalf("AccountsGet: %v", err)
+	}
+	want := []core.Account{
+		{ID: "111111111111", Role: "arn:aws:iam::111111111111:role/janitor"},
+		{ID: "333333333333", Role: "arn:aws:iam::333333333333:role/janitor"},
+	}
+	if len(accounts) != len(want) || accounts[0] != want[0] || accounts[1] != want[1] {
+		t.Errorf("want %v, got %v", want, accounts)
+	}
+	if !strings.Contains(buf.String(), "skipping AWS role arn:aws:iam::222222222222:role/janitor") {
+		t.Errorf("want the unassumable role warned about, got %q", buf.String())
+	}
+}
+
+func TestAws_AccountsGet_AllRolesFail(t *testing.T) {
+	log := &callLog{}
+	a := accountsAws(log, nil)
+	ctx := context.WithValue(context.Background(), core.AWSRoleARNsKey, []string{"arn:aws:iam::111111111111:role/janitor"})
+
+	_, err := a.AccountsGet(ctx)
+	if err == nil || !strings.Contains(err.Error(), "all accounts failed") {
+		t.Fatalf("want an aggregated error, got %v", err)
+	}
+}
+
+func TestAws_AccountsGet_DefaultChainIsUnlabelled(t *testing.T) {
+	log := &callLog{}
+	a := accountsAws(log, nil)
+
+	accounts, err := a.AccountsGet(context.Background())
+	if err != nil {
+		t.Fatalf("AccountsGet: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0] != (core.Account{}) {
+		t.Errorf("want one unlabelled account, got %v", accounts)
+	}
+	if n := log.count("sts.GetCallerIdentity"); n != 0 {
+		t.Errorf("the default chain must not call STS, saw %d calls", n)
+	}
+}
+
+func TestAws_AccountsGet_ProfileIsLabelled(t *testing.T) {
+	log := &callLog{}
+	a := accountsAws(log, map[string]string{"": "444444444444"})
+	ctx := context.WithValue(context.Background(), core.AWSProfileKey, "staging")
+
+	accounts, err := a.AccountsGet(ctx)
+	if err != nil {
+		t.Fatalf("AccountsGet: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0] != (core.Account{ID: "444444444444"}) {
+		t.Errorf("want the profile's account, got %v", accounts)
+	}
+}
+
+// TestAws_Credentials_CachedPerAccount asserts each assumed role gets its
+// own provider, reused across calls, instead of one provider per process.
+func TestAws_Credentials_CachedPerAccount(t *testing.T) {
+	credsMu.Lock()
+	prev := credsCache
+	credsCache = map[string]aws.CredentialsProvider{}
+	credsMu.Unlock()
+	t.Cleanup(func() {
+		credsMu.Lock()
+		credsCache = prev
+		credsMu.Unlock()
+	})
+	ctx := context.WithValue(context.Background(), core.AWSAccessKeyIDKey, "AKIDEXAMPLE")
+	ctx = context.WithValue(ctx, core.AWSSecretAccessKeyKey, "secret")
+	roleCtx := func(role string) context.Context {
+		return context.WithValue(ctx, core.AccountKey, core.Account{Role: role})
+	}
+	a := Aws{}
+
+	base := a.credentials(ctx)
+	first := a.credentials(roleCtx("arn:aws:iam::111111111111:role/janitor"))
+	second := a.credentials(roleCtx("arn:aws:iam::222222222222:role/janitor"))
+	if first == base || second == base || first == second {
+		t.Error("want a distinct provider per account")
+	}
+	if a.credentials(roleCtx("arn:aws:iam::111111111111:role/janitor")) != first {
+		t.Error("want the account's provider reused")
+	}
+	if a.credentials(ctx) != base {
+		t.Error("want the base provider reused")
+	}
+	otherKey := context.WithValue(ctx, core.AWSAccessKeyIDKey, "AKIDOTHER")
+	if a.credentials(otherKey) == base {
+		t.Error("want a distinct provider per access key")
+	}
+}
+
+// TestAws_Credentials_FailureNotCached asserts a default chain that cannot be
+// loaded is retried on the next call: a daemon must not keep the empty
+// credentials of one transient failure for the rest of its life.
+func TestAws_Credentials_FailureNotCached(t *testing.T) {
+	credsMu.Lock()
+	prev := credsCache
+	credsCache = map[string]aws.CredentialsProvider{}
+	credsMu.Unlock()
+	t.Cleanup(func() {
+		credsMu.Lock()
+		credsCache = prev
+		credsMu.Unlock()
+	})
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", empty)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", empty)
+	var warned bytes.Buffer
+	ctx := context.WithValue(context.Background(), core.AWSProfileKey, "no-such-profile")
+	ctx = context.WithValue(ctx, core.WarnWriterKey, io.Writer(&warned))
+	a := Aws{}
+
+	a.credentials(ctx)
+	a.credentials(context.WithValue(ctx, core.AccountKey, core.Account{Role: "arn:aws:iam::111111111111:role/janitor"}))
+	if n := len(credsCache); n != 0 {
+		t.Errorf("want nothing cached after LoadDefaultConfig failed, got %d providers", n)
+	}
+	if !strings.Contains(warned.String(), "LoadDefaultConfig failed") {
+		t.Errorf("want the failure warned about, got %q", warned.String())
+	}
+}
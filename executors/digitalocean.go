@@ -2,6 +2,7 @@ package executors
 
 import (
 	"context"
+	"fmt"
 	"net/url"
 	"strconv"
 	"time"
@@ -64,7 +65,7 @@ func (d DigitalOcean) ServersGet(ctx context.Context, vendorIDs []string, region
 				age = time.Since(createdAtDate).Hours() / 24.0
 			}
 		}
-		result = append(result, core.Server{VendorID: strconv.Itoa(droplet.ID), Name: droplet.Name, Age: age, Region: "Global", State: "RUNNING", Tags: droplet.Tags})
+		result = append(result, core.Server{VendorID: strconv.Itoa(droplet.ID), Name: droplet.Name, Age: age, Region: "Global", State: mapDropletState(droplet.Status), Tags: droplet.Tags})
 	}
 
 	return result, nil
@@ -80,14 +81,62 @@ func (d DigitalOcean) ServerDelete(ctx context.Context, server core.Server) erro
 	return nil
 }
 
-// ServerStop is unsupported on DigitalOcean
+// ServerStop powers the droplet off. a powered-off droplet is still billed,
+// but keeps its disk and IP, which is what overnight QA boxes want.
 func (d DigitalOcean) ServerStop(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	id, err := parseDropletID(server.VendorID)
+	if err != nil {
+		return err
+	}
+	_, _, err = d.client(ctx).DropletActions.PowerOff(ctx, id)
+	return err
 }
 
-// ServerStart is unsupported on DigitalOcean
+// ServerStart powers the droplet back on
 func (d DigitalOcean) ServerStart(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	id, err := parseDropletID(server.VendorID)
+	if err != nil {
+		return err
+	}
+	_, _, err = d.client(ctx).DropletActions.PowerOn(ctx, id)
+	return err
+}
+
+// ServerMark tags the droplet with the quarantine mark
+func (d DigitalOcean) ServerMark(ctx context.Context, server core.Server, value string) error {
+	if _, err := parseDropletID(server.VendorID); err != nil {
+		return err
+	}
+	return d.tagResource(ctx, server.VendorID, godo.DropletResourceType, value)
+}
+
+// parseDropletID strictly parses a droplet ID. power actions must never fall
+// back to id=0 on garbage input the way strconv.Atoi's ignored error would.
+func parseDropletID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid droplet id %q: %w", s, err)
+	}
+	if id <= 0 {
+		return 0, fmt.Errorf("invalid droplet id %q: must be positive", s)
+	}
+	return id, nil
+}
+
+// mapDropletState canonicalises the droplet status ("new", "active", "off",
+// "archive") to the upper-case form used throughout janitor. unknown values
+// map to RUNNING so they are never treated as safely powered off.
+func mapDropletState(status string) string {
+	switch status {
+	case "off":
+		return "STOPPED"
+	case "new":
+		return "PENDING"
+	case "archive":
+		return "ARCHIVED"
+	default:
+		return "RUNNING"
+	}
 }
 
 // LoadBalancersGet returns all DigitalOcean load balancers with droplet counts
@@ -161,6 +210,11 @@ func (d DigitalOcean) LoadBalancerDelete(ctx context.Context, loadBalancer core.
 	return err
 }
 
+// LoadBalancerMark tags the load balancer with the quarantine mark
+func (d DigitalOcean) LoadBalancerMark(ctx context.Context, loadBalancer core.LoadBalancer, value string) error {
+	return d.tagResource(ctx, loadBalancer.LoadBalancerArn, godo.LoadBalancerResourceType, value)
+}
+
 // SshKeysGet gets SSH keys
 func (d DigitalOcean) SshKeysGet(ctx context.Context) ([]core.SshKey, error) {
 	doAllSshKeys := []godo.Key{}
@@ -258,6 +312,166 @@ func (d DigitalOcean) VolumeDelete(ctx context.Context, volume core.Volume) erro
 	return err
 }
 
+// VolumeMark tags the volume with the quarantine mark
+func (d DigitalOcean) VolumeMark(ctx context.Context, volume core.Volume, value string) error {
+	return d.tagResource(ctx, volume.VendorID, godo.VolumeResourceType, value)
+}
+
+// SnapshotsGet returns all droplet and volume snapshots. DigitalOcean
+// snapshots are images in their own right — nothing else references them —
+// so InUse is always false.
+func (d DigitalOcean) SnapshotsGet(ctx context.Context) ([]core.Snapshot, error) {
+	allSnapshots := []godo.Snapshot{}
+	opt := &godo.ListOptions{}
+	for {
+		doSnapshots, resp, err := d.client(ctx).Snapshots.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		allSnapshots = append(allSnapshots, doSnapshots...)
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+
+		opt.Page = page + 1
+	}
+
+	result := make([]core.Snapshot, 0, len(allSnapshots))
+	for _, snap := range allSnapshots {
+		var age float64
+		createdAt, err := time.Parse(time.RFC3339, snap.Created)
+		if err != nil {
+			core.Warnf(ctx, "unparseable created_at %q for snapshot %q", snap.Created, snap.Name)
+		} else {
+			age = time.Since(createdAt).Hours() / 24.0
+		}
+		// a snapshot copied to several regions is still one object; report
+		// the region it was taken in.
+		region := ""
+		if len(snap.Regions) > 0 {
+			region = snap.Regions[0]
+		}
+		result = append(result, core.Snapshot{
+			VendorID: snap.ID,
+			Name:     snap.Name,
+			Age:      age,
+			Region:   region,
+			Type:     core.SnapshotTypeSnapshot,
+			Tags:     snap.Tags,
+		})
+	}
+
+	return result, nil
+}
+
+// SnapshotDelete removes the specified snapshot
+func (d DigitalOcean) SnapshotDelete(ctx context.Context, snapshot core.Snapshot) error {
+	_, err := d.client(ctx).Snapshots.Delete(ctx, snapshot.VendorID)
+	return err
+}
+
+// IPAddressesGet returns all reserved IPs. DigitalOcean reports neither an
+// allocation time nor tags, so Age stays 0 and the default policy keeps them
+// (WARN). a locked address has an assign/unassign action in flight and is
+// treated as assigned.
+func (d DigitalOcean) IPAddressesGet(ctx context.Context) ([]core.IPAddress, error) {
+	allIPs := []godo.ReservedIP{}
+	opt := &godo.ListOptions{}
+	for {
+		doIPs, resp, err := d.client(ctx).ReservedIPs.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		allIPs = append(allIPs, doIPs...)
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+
+		opt.Page = page + 1
+	}
+
+	result := make([]core.IPAddress, 0, len(allIPs))
+	for _, ip := range allIPs {
+		region := ""
+		if ip.Region != nil {
+			region = ip.Region.Slug
+		}
+		result = append(result, core.IPAddress{
+			VendorID: ip.IP,
+			Address:  ip.IP,
+			Name:     ip.IP,
+			Region:   region,
+			Assigned: ip.Droplet != nil || ip.Locked,
+		})
+	}
+
+	return result, nil
+}
+
+// IPAddressRelease deletes the reserved IP, which is addressed by the IP itself
+func (d DigitalOcean) IPAddressRelease(ctx context.Context, ipAddress core.IPAddress) error {
+	_, err := d.client(ctx).ReservedIPs.Delete(ctx, ipAddress.VendorID)
+	return err
+}
+
+// TargetGroupsGet is unsupported: DigitalOcean load balancers forward to droplets directly
+func (d DigitalOcean) TargetGroupsGet(ctx context.Context) ([]core.TargetGroup, error) {
+	return nil, core.ErrUnsupported
+}
+
+// TargetGroupMark is unsupported: DigitalOcean has no target groups
+func (d DigitalOcean) TargetGroupMark(ctx context.Context, targetGroup core.TargetGroup, value string) error {
+	return core.ErrUnsupported
+}
+
+// TargetGroupDelete is unsupported: DigitalOcean has no target groups
+func (d DigitalOcean) TargetGroupDelete(ctx context.Context, targetGroup core.TargetGroup) error {
+	return core.ErrUnsupported
+}
+
+// SkippedRegionsGet is unsupported: DigitalOcean lists every region in one call
+func (d DigitalOcean) SkippedRegionsGet(ctx context.Context) ([]core.SkippedRegion, error) {
+	return nil, core.ErrUnsupported
+}
+
+// AccountsGet is unsupported: a DigitalOcean token reaches a single account
+func (d DigitalOcean) AccountsGet(ctx context.Context) ([]core.Account, error) {
+	return nil, core.ErrUnsupported
+}
+
+// tagResource applies the "<MarkTagKey>:<value>" tag to a resource. DO tags
+// are plain names (no key/value), and a tag must exist before it can be
+// attached — Create is idempotent for an existing name. tags are additive, so
+// an older mark that failed verification stays on the resource alongside.
+func (d DigitalOcean) tagResource(ctx context.Context, id string, resourceType godo.ResourceType, value string) error {
+	if id == "" {
+		return fmt.Errorf("empty %s id", resourceType)
+	}
+	name := core.MarkTagKey + ":" + value
+	client := d.client(ctx)
+	if _, _, err := client.Tags.Create(ctx, &godo.TagCreateRequest{Name: name}); err != nil {
+		return err
+	}
+	_, err := client.Tags.TagResources(ctx, name, &godo.TagResourcesRequest{
+		Resources: []godo.Resource{{ID: id, Type: resourceType}},
+	})
+	return err
+}
+
 // Token retrieves the oauth token
 func (t *TokenSource) Token() (*oauth2.Token, error) {
 	token := &oauth2.Token{
@@ -274,6 +488,7 @@ func (d DigitalOcean) client(ctx context.Context) *godo.Client {
 	tokenSource := &TokenSource{AccessToken: pat}
 	// oauth2.NoContext is deprecated; use context.Background for the same effect.
 	oauthClient := oauth2.NewClient(context.Background(), tokenSource)
+	oauthClient.Transport = apiCallTransport{cloud: "digitalocean", base: oauthClient.Transport}
 	client := godo.NewClient(oauthClient)
 	// test seam: if a base URL override is present, redirect the SDK to it.
 	// surface parse failures via Warnf — silently falling through to
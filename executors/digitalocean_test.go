@@ -3,6 +3,7 @@ package executors
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/cloud66/janitor/core"
+	"github.com/digitalocean/godo"
 )
 
 // readFixture loads a JSON fixture from testdata.
@@ -248,3 +250,194 @@ func TestDigitalOcean_SshKeysGet(t *testing.T) {
 		t.Fatalf("want 2 keys, got %d", len(keys))
 	}
 }
+
+// TestDigitalOcean_ServerStopStart asserts power_off / power_on droplet
+// actions are posted for the parsed ID and that garbage IDs never reach the
+// API (strconv.Atoi's ignored error used to turn them into droplet 0).
+func TestDigitalOcean_ServerStopStart(t *testing.T) {
+	var gotTypes []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/droplets/7/actions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("want POST, got %s", r.Method)
+		}
+		var req struct {
+			Type string `json:"type"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotTypes = append(gotTypes, req.Type)
+		fmt.Fprint(w, `{"action":{"id":1,"status":"in-progress"}}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	d := DigitalOcean{}
+	if err := d.ServerStop(newDOCtx(ts), core.Server{VendorID: "7"}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := d.ServerStart(newDOCtx(ts), core.Server{VendorID: "7"}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if strings.Join(gotTypes, ",") != "power_off,power_on" {
+		t.Errorf("want power_off then power_on, got %v", gotTypes)
+	}
+	for _, bad := range []string{"", "7abc", "0", "-7"} {
+		if err := d.ServerStop(newDOCtx(ts), core.Server{VendorID: bad}); err == nil {
+			t.Errorf("want error on droplet id %q, got nil", bad)
+		}
+	}
+	if len(gotTypes) != 2 {
+		t.Errorf("invalid IDs must not hit the API, got %d calls", len(gotTypes))
+	}
+}
+
+func TestDigitalOcean_ServersGet_MapsPowerState(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/droplets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"droplets":[
+			{"id":1,"name":"on","status":"active","created_at":"2024-01-01T00:00:00Z"},
+			{"id":2,"name":"off","status":"off","created_at":"2024-01-01T00:00:00Z"}
+		],"links":{},"meta":{"total":2}}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	servers, err := DigitalOcean{}.ServersGet(newDOCtx(ts), nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	got := map[string]string{}
+	for _, s := range servers {
+		got[s.Name] = s.State
+	}
+	if got["on"] != "RUNNING" || got["off"] != "STOPPED" {
+		t.Errorf("want on=RUNNING off=STOPPED, got %v", got)
+	}
+}
+
+func TestDigitalOcean_Mark(t *testing.T) {
+	var calls []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/tags", func(w http.ResponseWriter, r *http.Request) {
+		var req godo.TagCreateRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		calls = append(calls, "create "+req.Name)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"tag":{"name":%q}}`, req.Name)
+	})
+	mux.HandleFunc("/v2/tags/", func(w http.ResponseWriter, r *http.Request) {
+		var req godo.TagResourcesRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, res := range req.Resources {
+			calls = append(calls, fmt.Sprintf("tag %s %s:%s", r.URL.Path, res.Type, res.ID))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	d := DigitalOcean{}
+	if err := d.ServerMark(newDOCtx(ts), core.Server{VendorID: "7"}, "1-aa"); err != nil {
+		t.Fatalf("server: %v", err)
+	}
+	if err := d.VolumeMark(newDOCtx(ts), core.Volume{VendorID: "vol-1"}, "2-bb"); err != nil {
+		t.Fatalf("volume: %v", err)
+	}
+	if err := d.LoadBalancerMark(newDOCtx(ts), core.LoadBalancer{LoadBalancerArn: "lb-1"}, "3-cc"); err != nil {
+		t.Fatalf("lb: %v", err)
+	}
+	tag := core.MarkTagKey + ":"
+	want := []string{
+		"create " + tag + "1-aa", "tag /v2/tags/" + tag + "1-aa/resources droplet:7",
+		"create " + tag + "2-bb", "tag /v2/tags/" + tag + "2-bb/resources volume:vol-1",
+		"create " + tag + "3-cc", "tag /v2/tags/" + tag + "3-cc/resources load_balancer:lb-1",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(calls, "\n"))
+	}
+	if err := d.ServerMark(newDOCtx(ts), core.Server{VendorID: "7abc"}, "x"); err == nil {
+		t.Error("want error on malformed droplet id, got nil")
+	}
+}
+
+func TestDigitalOcean_SnapshotsGet(t *testing.T) {
+	body := readFixture(t, "digitalocean/snapshots_list.json")
+	var gotDelete string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	mux.HandleFunc("/v2/snapshots/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("want DELETE, got %s", r.Method)
+		}
+		gotDelete = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := newDOCtx(ts)
+	snapshots, err := DigitalOcean{}.SnapshotsGet(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("want 2 snapshots, got %d", len(snapshots))
+	}
+	first := snapshots[0]
+	if first.VendorID != "6372321" || first.Region != "nyc1" || first.Age <= 0 || first.InUse || first.Tags[0] != "team:qa" {
+		t.Errorf("unexpected snapshot 0: %+v", first)
+	}
+	// malformed created_at → Age=0 so the default policy keeps it (WARN)
+	if snapshots[1].Age != 0 {
+		t.Errorf("want Age 0 for malformed created_at, got %f", snapshots[1].Age)
+	}
+
+	if err := (DigitalOcean{}).SnapshotDelete(ctx, first); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if gotDelete != "/v2/snapshots/6372321" {
+		t.Errorf("unexpected delete path %q", gotDelete)
+	}
+}
+
+func TestDigitalOcean_IPAddressesGet(t *testing.T) {
+	body := readFixture(t, "digitalocean/reserved_ips_list.json")
+	var gotDelete string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/reserved_ips", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	mux.HandleFunc("/v2/reserved_ips/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("want DELETE, got %s", r.Method)
+		}
+		gotDelete = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := newDOCtx(ts)
+	ips, err := DigitalOcean{}.IPAddressesGet(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(ips) != 3 {
+		t.Fatalf("want 3 reserved IPs, got %d", len(ips))
+	}
+	if ips[0].VendorID != "45.55.96.47" || ips[0].Region != "nyc3" || ips[0].Assigned || ips[0].Age != 0 {
+		t.Errorf("unexpected IP 0: %+v", ips[0])
+	}
+	if !ips[1].Assigned || !ips[2].Assigned {
+		t.Errorf("droplet-bound and locked IPs must count as assigned, got %+v / %+v", ips[1], ips[2])
+	}
+
+	if err := (DigitalOcean{}).IPAddressRelease(ctx, ips[0]); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if gotDelete != "/v2/reserved_ips/45.55.96.47" {
+		t.Errorf("unexpected delete path %q", gotDelete)
+	}
+}
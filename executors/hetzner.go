@@ -3,6 +3,7 @@ package executors
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
@@ -44,9 +45,12 @@ func (h Hetzner) ServersGet(ctx context.Context, vendorIDs []string, regions []s
 		}
 
 		// map hetzner status to our state format
-		state := "RUNNING"
-		if server.Status != hcloud.ServerStatusRunning {
-			state = string(server.Status)
+		state := string(server.Status)
+		switch server.Status {
+		case hcloud.ServerStatusRunning:
+			state = "RUNNING"
+		case hcloud.ServerStatusOff:
+			state = "STOPPED"
 		}
 
 		// guard against nil Datacenter in case of incomplete API response
@@ -80,14 +84,37 @@ func (h Hetzner) ServerDelete(ctx context.Context, server core.Server) error {
 	return err
 }
 
-// ServerStop is unsupported on Hetzner
+// ServerStop powers off the specified Hetzner Cloud server. this is a hard
+// power cut, not an ACPI shutdown — janitor targets throwaway test boxes.
 func (h Hetzner) ServerStop(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	id, err := parseHetznerID(server.VendorID)
+	if err != nil {
+		return err
+	}
+	_, _, err = h.client(ctx).Server.Poweroff(ctx, &hcloud.Server{ID: id})
+	return err
 }
 
-// ServerStart is unsupported on Hetzner
+// ServerStart powers on the specified Hetzner Cloud server
 func (h Hetzner) ServerStart(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	id, err := parseHetznerID(server.VendorID)
+	if err != nil {
+		return err
+	}
+	_, _, err = h.client(ctx).Server.Poweron(ctx, &hcloud.Server{ID: id})
+	return err
+}
+
+// ServerMark sets the quarantine mark label on the server
+func (h Hetzner) ServerMark(ctx context.Context, server core.Server, value string) error {
+	id, err := parseHetznerID(server.VendorID)
+	if err != nil {
+		return err
+	}
+	_, _, err = h.client(ctx).Server.Update(ctx, &hcloud.Server{ID: id}, hcloud.ServerUpdateOpts{
+		Labels: hetznerMarkLabels(server.Tags, value),
+	})
+	return err
 }
 
 // LoadBalancersGet returns all Hetzner Cloud load balancers with target counts
@@ -163,6 +190,18 @@ func (h Hetzner) LoadBalancerDelete(ctx context.Context, loadBalancer core.LoadB
 	return err
 }
 
+// LoadBalancerMark sets the quarantine mark label on the load balancer
+func (h Hetzner) LoadBalancerMark(ctx context.Context, loadBalancer core.LoadBalancer, value string) error {
+	id, err := parseHetznerID(loadBalancer.LoadBalancerArn)
+	if err != nil {
+		return err
+	}
+	_, _, err = h.client(ctx).LoadBalancer.Update(ctx, &hcloud.LoadBalancer{ID: id}, hcloud.LoadBalancerUpdateOpts{
+		Labels: hetznerMarkLabels(loadBalancer.Tags, value),
+	})
+	return err
+}
+
 // VolumesGet returns all Hetzner Cloud volumes with attachment status
 func (h Hetzner) VolumesGet(ctx context.Context) ([]core.Volume, error) {
 	client := h.client(ctx)
@@ -201,16 +240,202 @@ func (h Hetzner) VolumesGet(ctx context.Context) ([]core.Volume, error) {
 	return result, nil
 }
 
-// SshKeysGet is unsupported on Hetzner today (no feature request yet)
+// SshKeysGet returns all SSH keys of the Hetzner Cloud project. keys are
+// project-wide, so Region stays empty.
 func (h Hetzner) SshKeysGet(ctx context.Context) ([]core.SshKey, error) {
-	return nil, core.ErrUnsupported
+	// All() handles pagination internally
+	keys, err := h.client(ctx).SSHKey.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]core.SshKey, 0, len(keys))
+	for _, key := range keys {
+		sshKey := core.SshKey{
+			VendorID: fmt.Sprintf("%d", key.ID),
+			Name:     key.Name,
+			Created:  key.Created,
+			Tags:     hetznerLabelsToTags(key.Labels),
+		}
+		// zero Created → Age stays 0 and keep-last-N falls back to ID order
+		if !key.Created.IsZero() {
+			sshKey.Age = time.Since(key.Created).Hours() / 24.0
+		} else {
+			core.Warnf(ctx, "missing Created for ssh key %q", key.Name)
+		}
+		result = append(result, sshKey)
+	}
+
+	return result, nil
 }
 
-// SshKeyDelete is unsupported on Hetzner today
+// SshKeyDelete removes the specified Hetzner Cloud SSH key
 func (h Hetzner) SshKeyDelete(ctx context.Context, sshKey core.SshKey) error {
+	id, err := parseHetznerID(sshKey.VendorID)
+	if err != nil {
+		return err
+	}
+	_, err = h.client(ctx).SSHKey.Delete(ctx, &hcloud.SSHKey{ID: id})
+	return err
+}
+
+// SnapshotsGet returns all Hetzner Cloud snapshot images. system images and
+// backups are never listed; snapshots are location-independent (Region stays
+// empty) and are named by their description.
+func (h Hetzner) SnapshotsGet(ctx context.Context) ([]core.Snapshot, error) {
+	// AllWithOpts() handles pagination internally
+	images, err := h.client(ctx).Image.AllWithOpts(ctx, hcloud.ImageListOpts{Type: []hcloud.ImageType{hcloud.ImageTypeSnapshot}})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]core.Snapshot, 0, len(images))
+	for _, image := range images {
+		var age float64
+		if !image.Created.IsZero() {
+			age = time.Since(image.Created).Hours() / 24.0
+		} else {
+			core.Warnf(ctx, "missing Created for snapshot %d", image.ID)
+		}
+		name := image.Description
+		if name == "" {
+			name = fmt.Sprintf("%d", image.ID)
+		}
+		result = append(result, core.Snapshot{
+			VendorID: fmt.Sprintf("%d", image.ID),
+			Name:     name,
+			Age:      age,
+			Type:     core.SnapshotTypeSnapshot,
+			Tags:     hetznerLabelsToTags(image.Labels),
+		})
+	}
+
+	return result, nil
+}
+
+// SnapshotDelete removes the specified Hetzner Cloud snapshot image
+func (h Hetzner) SnapshotDelete(ctx context.Context, snapshot core.Snapshot) error {
+	id, err := parseHetznerID(snapshot.VendorID)
+	if err != nil {
+		return err
+	}
+	_, err = h.client(ctx).Image.Delete(ctx, &hcloud.Image{ID: id})
+	return err
+}
+
+// Hetzner has two kinds of public address with separate APIs; Type records
+// which one an IPAddress came from so the release hits the right endpoint.
+const (
+	hetznerFloatingIP = "floating"
+	hetznerPrimaryIP  = "primary"
+)
+
+// IPAddressesGet returns all Hetzner Cloud floating and primary IPs. a primary
+// IP with auto_delete set goes away with its server and is never listed.
+func (h Hetzner) IPAddressesGet(ctx context.Context) ([]core.IPAddress, error) {
+	client := h.client(ctx)
+	// All() handles pagination internally
+	floatingIPs, err := client.FloatingIP.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	primaryIPs, err := client.PrimaryIP.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]core.IPAddress, 0, len(floatingIPs)+len(primaryIPs))
+	for _, ip := range floatingIPs {
+		region := ""
+		if ip.HomeLocation != nil {
+			region = ip.HomeLocation.Name
+		}
+		result = append(result, core.IPAddress{
+			VendorID: fmt.Sprintf("%d", ip.ID),
+			Address:  ip.IP.String(),
+			Name:     ip.Name,
+			Age:      hetznerIPAge(ctx, ip.Created, ip.Name),
+			Region:   region,
+			Type:     hetznerFloatingIP,
+			Assigned: ip.Server != nil,
+			Tags:     hetznerLabelsToTags(ip.Labels),
+		})
+	}
+	for _, ip := range primaryIPs {
+		if ip.AutoDelete {
+			continue
+		}
+		region := ""
+		if ip.Location != nil {
+			region = ip.Location.Name
+		}
+		result = append(result, core.IPAddress{
+			VendorID: fmt.Sprintf("%d", ip.ID),
+			Address:  ip.IP.String(),
+			Name:     ip.Name,
+			Age:      hetznerIPAge(ctx, ip.Created, ip.Name),
+			Region:   region,
+			Type:     hetznerPrimaryIP,
+			Assigned: ip.AssigneeID != 0,
+			Tags:     hetznerLabelsToTags(ip.Labels),
+		})
+	}
+
+	return result, nil
+}
+
+// hetznerIPAge converts Created to days; zero Created → Age=0 (WARN by policy)
+func hetznerIPAge(ctx context.Context, created time.Time, name string) float64 {
+	if created.IsZero() {
+		core.Warnf(ctx, "missing Created for IP %q", name)
+		return 0
+	}
+	return time.Since(created).Hours() / 24.0
+}
+
+// IPAddressRelease deletes the specified Hetzner Cloud floating or primary IP
+func (h Hetzner) IPAddressRelease(ctx context.Context, ipAddress core.IPAddress) error {
+	id, err := parseHetznerID(ipAddress.VendorID)
+	if err != nil {
+		return err
+	}
+	client := h.client(ctx)
+	switch ipAddress.Type {
+	case hetznerFloatingIP:
+		_, err = client.FloatingIP.Delete(ctx, &hcloud.FloatingIP{ID: id})
+	case hetznerPrimaryIP:
+		_, err = client.PrimaryIP.Delete(ctx, &hcloud.PrimaryIP{ID: id})
+	default:
+		err = fmt.Errorf("unrecognised Hetzner IP type %q", ipAddress.Type)
+	}
+	return err
+}
+
+// TargetGroupsGet is unsupported: Hetzner load balancer targets live on the load balancer
+func (h Hetzner) TargetGroupsGet(ctx context.Context) ([]core.TargetGroup, error) {
+	return nil, core.ErrUnsupported
+}
+
+// TargetGroupMark is unsupported: Hetzner has no target groups
+func (h Hetzner) TargetGroupMark(ctx context.Context, targetGroup core.TargetGroup, value string) error {
+	return core.ErrUnsupported
+}
+
+// TargetGroupDelete is unsupported: Hetzner has no target groups
+func (h Hetzner) TargetGroupDelete(ctx context.Context, targetGroup core.TargetGroup) error {
 	return core.ErrUnsupported
 }
 
+// SkippedRegionsGet is unsupported: Hetzner lists every location in one call
+func (h Hetzner) SkippedRegionsGet(ctx context.Context) ([]core.SkippedRegion, error) {
+	return nil, core.ErrUnsupported
+}
+
+// AccountsGet is unsupported: a Hetzner token reaches a single account
+func (h Hetzner) AccountsGet(ctx context.Context) ([]core.Account, error) {
+	return nil, core.ErrUnsupported
+}
+
 // VolumeDelete removes the specified Hetzner Cloud volume
 func (h Hetzner) VolumeDelete(ctx context.Context, volume core.Volume) error {
 	client := h.client(ctx)
@@ -222,6 +447,32 @@ func (h Hetzner) VolumeDelete(ctx context.Context, volume core.Volume) error {
 	return err
 }
 
+// VolumeMark sets the quarantine mark label on the volume
+func (h Hetzner) VolumeMark(ctx context.Context, volume core.Volume, value string) error {
+	id, err := parseHetznerID(volume.VendorID)
+	if err != nil {
+		return err
+	}
+	_, _, err = h.client(ctx).Volume.Update(ctx, &hcloud.Volume{ID: id}, hcloud.VolumeUpdateOpts{
+		Labels: hetznerMarkLabels(volume.Tags, value),
+	})
+	return err
+}
+
+// hetznerMarkLabels rebuilds the label map from the listed tags with the mark
+// label set to value. Update replaces the whole label set, so every existing
+// label must be sent back; label keys cannot contain '=', making the split
+// on the first '=' exact.
+func hetznerMarkLabels(tags []string, value string) map[string]string {
+	labels := make(map[string]string, len(tags)+1)
+	for _, tag := range tags {
+		key, val, _ := strings.Cut(tag, "=")
+		labels[key] = val
+	}
+	labels[core.MarkTagKey] = value
+	return labels
+}
+
 // parseHetznerID strictly parses a Hetzner resource ID. Unlike fmt.Sscanf with
 // "%d", it rejects inputs that have any trailing non-digit garbage (e.g.
 // "123abc") so we never silently act on a partial match — that was B9.
@@ -268,7 +519,10 @@ func hetznerLabelsToTags(labels map[string]string) []string {
 // redirects the SDK to an httptest server (must mount routes under /v1/...).
 func (h Hetzner) client(ctx context.Context) *hcloud.Client {
 	apiToken, _ := ctx.Value(core.HetznerPatKey).(string)
-	opts := []hcloud.ClientOption{hcloud.WithToken(apiToken)}
+	opts := []hcloud.ClientOption{
+		hcloud.WithToken(apiToken),
+		hcloud.WithHTTPClient(&http.Client{Transport: apiCallTransport{cloud: "hetzner"}}),
+	}
 	// test seam: if a base URL override is present, redirect the SDK to it.
 	// hcloud.WithEndpoint doesn't validate, so we pre-parse and warn on
 	// failure rather than silently hitting api.hetzner.cloud.
@@ -3,6 +3,7 @@ package executors
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cloud66/janitor/core"
 )
@@ -230,3 +232,238 @@ func TestHetzner_LoadBalancersGet_Pagination(t *testing.T) {
 		}
 	}
 }
+
+// TestHetzner_ServerStopStart asserts poweroff/poweron actions are posted for
+// the parsed ID and that malformed IDs are rejected before any HTTP call.
+func TestHetzner_ServerStopStart(t *testing.T) {
+	var gotPaths []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/servers/", func(w http.ResponseWriter, r *http.Request) {
+		gotPaths = append(gotPaths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"action":{"id":1,"status":"running","command":"poweroff"}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	h := Hetzner{}
+	if err := h.ServerStop(newHetznerCtx(ts), core.Server{VendorID: "42"}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := h.ServerStart(newHetznerCtx(ts), core.Server{VendorID: "42"}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	want := []string{"/v1/servers/42/actions/poweroff", "/v1/servers/42/actions/poweron"}
+	if !reflect.DeepEqual(gotPaths, want) {
+		t.Errorf("want %v, got %v", want, gotPaths)
+	}
+	if err := h.ServerStop(newHetznerCtx(ts), core.Server{VendorID: "42abc"}); err == nil {
+		t.Error("want error on malformed id, got nil")
+	}
+	if len(gotPaths) != 2 {
+		t.Errorf("malformed id must not hit the API, got %v", gotPaths)
+	}
+}
+
+// TestHetzner_Mark pins that a mark re-sends every existing label: Update
+// replaces the label set, so dropping one would silently strip e.g. a
+// permanent=true label from the resource.
+func TestHetzner_Mark(t *testing.T) {
+	got := map[string]map[string]string{}
+	mux := http.NewServeMux()
+	for _, prefix := range []string{"/v1/servers/", "/v1/load_balancers/", "/v1/volumes/"} {
+		mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut {
+				t.Errorf("want PUT, got %s", r.Method)
+			}
+			var req struct {
+				Labels map[string]string `json:"labels"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			got[r.URL.Path] = req.Labels
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		})
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	h := Hetzner{}
+	ctx := newHetznerCtx(ts)
+	if err := h.ServerMark(ctx, core.Server{VendorID: "1", Tags: []string{"env=qa", "bare="}}, "1-aa"); err != nil {
+		t.Fatalf("server: %v", err)
+	}
+	if err := h.LoadBalancerMark(ctx, core.LoadBalancer{LoadBalancerArn: "2"}, "2-bb"); err != nil {
+		t.Fatalf("lb: %v", err)
+	}
+	if err := h.VolumeMark(ctx, core.Volume{VendorID: "3", Tags: []string{core.MarkTagKey + "=old"}}, "3-cc"); err != nil {
+		t.Fatalf("volume: %v", err)
+	}
+	want := map[string]map[string]string{
+		"/v1/servers/1":        {"env": "qa", "bare": "", core.MarkTagKey: "1-aa"},
+		"/v1/load_balancers/2": {core.MarkTagKey: "2-bb"},
+		"/v1/volumes/3":        {core.MarkTagKey: "3-cc"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if err := h.ServerMark(ctx, core.Server{VendorID: "1x"}, "x"); err == nil {
+		t.Error("want error on malformed id, got nil")
+	}
+}
+
+func TestHetzner_SshKeysGet(t *testing.T) {
+	body := readFixture(t, "hetzner/ssh_keys_list.json")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/ssh_keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	keys, err := Hetzner{}.SshKeysGet(newHetznerCtx(ts))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("want 2 keys, got %d", len(keys))
+	}
+	if keys[0].VendorID != "101" || keys[0].Name != "c66-stack-a" || keys[0].Region != "" {
+		t.Errorf("unexpected key 0: %+v", keys[0])
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !keys[0].Created.Equal(want) || keys[0].Age <= 0 {
+		t.Errorf("want Created %v with positive age, got %v / %f", want, keys[0].Created, keys[0].Age)
+	}
+	// labels flow through hetznerLabelsToTags so the permanent marker applies
+	if !reflect.DeepEqual(keys[1].Tags, []string{"lifecycle=permanent"}) {
+		t.Errorf("want normalized labels, got %v", keys[1].Tags)
+	}
+}
+
+func TestHetzner_SshKeyDelete_IDParsing(t *testing.T) {
+	var gotPaths []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/ssh_keys/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("want DELETE, got %s", r.Method)
+		}
+		gotPaths = append(gotPaths, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	if err := (Hetzner{}).SshKeyDelete(newHetznerCtx(ts), core.SshKey{VendorID: "101"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for _, bad := range []string{"", "101abc", "0", "+101"} {
+		if err := (Hetzner{}).SshKeyDelete(newHetznerCtx(ts), core.SshKey{VendorID: bad}); err == nil {
+			t.Errorf("want error on id %q, got nil", bad)
+		}
+	}
+	if !reflect.DeepEqual(gotPaths, []string{"/v1/ssh_keys/101"}) {
+		t.Errorf("only the valid id may hit the API, got %v", gotPaths)
+	}
+}
+
+func TestHetzner_SnapshotsGet(t *testing.T) {
+	body := readFixture(t, "hetzner/snapshots_list.json")
+	var gotQuery, gotDelete string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/images", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("type")
+		w.Write(body)
+	})
+	mux.HandleFunc("/v1/images/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("want DELETE, got %s", r.Method)
+		}
+		gotDelete = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := newHetznerCtx(ts)
+	snapshots, err := Hetzner{}.SnapshotsGet(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// system images and backups must never be listed
+	if gotQuery != "snapshot" {
+		t.Errorf("want type=snapshot filter, got %q", gotQuery)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("want 1 snapshot, got %d", len(snapshots))
+	}
+	snap := snapshots[0]
+	if snap.VendorID != "4711" || snap.Name != "c66-stack-a before resize" || snap.Age <= 0 {
+		t.Errorf("unexpected snapshot: %+v", snap)
+	}
+	if !reflect.DeepEqual(snap.Tags, []string{"lifecycle=permanent"}) {
+		t.Errorf("want normalized labels, got %v", snap.Tags)
+	}
+
+	if err := (Hetzner{}).SnapshotDelete(ctx, snap); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if gotDelete != "/v1/images/4711" {
+		t.Errorf("unexpected delete path %q", gotDelete)
+	}
+}
+
+func TestHetzner_IPAddressesGet(t *testing.T) {
+	floating := readFixture(t, "hetzner/floating_ips_list.json")
+	primary := readFixture(t, "hetzner/primary_ips_list.json")
+	var gotDeletes []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/floating_ips", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(floating)
+	})
+	mux.HandleFunc("/v1/primary_ips", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(primary)
+	})
+	deleteHandler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("want DELETE, got %s", r.Method)
+		}
+		gotDeletes = append(gotDeletes, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+	mux.HandleFunc("/v1/floating_ips/", deleteHandler)
+	mux.HandleFunc("/v1/primary_ips/", deleteHandler)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := newHetznerCtx(ts)
+	ips, err := Hetzner{}.IPAddressesGet(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// the auto_delete primary IP lives and dies with its server
+	if len(ips) != 3 {
+		t.Fatalf("want 2 floating + 1 primary IP, got %+v", ips)
+	}
+	if ips[0].Address != "198.51.100.1" || ips[0].Region != "fsn1" || ips[0].Assigned || ips[0].Age <= 0 || ips[0].Type != hetznerFloatingIP {
+		t.Errorf("unexpected floating IP: %+v", ips[0])
+	}
+	if !ips[1].Assigned || !reflect.DeepEqual(ips[1].Tags, []string{"lifecycle=permanent"}) {
+		t.Errorf("want assigned floating IP with labels, got %+v", ips[1])
+	}
+	if ips[2].VendorID != "21" || ips[2].Region != "nbg1" || ips[2].Assigned || ips[2].Type != hetznerPrimaryIP {
+		t.Errorf("unexpected primary IP: %+v", ips[2])
+	}
+
+	for _, ip := range []core.IPAddress{ips[0], ips[2]} {
+		if err := (Hetzner{}).IPAddressRelease(ctx, ip); err != nil {
+			t.Fatalf("release %s: %v", ip.VendorID, err)
+		}
+	}
+	if !reflect.DeepEqual(gotDeletes, []string{"/v1/floating_ips/11", "/v1/primary_ips/21"}) {
+		t.Errorf("release must hit the endpoint of the IP's type, got %v", gotDeletes)
+	}
+	if err := (Hetzner{}).IPAddressRelease(ctx, core.IPAddress{VendorID: "11"}); err == nil {
+		t.Error("want an error for an IP of unknown type")
+	}
+}
@@ -2,6 +2,7 @@ package executors
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/cloud66/janitor/core"
@@ -58,7 +59,7 @@ func (v Vultr) ServersGet(ctx context.Context, vendorIDs []string, regions []str
 			Name:     inst.Label,
 			Age:      age,
 			Region:   inst.Region,
-			State:    "RUNNING",
+			State:    mapVultrState(inst.PowerStatus),
 			Tags:     inst.Tags,
 		})
 	}
@@ -73,14 +74,39 @@ func (v Vultr) ServerDelete(ctx context.Context, server core.Server) error {
 	return client.Instance.Delete(ctx, server.VendorID)
 }
 
-// ServerStop is unsupported on Vultr
+// ServerStop halts the specified Vultr instance
 func (v Vultr) ServerStop(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	return v.client(ctx).Instance.Halt(ctx, server.VendorID)
 }
 
-// ServerStart is unsupported on Vultr
+// ServerStart starts the specified Vultr instance. note Vultr restarts an
+// instance that is already running, so callers must only start STOPPED ones.
 func (v Vultr) ServerStart(ctx context.Context, server core.Server) error {
-	return core.ErrUnsupported
+	return v.client(ctx).Instance.Start(ctx, server.VendorID)
+}
+
+// ServerMark sets the quarantine mark tag on the instance. Vultr tags are
+// plain strings, so the mark is stored as "<key>=<value>" and any previous
+// mark is replaced rather than accumulated.
+func (v Vultr) ServerMark(ctx context.Context, server core.Server, value string) error {
+	tags := make([]string, 0, len(server.Tags)+1)
+	for _, tag := range server.Tags {
+		if !strings.HasPrefix(tag, core.MarkTagKey+"=") {
+			tags = append(tags, tag)
+		}
+	}
+	tags = append(tags, core.MarkTagKey+"="+value)
+	_, _, err := v.client(ctx).Instance.Update(ctx, server.VendorID, &govultr.InstanceUpdateReq{Tags: tags})
+	return err
+}
+
+// mapVultrState maps the instance power_status to janitor's state form.
+// anything other than "stopped" is treated as running (fail-safe).
+func mapVultrState(powerStatus string) string {
+	if powerStatus == "stopped" {
+		return "STOPPED"
+	}
+	return "RUNNING"
 }
 
 // LoadBalancersGet returns all Vultr load balancers with accurate instance counts
@@ -137,14 +163,57 @@ func (v Vultr) LoadBalancerDelete(ctx context.Context, loadBalancer core.LoadBal
 	return client.LoadBalancer.Delete(ctx, loadBalancer.LoadBalancerArn)
 }
 
-// SshKeysGet is unsupported on Vultr today
+// LoadBalancerMark is unsupported: Vultr load balancers carry no tags
+func (v Vultr) LoadBalancerMark(ctx context.Context, loadBalancer core.LoadBalancer, value string) error {
+	return core.ErrUnsupported
+}
+
+// SshKeysGet returns all Vultr SSH keys. keys are account-wide (Region stays
+// empty) and carry no tags.
 func (v Vultr) SshKeysGet(ctx context.Context) ([]core.SshKey, error) {
-	return nil, core.ErrUnsupported
+	client := v.client(ctx)
+
+	var allKeys []govultr.SSHKey
+	opts := &govultr.ListOptions{PerPage: 100}
+
+	// paginate through all keys using cursor-based meta.Links.Next
+	for {
+		keys, meta, _, err := client.SSHKey.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		allKeys = append(allKeys, keys...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			break
+		}
+		opts.Cursor = meta.Links.Next
+	}
+
+	result := make([]core.SshKey, 0, len(allKeys))
+	for _, key := range allKeys {
+		sshKey := core.SshKey{VendorID: key.ID, Name: key.Name}
+		// unparseable date → zero Created, so keep-last-N falls back to ID
+		// order for this key rather than trusting a fabricated age (B10).
+		if key.DateCreated != "" {
+			createdAt, err := time.Parse(time.RFC3339, key.DateCreated)
+			if err != nil {
+				core.Warnf(ctx, "unparseable DateCreated %q for ssh key %q", key.DateCreated, key.Name)
+			} else {
+				sshKey.Created = createdAt
+				sshKey.Age = time.Since(createdAt).Hours() / 24.0
+			}
+		}
+		result = append(result, sshKey)
+	}
+
+	return result, nil
 }
 
-// SshKeyDelete is unsupported on Vultr today
+// SshKeyDelete removes the specified Vultr SSH key
 func (v Vultr) SshKeyDelete(ctx context.Context, sshKey core.SshKey) error {
-	return core.ErrUnsupported
+	return v.client(ctx).SSHKey.Delete(ctx, sshKey.VendorID)
 }
 
 // VolumesGet returns all Vultr block storage volumes with attachment status
@@ -200,6 +269,138 @@ func (v Vultr) VolumeDelete(ctx context.Context, volume core.Volume) error {
 	return client.BlockStorage.Delete(ctx, volume.VendorID)
 }
 
+// VolumeMark is unsupported: Vultr block storage carries no tags
+func (v Vultr) VolumeMark(ctx context.Context, volume core.Volume, value string) error {
+	return core.ErrUnsupported
+}
+
+// SnapshotsGet returns all Vultr snapshots. snapshots are account-wide
+// (Region stays empty), carry no tags and are named by their description.
+func (v Vultr) SnapshotsGet(ctx context.Context) ([]core.Snapshot, error) {
+	client := v.client(ctx)
+
+	var allSnapshots []govultr.Snapshot
+	opts := &govultr.ListOptions{PerPage: 100}
+
+	// paginate through all snapshots using cursor-based meta.Links.Next
+	for {
+		snapshots, meta, _, err := client.Snapshot.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		allSnapshots = append(allSnapshots, snapshots...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			break
+		}
+		opts.Cursor = meta.Links.Next
+	}
+
+	result := make([]core.Snapshot, 0, len(allSnapshots))
+	for _, snap := range allSnapshots {
+		var age float64
+		if snap.DateCreated != "" {
+			createdAt, err := time.Parse(time.RFC3339, snap.DateCreated)
+			if err != nil {
+				core.Warnf(ctx, "unparseable DateCreated %q for snapshot %q", snap.DateCreated, snap.Description)
+			} else {
+				age = time.Since(createdAt).Hours() / 24.0
+			}
+		}
+		name := snap.Description
+		if name == "" {
+			name = snap.ID
+		}
+		result = append(result, core.Snapshot{
+			VendorID: snap.ID,
+			Name:     name,
+			Age:      age,
+			Type:     core.SnapshotTypeSnapshot,
+		})
+	}
+
+	return result, nil
+}
+
+// SnapshotDelete removes the specified Vultr snapshot
+func (v Vultr) SnapshotDelete(ctx context.Context, snapshot core.Snapshot) error {
+	return v.client(ctx).Snapshot.Delete(ctx, snapshot.VendorID)
+}
+
+// IPAddressesGet returns all Vultr reserved IPs. the API reports neither an
+// allocation time nor tags, so Age stays 0 and the default policy keeps them
+// (WARN). the label doubles as the name.
+func (v Vultr) IPAddressesGet(ctx context.Context) ([]core.IPAddress, error) {
+	client := v.client(ctx)
+
+	var allIPs []govultr.ReservedIP
+	opts := &govultr.ListOptions{PerPage: 100}
+
+	// paginate through all reserved IPs using cursor-based meta.Links.Next
+	for {
+		ips, meta, _, err := client.ReservedIP.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		allIPs = append(allIPs, ips...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			break
+		}
+		opts.Cursor = meta.Links.Next
+	}
+
+	result := make([]core.IPAddress, 0, len(allIPs))
+	for _, ip := range allIPs {
+		name := ip.Label
+		if name == "" {
+			name = ip.Subnet
+		}
+		result = append(result, core.IPAddress{
+			VendorID: ip.ID,
+			Address:  ip.Subnet,
+			Name:     name,
+			Region:   ip.Region,
+			Type:     ip.IPType,
+			Assigned: ip.InstanceID != "",
+		})
+	}
+
+	return result, nil
+}
+
+// IPAddressRelease deletes the specified Vultr reserved IP
+func (v Vultr) IPAddressRelease(ctx context.Context, ipAddress core.IPAddress) error {
+	return v.client(ctx).ReservedIP.Delete(ctx, ipAddress.VendorID)
+}
+
+// TargetGroupsGet is unsupported: Vultr load balancers forward to instances directly
+func (v Vultr) TargetGroupsGet(ctx context.Context) ([]core.TargetGroup, error) {
+	return nil, core.ErrUnsupported
+}
+
+// TargetGroupMark is unsupported: Vultr has no target groups
+func (v Vultr) TargetGroupMark(ctx context.Context, targetGroup core.TargetGroup, value string) error {
+	return core.ErrUnsupported
+}
+
+// TargetGroupDelete is unsupported: Vultr has no target groups
+func (v Vultr) TargetGroupDelete(ctx context.Context, targetGroup core.TargetGroup) error {
+	return core.ErrUnsupported
+}
+
+// SkippedRegionsGet is unsupported: Vultr lists every region in one call
+func (v Vultr) SkippedRegionsGet(ctx context.Context) ([]core.SkippedRegion, error) {
+	return nil, core.ErrUnsupported
+}
+
+// AccountsGet is unsupported: a Vultr token reaches a single account
+func (v Vultr) AccountsGet(ctx context.Context) ([]core.Account, error) {
+	return nil, core.ErrUnsupported
+}
+
 // client creates an authenticated Vultr API client. Credentials come from
 // typed ctx key core.VultrPatKey. For tests, core.VultrBaseURLKey redirects
 // the SDK to an httptest server via SetBaseURL.
@@ -208,6 +409,7 @@ func (v Vultr) client(ctx context.Context) *govultr.Client {
 	// reuse the same oauth2 token pattern as DigitalOcean
 	tokenSource := &TokenSource{AccessToken: apiKey}
 	oauthClient := oauth2.NewClient(ctx, tokenSource)
+	oauthClient.Transport = apiCallTransport{cloud: "vultr", base: oauthClient.Transport}
 	client := govultr.NewClient(oauthClient)
 	if base, ok := ctx.Value(core.VultrBaseURLKey).(string); ok && base != "" {
 		// SetBaseURL validates the URL; surface failures via Warnf so tests
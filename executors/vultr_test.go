@@ -3,11 +3,15 @@ package executors
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cloud66/janitor/core"
 )
@@ -180,3 +184,225 @@ func TestVultr_ServersGet_MalformedDate_B10(t *testing.T) {
 		t.Errorf("expected WARN log in sink, got %q", warnBuf.String())
 	}
 }
+
+// TestVultr_ServerStopStart asserts halt/start hit the instance-scoped
+// endpoints and that power_status drives the mapped State.
+func TestVultr_ServerStopStart(t *testing.T) {
+	var gotPaths []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/instances") {
+			w.Write([]byte(`{"instances":[
+				{"id":"a","label":"on","date_created":"2024-01-01T00:00:00+00:00","power_status":"running"},
+				{"id":"b","label":"off","date_created":"2024-01-01T00:00:00+00:00","power_status":"stopped"}
+			],"meta":{"total":2,"links":{"next":"","prev":""}}}`))
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		gotPaths = append(gotPaths, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	servers, err := Vultr{}.ServersGet(newVultrCtx(ts), nil, nil)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	states := map[string]string{}
+	for _, s := range servers {
+		states[s.Name] = s.State
+	}
+	if states["on"] != "RUNNING" || states["off"] != "STOPPED" {
+		t.Errorf("want on=RUNNING off=STOPPED, got %v", states)
+	}
+
+	if err := (Vultr{}).ServerStop(newVultrCtx(ts), core.Server{VendorID: "a"}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := (Vultr{}).ServerStart(newVultrCtx(ts), core.Server{VendorID: "b"}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if len(gotPaths) != 2 || !strings.HasSuffix(gotPaths[0], "/instances/a/halt") || !strings.HasSuffix(gotPaths[1], "/instances/b/start") {
+		t.Errorf("want halt on a then start on b, got %v", gotPaths)
+	}
+}
+
+func TestVultr_Mark(t *testing.T) {
+	var gotTags []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/v2/instances/a" {
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Tags []string `json:"tags"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotTags = req.Tags
+		w.Write([]byte(`{"instance":{"id":"a"}}`))
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	server := core.Server{VendorID: "a", Tags: []string{"team=qa", core.MarkTagKey + "=stale"}}
+	if err := (Vultr{}).ServerMark(newVultrCtx(ts), server, "1-aa"); err != nil {
+		t.Fatalf("mark: %v", err)
+	}
+	want := []string{"team=qa", core.MarkTagKey + "=1-aa"}
+	if !reflect.DeepEqual(gotTags, want) {
+		t.Errorf("want stale mark replaced: %v, got %v", want, gotTags)
+	}
+	if err := (Vultr{}).LoadBalancerMark(newVultrCtx(ts), core.LoadBalancer{}, "x"); !errors.Is(err, core.ErrUnsupported) {
+		t.Errorf("LB marks: want ErrUnsupported, got %v", err)
+	}
+	if err := (Vultr{}).VolumeMark(newVultrCtx(ts), core.Volume{}, "x"); !errors.Is(err, core.ErrUnsupported) {
+		t.Errorf("volume marks: want ErrUnsupported, got %v", err)
+	}
+}
+
+// TestVultr_SshKeysGet_CursorPagination walks both pages and carries
+// date_created into Created/Age; a malformed date leaves them zero.
+func TestVultr_SshKeysGet_CursorPagination(t *testing.T) {
+	page1 := readFixture(t, "vultr/ssh_keys_list_page1.json")
+	page2 := readFixture(t, "vultr/ssh_keys_list_page2.json")
+	var cursors []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/ssh-keys") {
+			http.NotFound(w, r)
+			return
+		}
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+		if cursor == "" {
+			w.Write(page1)
+			return
+		}
+		w.Write(page2)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	var warn bytes.Buffer
+	ctx := context.WithValue(newVultrCtx(ts), core.WarnWriterKey, &warn)
+	keys, err := Vultr{}.SshKeysGet(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(cursors, []string{"", "cursor-page-2"}) {
+		t.Errorf("want two calls following meta.links.next, got cursors %q", cursors)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("want 2 keys across 2 pages, got %d", len(keys))
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); keys[0].VendorID != "key-1" || !keys[0].Created.Equal(want) || keys[0].Age <= 0 {
+		t.Errorf("unexpected key 0: %+v", keys[0])
+	}
+	if !keys[1].Created.IsZero() || keys[1].Age != 0 {
+		t.Errorf("malformed date must leave Created/Age zero, got %+v", keys[1])
+	}
+	if !strings.Contains(warn.String(), "not-a-date") {
+		t.Errorf("want a warning for the malformed date, got %q", warn.String())
+	}
+}
+
+func TestVultr_SshKeyDelete_IDPassthrough(t *testing.T) {
+	var gotPath string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("want DELETE, got %s", r.Method)
+		}
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	if err := (Vultr{}).SshKeyDelete(newVultrCtx(ts), core.SshKey{VendorID: "key-1"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if gotPath != "/v2/ssh-keys/key-1" {
+		t.Errorf("want /v2/ssh-keys/key-1, got %q", gotPath)
+	}
+}
+
+func TestVultr_SnapshotsGet(t *testing.T) {
+	body := readFixture(t, "vultr/snapshots_list.json")
+	var gotDelete string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete:
+			gotDelete = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/snapshots"):
+			w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	ctx := newVultrCtx(ts)
+	snapshots, err := Vultr{}.SnapshotsGet(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("want 2 snapshots, got %d", len(snapshots))
+	}
+	if snapshots[0].Name != "pre-upgrade" || snapshots[0].Age <= 0 || snapshots[0].Type != core.SnapshotTypeSnapshot {
+		t.Errorf("unexpected snapshot 0: %+v", snapshots[0])
+	}
+	// no description → fall back to the ID so the output line has a name
+	if snapshots[1].Name != "snap-2" {
+		t.Errorf("want ID as name, got %q", snapshots[1].Name)
+	}
+
+	if err := (Vultr{}).SnapshotDelete(ctx, snapshots[0]); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if !strings.HasSuffix(gotDelete, "/snapshots/snap-1") {
+		t.Errorf("unexpected delete path %q", gotDelete)
+	}
+}
+
+func TestVultr_IPAddressesGet(t *testing.T) {
+	body := readFixture(t, "vultr/reserved_ips_list.json")
+	var gotDelete string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete:
+			gotDelete = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/reserved-ips"):
+			w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	ctx := newVultrCtx(ts)
+	ips, err := Vultr{}.IPAddressesGet(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	want := []core.IPAddress{
+		{VendorID: "rip-1", Address: "192.0.2.10", Name: "192.0.2.10", Region: "ewr", Type: "v4"},
+		{VendorID: "rip-2", Address: "192.0.2.11", Name: "api", Region: "ewr", Type: "v4", Assigned: true},
+	}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("want %+v, got %+v", want, ips)
+	}
+
+	if err := (Vultr{}).IPAddressRelease(ctx, ips[0]); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if !strings.HasSuffix(gotDelete, "/reserved-ips/rip-1") {
+		t.Errorf("unexpected delete path %q", gotDelete)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+)
+
+const (
+	// expiryPrecision is how closely checkExpiry pins down the moment a
+	// resource becomes eligible.
+	expiryPrecision = time.Minute
+	// expiryHintKey is the tag key warnings suggest for the permanent and
+	// long markers; any key works, see tagValueMatchesToken.
+	expiryHintKey = "lifetime"
+	// warningRetention is how long an announcement is remembered after its
+	// resource became eligible; by then it is deleted or has been re-tagged.
+	warningRetention = 7 * 24 * time.Hour
+)
+
+// flagWarnBefore is how far ahead of eligibility kept resources are
+// announced. 0 disables pre-expiry warnings.
+var flagWarnBefore time.Duration
+
+// checkExpiry stamps rec with the time its resource becomes eligible for
+// deletion when that is within --warn-before. the policy decides: s is
+// re-evaluated as if older, so the check follows --policy files and the
+// per-account age limits of pass p exactly like the deletion itself will.
+func checkExpiry(p pass, pol policy, s policySubject, rec *record) {
+	// an unknown age never ages into a deletion (stateWarn)
+	if flagWarnBefore <= 0 || s.Age <= 0 {
+		return
+	}
+	deletes := func(age float64) bool {
+		at := s
+		at.Age = age
+		return pol.evaluate(at, p.limits).Action == policyDelete
+	}
+	lead := flagWarnBefore.Hours() / 24
+	if !deletes(s.Age + lead) {
+		return
+	}
+	// kept at lo, deleted at hi: bisect to the crossing.
+	lo, hi := s.Age, s.Age+lead
+	for days(hi-lo) > expiryPrecision {
+		mid := (lo + hi) / 2
+		if deletes(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	at := time.Now().Add(days(hi - s.Age)).Truncate(expiryPrecision)
+	rec.ExpiresAt = &at
+	_, _ = fmt.Fprintf(p.out, "    eligible for deletion at %s\n", formatTimestamp(at))
+}
+
+// days converts a number of days to a duration.
+func days(d float64) time.Duration {
+	return time.Duration(d * 24 * float64(time.Hour))
+}
+
+// formatTimestamp prints eligibility and state times the same way everywhere.
+func formatTimestamp(at time.Time) string {
+	return at.UTC().Format("2006-01-02 15:04 MST")
+}
+
+// expiryHint tells the owner of rec how to keep it. long only helps
+// resources the age flags govern that are not long already.
+func expiryHint(rec record) string {
+	hint := fmt.Sprintf("tag it %s=%s to keep it", expiryHintKey, core.TagPermanent)
+	if rec.MaxAgeDays > 0 && rec.State != "LONG" {
+		hint += fmt.Sprintf(", or %s=%s to keep it until %.2f days old", expiryHintKey, core.TagLong, flagMaxAgeLong)
+	}
+	return hint
+}
+
+// warningMemory remembers which expiring resources were announced, so a
+// resource is announced once rather than on every run until it is deleted.
+type warningMemory interface {
+	// announced reports whether rec was already announced with about the
+	// same eligibility time.
+	announced(rec record) bool
+	// remember logs recs as announced at now.
+	remember(recs []record, now time.Time) error
+}
+
+// warningLog is the warningMemory of --warned-file, or of the process when
+// no file is set, which is enough for --action=daemon.
+type warningLog struct {
+	path string
+	mu   sync.Mutex
+	// Warned maps a resource to the eligibility time it was announced with.
+	Warned map[string]time.Time `json:"warned"`
+}
+
+// warnings is the memory notifyRun consults: --warned-file or the state
+// store, set up in main.
+var warnings warningMemory = &warningLog{Warned: map[string]time.Time{}}
+
+// loadWarningLog reads path; a missing file is an empty log.
+func loadWarningLog(path string) (*warningLog, error) {
+	l := &warningLog{path: path, Warned: map[string]time.Time{}}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if l.Warned == nil {
+		l.Warned = map[string]time.Time{}
+	}
+	return l, nil
+}
+
+// warningKey identifies a resource across runs.
+func warningKey(rec record) string {
+	return fmt.Sprintf("%s/%s/%s/%s", rec.Cloud, rec.Account, rec.Kind, rec.VendorID)
+}
+
+func (l *warningLog) announced(rec record) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at, ok := l.Warned[warningKey(rec)]
+	return ok && sameExpiry(at, *rec.ExpiresAt)
+}
+
+// sameExpiry reports whether two eligibility times announce the same
+// expiry. a resource re-tagged long moves its time by days and is announced
+// again as the new one approaches.
+func sameExpiry(a, b time.Time) bool {
+	drift := a.Sub(b)
+	return drift > -time.Hour && drift < time.Hour
+}
+
+// remember also forgets announcements long past and saves the log when it
+// has a file.
+func (l *warningLog) remember(recs []record, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, rec := range recs {
+		l.Warned[warningKey(rec)] = *rec.ExpiresAt
+	}
+	for key, at := range l.Warned {
+		if now.Sub(at) > warningRetention {
+			delete(l.Warned, key)
+		}
+	}
+	if l.path == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write then rename, so a crash never leaves a truncated log
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// withWarnBefore sets --warn-before and a fresh in-process warning log.
+func withWarnBefore(t *testing.T, lead time.Duration) {
+	t.Helper()
+	prevLead, prevLog := flagWarnBefore, warnings
+	flagWarnBefore = lead
+	warnings = &warningLog{Warned: map[string]time.Time{}}
+	t.Cleanup(func() { flagWarnBefore, warnings = prevLead, prevLog })
+}
+
+// expiringIn returns how far from now rec becomes eligible, or -1 when it
+// was not stamped.
+func expiringIn(rec record) time.Duration {
+	if rec.ExpiresAt == nil {
+		return -1
+	}
+	return time.Until(*rec.ExpiresAt)
+}
+
+func TestCheckExpiry_ServersCrossingTheirLimit(t *testing.T) {
+	withFlags(t, true, 0.5, 5.0)
+	withWarnBefore(t, 6*time.Hour)
+	captureReport(t, outputText)
+
+	servers := []core.Server{
+		{VendorID: "i-soon", Name: "soon", Age: 0.3},          // eligible in 4.8h
+		{VendorID: "i-later", Name: "later", Age: 0.1},        // in 9.6h, past the lead
+		{VendorID: "i-long", Name: "soak-long", Age: 0.3},     // long: days away
+		{VendorID: "i-perm", Name: "db-permanent", Age: 0.45}, // never
+		{VendorID: "i-odd", Name: "odd", Age: 0},              // unknown age
+		{VendorID: "i-old", Name: "old", Age: 0.6},            // deleted now
+	}
+	got := captureOutput(t, func() { deleteServers(nil, "aws", servers) })
+
+	expiring := report.summary.expiring
+	if len(expiring) != 1 || expiring[0].VendorID != "i-soon" {
+		t.Fatalf("want only i-soon expiring, got %+v", expiring)
+	}
+	if in := expiringIn(expiring[0]); in < 4*time.Hour+45*time.Minute || in > 4*time.Hour+50*time.Minute {
+		t.Errorf("want i-soon eligible in about 4h48m, got %s", in)
+	}
+	if !strings.Contains(got, "    eligible for deletion at "+formatTimestamp(*expiring[0].ExpiresAt)+"\n") {
+		t.Errorf("want the eligibility time printed, got:\n%s", got)
+	}
+}
+
+func TestCheckExpiry_FollowsThePolicy(t *testing.T) {
+	withFlags(t, true, 0.5, 5.0)
+	withWarnBefore(t, 2*time.Hour)
+	captureReport(t, outputText)
+
+	// the built-in policy deletes unattached volumes once they are an hour
+	// old, whatever --max-age-regular says
+	volumes := []core.Volume{
+		{VendorID: "vol-new", Name: "new", Age: 0.5 / 24},
+		{VendorID: "vol-used", Name: "used", Age: 0.5 / 24, Attached: true},
+	}
+	captureOutput(t, func() { deleteVolumes(nil, volumes) })
+
+	expiring := report.summary.expiring
+	if len(expiring) != 1 || expiring[0].VendorID != "vol-new" {
+		t.Fatalf("want only the unattached volume expiring, got %+v", expiring)
+	}
+	if in := expiringIn(expiring[0]); in < 29*time.Minute || in > 31*time.Minute {
+		t.Errorf("want vol-new eligible in about 30m, got %s", in)
+	}
+
+	// off by default
+	withWarnBefore(t, 0)
+	captureReport(t, outputText)
+	captureOutput(t, func() { deleteVolumes(nil, volumes) })
+	if len(report.summary.expiring) != 0 {
+		t.Errorf("--warn-before=0: want no warnings, got %+v", report.summary.expiring)
+	}
+}
+
+func TestNotifyRun_AnnouncesExpiryOnce(t *testing.T) {
+	receivers := withWebhooks(t)
+	withFlags(t, false, 0.5, 5.0)
+	withWarnBefore(t, 6*time.Hour)
+	warnings = &warningLog{path: filepath.Join(t.TempDir(), "warned.json"), Warned: map[string]time.Time{}}
+	path := warnings.(*warningLog).path
+	prevAction, prevClouds := flagAction, flagClouds
+	flagAction, flagClouds = actionDelete, "hetzner"
+	t.Cleanup(func() { flagAction, flagClouds = prevAction, prevClouds })
+
+	servers := []core.Server{{VendorID: "42", Name: "ci-box", Region: "fsn1", Age: 0.3, Tags: []string{"owner=alice"}}}
+	run := func() {
+		captureReport(t, outputText)
+		captureOutput(t, func() { deleteServers(nil, "hetzner", servers) })
+		notifyRun(context.Background(), report.summary.exitCode())
+	}
+
+	run()
+	slack := onlyText(t, receivers[webhookSlack])
+	at := formatTimestamp(*report.summary.expiring[0].ExpiresAt)
+	for _, want := range []string{
+		"0 deleted, 0 failed, 0 warnings, 1 expiring soon*",
+		"*Expiring soon*\n• hetzner server `ci-box` (42) fsn1, 0.30 days: eligible for deletion at " + at +
+			"; tag it lifetime=permanent to keep it, or lifetime=long to keep it until 5.00 days old",
+	} {
+		if !strings.Contains(slack, want) {
+			t.Errorf("slack digest lacks %q:\n%s", want, slack)
+		}
+	}
+
+	// the next run, in this process or a fresh one reading --warned-file,
+	// stays quiet about it
+	run()
+	reloaded, err := loadWarningLog(path)
+	if err != nil {
+		t.Fatalf("loading the warned file: %v", err)
+	}
+	warnings = reloaded
+	run()
+	for i, payload := range receivers[webhookSlack].payloads[1:] {
+		if text := payload["text"].(string); strings.Contains(text, "Expiring soon") {
+			t.Errorf("run %d announced ci-box again:\n%s", i+2, text)
+		}
+	}
+
+	// re-tagged long it expires days later, which is news again
+	servers[0].Name = "ci-box-long"
+	servers[0].Age = 4.9
+	run()
+	last := receivers[webhookSlack].payloads[3]["text"].(string)
+	if !strings.Contains(last, "`ci-box-long` (42) fsn1, 4.90 days: eligible for deletion at") || strings.Contains(last, "lifetime=long") {
+		t.Errorf("want the new eligibility announced without the long hint:\n%s", last)
+	}
+}
+
+func TestNotifyRun_EmailsExpiryToOwner(t *testing.T) {
+	relay := withOwnerMail(t)
+	withFlags(t, false, 0.5, 5.0)
+	withWarnBefore(t, 6*time.Hour)
+	prevAction, prevClouds := flagAction, flagClouds
+	flagAction, flagClouds = actionDelete, "aws"
+	t.Cleanup(func() { flagAction, flagClouds = prevAction, prevClouds })
+	captureReport(t, outputText)
+
+	captureOutput(t, func() {
+		deleteServers(nil, "aws", []core.Server{{VendorID: "i-1", Name: "demo", Region: "us-east-1", Age: 0.3, Tags: []string{"created-by=bob"}}})
+	})
+	notifyRun(context.Background(), report.summary.exitCode())
+
+	bob := mailedTo(t, relay, "bob@example.com")
+	at := formatTimestamp(*report.summary.expiring[0].ExpiresAt)
+	if !containsRow(bob, "aws", "server", "demo", "i-1", "us-east-1", "0.30", "0.50", "eligible", "at", strings.Fields(at)[0], strings.Fields(at)[1], "UTC",
+		"tag", "it", "lifetime=permanent", "to", "keep", "it,", "or", "lifetime=long", "to", "keep", "it", "until", "5.00", "days", "old") {
+		t.Errorf("want bob warned about demo:\n%s", bob)
+	}
+}
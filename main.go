@@ -11,9 +11,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloud66/janitor/core"
 	"github.com/cloud66/janitor/executors"
+	"github.com/robfig/cron/v3"
 )
 
 // out is the package-level sink for all user-visible output. Tests can swap
@@ -22,23 +24,89 @@ var out io.Writer = os.Stdout
 
 const (
 	actionWebServer = "webserver"
+	actionDaemon    = "daemon"
 	actionDelete    = "delete"
+	actionStop      = "stop"
+	actionStart     = "start"
 
 	//Defaults
 	defaultSshKeyKeepCount = 10
+	defaultMaxAgeSnapshot  = 30.0
 )
 
 var (
 	clouds     map[string]core.ExecutorInterface
 	flagAction string
 
-	flagMaxAgeNormal     float64
-	flagMaxAgeLong       float64
+	flagMaxAgeNormal float64
+	flagMaxAgeLong   float64
+	// flagMaxAgeSnapshot is the age snapshots and images are held to; they
+	// are backups, so it is far longer than the server allowances.
+	flagMaxAgeSnapshot   float64
 	flagSshKeysKeepCount int
+	// flagSshKeysKeepDays keeps any delete candidate younger than this many
+	// days on top of the keep-count. 0 disables it.
+	flagSshKeysKeepDays float64
 
 	flagClouds string
 	flagMock   bool
 	flagYes    bool
+	flagPolicy string
+	// quarantine: mark eligible resources first, delete on a later run once
+	// the signed mark is older than the grace window.
+	flagQuarantine         bool
+	flagQuarantineGrace    float64
+	flagMarkSigningKeyFile string
+	// flagOutput selects text (default) or a structured json/ndjson report.
+	flagOutput string
+	// flagStartTag selects which stopped servers --action=start powers on.
+	flagStartTag string
+	// flagAWSRegionConcurrency bounds how many AWS regions are scanned at once.
+	flagAWSRegionConcurrency int
+	// flagAWSRegions narrows the discovered AWS regions (include / -exclude).
+	flagAWSRegions string
+	// multi-account AWS: a shared-config profile for the base credentials
+	// and roles assumed on top of it, one pass per role.
+	flagAWSProfile         string
+	flagAWSRoleARNs        string
+	flagAWSExternalID      string
+	flagAWSRoleSessionName string
+	// flagCredentialsFile holds named accounts for --clouds=<cloud>:<name>.
+	flagCredentialsFile string
+	// flagListen is the address the webserver and daemon actions serve on.
+	flagListen string
+	// flagSchedule is when the daemon sweeps: a cron expression or interval.
+	flagSchedule string
+	// flagKeepRuns is how many run reports the daemon keeps for /runs.
+	flagKeepRuns int
+	// flagAPIToken authorizes live runs started with POST /runs.
+	flagAPIToken string
+	// incoming-webhook URLs each run's digest is posted to.
+	flagSlackWebhook      string
+	flagMattermostWebhook string
+	flagTeamsWebhook      string
+	// owner emails: the SMTP relay, and the tag and fallback address that
+	// decide who hears about which resource.
+	flagSMTPServer         string
+	flagSMTPUsername       string
+	flagSMTPPassword       string
+	flagSMTPFrom           string
+	flagOwnerTag           string
+	flagOwnerEmailDomain   string
+	flagOwnerFallbackEmail string
+	flagEmailMockRuns      bool
+	// flagWarnedFile keeps the pre-expiry announcements across runs.
+	flagWarnedFile string
+	// flagStateFile is the state store: a bolt file path, or memory:.
+	flagStateFile string
+
+	// credentials is the --credentials-file, parsed once at startup. nil
+	// means --clouds may only name plain providers.
+	credentials *credentialsFile
+
+	// loadedPolicy is the --policy file, parsed once at startup. nil means
+	// the built-in defaultPolicy applies.
+	loadedPolicy *policy
 
 	//credentials
 	flagDOPat              string
@@ -58,14 +126,355 @@ func requireYesGate(mock, yes bool) string {
 	return "Refusing to run with --mock=false without --yes on the command line."
 }
 
-func prettyPrint(message string, mock bool) {
-	// route through the package-level out sink so tests can capture output.
+// fprettyPrint writes message to w, the writer of the pass being swept,
+// labelled [MOCK] on mock runs.
+func fprettyPrint(w io.Writer, message string, mock bool) {
 	// Fprintf errors on stdout / bytes.Buffer are not actionable → ignore.
 	if mock {
-		_, _ = fmt.Fprintf(out, "[MOCK] %s", message)
+		_, _ = fmt.Fprintf(w, "[MOCK] %s", message)
 	} else {
-		_, _ = fmt.Fprintf(out, "%s", message)
+		_, _ = fmt.Fprintf(w, "%s", message)
+	}
+}
+
+// liveModeName is the banner word for a live (non-mock) run of action.
+func liveModeName(action string) string {
+	switch action {
+	case actionStop:
+		return "STOP"
+	case actionStart:
+		return "START"
 	}
+	return "DELETION"
+}
+
+// activePolicy returns the operator's policy file when one was loaded, else
+// the built-in default that mirrors the historical classification.
+func activePolicy() policy {
+	if loadedPolicy != nil {
+		return *loadedPolicy
+	}
+	return defaultPolicy()
+}
+
+// currentAgeLimits snapshots the age flags symbolic policy ages resolve to.
+func currentAgeLimits() ageLimits {
+	return ageLimits{Normal: flagMaxAgeNormal, Long: flagMaxAgeLong, Snapshot: flagMaxAgeSnapshot}
+}
+
+// cloudFromContext returns the cloud being swept, or "" when unset. nil-safe
+// because classification tests call the delete loops with a nil ctx.
+func cloudFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	cloud, _ := ctx.Value(core.CloudKey).(string)
+	return cloud
+}
+
+// cancelled reports whether the run was asked to stop (SIGTERM in daemon
+// mode). the sweep loops check it between resources, never mid-action.
+// nil-safe for the same reason as cloudFromContext.
+func cancelled(ctx context.Context) bool {
+	return ctx != nil && ctx.Err() != nil
+}
+
+// actionContext detaches a single delete / stop / start / mark call from
+// run cancellation: a call already in flight when SIGTERM arrives finishes
+// instead of leaving the resource half-deleted, and the loop stops after it.
+func actionContext(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// sweepCloud lists and acts on every resource kind of one cloud account.
+func sweepCloud(ctx context.Context, cloud string, executor core.ExecutorInterface) {
+	p := passOf(ctx)
+	skippedRegions, err := executor.SkippedRegionsGet(ctx)
+	if err != nil {
+		if !errors.Is(err, core.ErrUnsupported) {
+			_, _ = fmt.Fprintf(p.out, "Cannot get regions due to %s\n", err.Error())
+			p.report.listFailed(cloud, p.account, kindRegion, err)
+		}
+	} else if len(skippedRegions) > 0 {
+		reportSkippedRegions(ctx, cloud, skippedRegions)
+	}
+
+	if cancelled(ctx) {
+		return
+	}
+	servers, err := executor.ServersGet(ctx, nil, nil)
+	err = partialListing(ctx, cloud, kindServer, err)
+	if err != nil {
+		// match the LB/SSH/Volume callers: a provider that does not
+		// implement ServersGet returns ErrUnsupported — no error line, only
+		// an "unsupported" summary row.
+		if errors.Is(err, core.ErrUnsupported) {
+			p.report.listUnsupported(cloud, p.account, kindServer)
+		} else {
+			_, _ = fmt.Fprintf(p.out, "[%s] Cannot get servers due to %s\n", cloud, err.Error())
+			p.report.listFailed(cloud, p.account, kindServer, err)
+		}
+	} else {
+		p.report.listed(cloud, p.account, kindServer)
+		fprettyPrint(p.out, fmt.Sprintf("[%d SERVERS]\n", len(servers)), p.run.Mock)
+		sort.Sort(core.ServerSorter(servers))
+		switch flagAction {
+		case actionDelete:
+			deleteServers(ctx, cloud, servers)
+		case actionStop:
+			stopServers(ctx, cloud, servers)
+		case actionStart:
+			startServers(ctx, servers)
+		}
+	}
+
+	if cancelled(ctx) {
+		return
+	}
+	if flagAction == actionDelete {
+		loadBalancers, err := executor.LoadBalancersGet(ctx, p.run.Mock)
+		err = partialListing(ctx, cloud, kindLoadBalancer, err)
+		if err != nil {
+			if errors.Is(err, core.ErrUnsupported) {
+				p.report.listUnsupported(cloud, p.account, kindLoadBalancer)
+			} else {
+				_, _ = fmt.Fprintf(p.out, "Cannot get load balancers due to %s\n", err.Error())
+				p.report.listFailed(cloud, p.account, kindLoadBalancer, err)
+			}
+		} else {
+			p.report.listed(cloud, p.account, kindLoadBalancer)
+			fprettyPrint(p.out, fmt.Sprintf("[%d LOAD BALANCERS]\n", len(loadBalancers)), p.run.Mock)
+			sort.Sort(core.LoadBalancerSorter(loadBalancers))
+			deleteLoadBalancers(ctx, loadBalancers)
+		}
+	}
+
+	if cancelled(ctx) {
+		return
+	}
+	if flagAction == actionDelete {
+		sshKeys, err := executor.SshKeysGet(ctx)
+		err = partialListing(ctx, cloud, kindSshKey, err)
+		if err != nil {
+			if errors.Is(err, core.ErrUnsupported) {
+				p.report.listUnsupported(cloud, p.account, kindSshKey)
+			} else {
+				_, _ = fmt.Fprintf(p.out, "Cannot get SSH keys due to %s\n", err.Error())
+				p.report.listFailed(cloud, p.account, kindSshKey, err)
+			}
+		} else {
+			p.report.listed(cloud, p.account, kindSshKey)
+			fprettyPrint(p.out, fmt.Sprintf("[%d SSH KEYS]\n", len(sshKeys)), p.run.Mock)
+			sort.Sort(core.SshKeySorter(sshKeys))
+			deleteSshKeys(ctx, sshKeys)
+		}
+	}
+
+	if cancelled(ctx) {
+		return
+	}
+	if flagAction == actionDelete {
+		volumes, err := executor.VolumesGet(ctx)
+		err = partialListing(ctx, cloud, kindVolume, err)
+		if err != nil {
+			if errors.Is(err, core.ErrUnsupported) {
+				p.report.listUnsupported(cloud, p.account, kindVolume)
+			} else {
+				_, _ = fmt.Fprintf(p.out, "Cannot get volumes due to %s\n", err.Error())
+				p.report.listFailed(cloud, p.account, kindVolume, err)
+			}
+		} else {
+			p.report.listed(cloud, p.account, kindVolume)
+			fprettyPrint(p.out, fmt.Sprintf("[%d VOLUMES]\n", len(volumes)), p.run.Mock)
+			sort.Sort(core.VolumeSorter(volumes))
+			deleteVolumes(ctx, volumes)
+		}
+	}
+
+	if cancelled(ctx) {
+		return
+	}
+	if flagAction == actionDelete {
+		snapshots, err := executor.SnapshotsGet(ctx)
+		err = partialListing(ctx, cloud, kindSnapshot, err)
+		if err != nil {
+			if errors.Is(err, core.ErrUnsupported) {
+				p.report.listUnsupported(cloud, p.account, kindSnapshot)
+			} else {
+				_, _ = fmt.Fprintf(p.out, "Cannot get snapshots due to %s\n", err.Error())
+				p.report.listFailed(cloud, p.account, kindSnapshot, err)
+			}
+		} else {
+			p.report.listed(cloud, p.account, kindSnapshot)
+			fprettyPrint(p.out, fmt.Sprintf("[%d SNAPSHOTS]\n", len(snapshots)), p.run.Mock)
+			sort.Sort(core.SnapshotSorter(snapshots))
+			deleteSnapshots(ctx, snapshots)
+		}
+	}
+
+	if cancelled(ctx) {
+		return
+	}
+	if flagAction == actionDelete {
+		ipAddresses, err := executor.IPAddressesGet(ctx)
+		err = partialListing(ctx, cloud, kindIPAddress, err)
+		if err != nil {
+			if errors.Is(err, core.ErrUnsupported) {
+				p.report.listUnsupported(cloud, p.account, kindIPAddress)
+			} else {
+				_, _ = fmt.Fprintf(p.out, "Cannot get IP addresses due to %s\n", err.Error())
+				p.report.listFailed(cloud, p.account, kindIPAddress, err)
+			}
+		} else {
+			p.report.listed(cloud, p.account, kindIPAddress)
+			fprettyPrint(p.out, fmt.Sprintf("[%d IP ADDRESSES]\n", len(ipAddresses)), p.run.Mock)
+			sort.Sort(core.IPAddressSorter(ipAddresses))
+			releaseIPAddresses(ctx, ipAddresses)
+		}
+	}
+
+	if cancelled(ctx) {
+		return
+	}
+	if flagAction == actionDelete {
+		targetGroups, err := executor.TargetGroupsGet(ctx)
+		err = partialListing(ctx, cloud, kindTargetGroup, err)
+		if err != nil {
+			if errors.Is(err, core.ErrUnsupported) {
+				p.report.listUnsupported(cloud, p.account, kindTargetGroup)
+			} else {
+				_, _ = fmt.Fprintf(p.out, "Cannot get target groups due to %s\n", err.Error())
+				p.report.listFailed(cloud, p.account, kindTargetGroup, err)
+			}
+		} else {
+			p.report.listed(cloud, p.account, kindTargetGroup)
+			fprettyPrint(p.out, fmt.Sprintf("[%d TARGET GROUPS]\n", len(targetGroups)), p.run.Mock)
+			sort.Sort(core.TargetGroupSorter(targetGroups))
+			deleteTargetGroups(ctx, targetGroups)
+		}
+	}
+}
+
+// partialListing handles a listing that failed in some regions only: the
+// failed regions are printed and recorded on the kind's summary row, so the
+// run exits exitListingFailed, and nil is returned so the caller still
+// sweeps what the other regions returned. any other err is returned as is.
+func partialListing(ctx context.Context, cloud, kind string, err error) error {
+	var partial *core.PartialError
+	if !errors.As(err, &partial) {
+		return err
+	}
+	p := passOf(ctx)
+	_, _ = fmt.Fprintf(p.out, "[%s] Cannot list %s in %s due to %s\n", cloud, kind, strings.Join(partial.Regions, ", "), partial.Err.Error())
+	p.report.listPartial(cloud, p.account, kind, partial.Regions)
+	return nil
+}
+
+// printBanner prints the run header of p: the action and the limits in force.
+func printBanner(p pass) {
+	fprettyPrint(p.out, fmt.Sprintf("[%s ACTION]\n", strings.ToUpper(flagAction)), p.run.Mock)
+	if flagAction == actionStart {
+		fprettyPrint(p.out, fmt.Sprintf("START TAG: %s\n", flagStartTag), p.run.Mock)
+		return
+	}
+	printAllowances(p)
+	if loadedPolicy != nil {
+		fprettyPrint(p.out, fmt.Sprintf("POLICY: %s (%d rules)\n", flagPolicy, len(loadedPolicy.Rules)), p.run.Mock)
+	}
+	if flagQuarantine {
+		fprettyPrint(p.out, fmt.Sprintf("QUARANTINE: %.3f days grace\n", flagQuarantineGrace), p.run.Mock)
+	} else if markSigningKey != nil {
+		fprettyPrint(p.out, fmt.Sprintf("ORPHAN TARGET GROUPS: %.3f days grace\n", flagQuarantineGrace), p.run.Mock)
+	}
+}
+
+// sweep runs flagAction over every cloud of the run ctx carries into its
+// reporter, prints the summary and returns the run's exit code. a cancelled
+// ctx stops the run between resources.
+func sweep(ctx context.Context) int {
+	p := passOf(ctx)
+	printBanner(p)
+	userClouds := strings.Split(p.run.Clouds, ",")
+	for _, userCloud := range userClouds {
+		if cancelled(ctx) {
+			break
+		}
+		//Output the cloud
+		_, _ = fmt.Fprintln(p.out)
+		fprettyPrint(p.out, fmt.Sprintf("[%s]\n", strings.ToUpper(userCloud)), p.run.Mock)
+
+		cloud, accountName := splitCloudToken(userCloud)
+		if _, ok := clouds[cloud]; !ok {
+			// in live mode, refuse to silently proceed past a typo'd cloud
+			// token (e.g. `--clouds=aws,awz`); a no-op on `awz` with deletes
+			// against `aws` is exactly the failure mode --yes guards against.
+			if !p.run.Mock {
+				fmt.Fprintf(os.Stderr, "Unknown cloud %q in --clouds=%q; refusing to continue in live mode.\n", userCloud, p.run.Clouds)
+				os.Exit(2)
+			}
+			_, _ = fmt.Fprintf(p.out, "Unsupported cloud %q (skipping)\n", userCloud)
+			continue
+		}
+
+		executor := clouds[cloud]
+		cloudCtx := context.WithValue(ctx, core.ExecutorKey, executor)
+		cloudCtx = context.WithValue(cloudCtx, core.CloudKey, cloud)
+		if accountName == "" {
+			sweepAccounts(cloudCtx, cloud, "", executor)
+			continue
+		}
+		// named accounts were checked against the file before any pass ran
+		named, _ := credentials.account(cloud, accountName)
+		namedPass := p
+		namedPass.limits = named.ageLimits(p.limits)
+		if named.MaxAgeRegular != nil || named.MaxAgeLong != nil || named.MaxAgeSnapshot != nil {
+			printAllowances(namedPass)
+		}
+		sweepAccounts(withPass(named.withCredentials(cloudCtx), namedPass), cloud, accountName, executor)
+	}
+
+	if cancelled(ctx) {
+		_, _ = fmt.Fprintln(p.out)
+		fprettyPrint(p.out, "[CANCELLED] stopped before the remaining resources\n", p.run.Mock)
+	}
+	p.report.summary.write(p.out, actedColumn(flagAction))
+	code := p.report.summary.exitCode()
+	notifyRun(ctx, code)
+	flushState()
+	return code
+}
+
+// printAllowances prints the age limits p is held to, for the run banner and
+// for credentials file accounts that override them.
+func printAllowances(p pass) {
+	limits := p.limits
+	fprettyPrint(p.out, fmt.Sprintf("NORMAL ALLOWANCE: %.3f days (%.0f hours)\n", limits.Normal, limits.Normal*24.0), p.run.Mock)
+	fprettyPrint(p.out, fmt.Sprintf("LONG ALLOWANCE: %.3f days (%.0f hours)\n", limits.Long, limits.Long*24.0), p.run.Mock)
+	fprettyPrint(p.out, fmt.Sprintf("SNAPSHOT ALLOWANCE: %.3f days (%.0f hours)\n", limits.Snapshot, limits.Snapshot*24.0), p.run.Mock)
+}
+
+// reportSkippedRegions lists the regions the executor left out of the run.
+// they are reported once per cloud rather than as an error from every
+// service scanned in them.
+func reportSkippedRegions(ctx context.Context, cloud string, regions []core.SkippedRegion) {
+	p := passOf(ctx)
+	fprettyPrint(p.out, fmt.Sprintf("[%d SKIPPED REGIONS]\n", len(regions)), p.run.Mock)
+	for _, region := range regions {
+		fprettyPrint(p.out, fmt.Sprintf("[%s] ▶ ", region.Region), p.run.Mock)
+		d := decision{Action: policyKeep, Reason: region.Reason, State: "SKIP"}
+		printKept(p.out, d)
+		reportSkipped(ctx, regionRecord(cloud, region, d))
+	}
+}
+
+// printKept prints the trailing line for a decision that leaves the resource
+// alone. report rules are called out separately so they are greppable.
+func printKept(w io.Writer, d decision) {
+	if d.Action == policyReport {
+		_, _ = fmt.Fprintf(w, "reported (%s)\n", d.Reason)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "skipped (%s)\n", d.Reason)
 }
 
 func handler(w http.ResponseWriter, r *http.Request) {
@@ -74,9 +483,18 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	_, _ = fmt.Fprint(w, "Its a TRAP!")
 }
 
+// newServeMux routes the webserver action: the placeholder handler on / and
+// the Prometheus endpoint on /metrics.
+func newServeMux(m *metrics) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handler)
+	mux.Handle("/metrics", m.handler())
+	return mux
+}
+
 func main() {
 	//action
-	flag.StringVar(&flagAction, "action", "", "Action to perform: delete|stop|start")
+	flag.StringVar(&flagAction, "action", "", "Action to perform: delete|stop|start|webserver|daemon")
 	//credentials
 	flag.StringVar(&flagDOPat, "do-pat", os.Getenv("JANITOR_DO_PAT"), "DigitalOcean Personal Access Token")
 	flag.StringVar(&flagAWSAccessKeyID, "aws-access-key-id", os.Getenv("JANITOR_AWS_ACCESS_KEY_ID"), "AWS Access Key ID")
@@ -91,9 +509,67 @@ func main() {
 	// with no flag visible in `ps` / audit logs (panel finding C2).
 	flag.BoolVar(&flagYes, "yes", false, "Required CLI flag for non-mock deletions; --mock=false without --yes is rejected.")
 	flag.StringVar(&flagClouds, "clouds", "", "Clouds to work on (comma separated for multiple)")
+	flag.StringVar(&flagStartTag, "start-tag", os.Getenv("JANITOR_START_TAG"), "Tag (key=value or bare tag) selecting the stopped servers --action=start powers back on")
+	flag.BoolVar(&flagQuarantine, "quarantine", strings.ToLower(os.Getenv("JANITOR_QUARANTINE")) == "true", "Mark eligible servers, load balancers and volumes for deletion and only delete them on a later run after --quarantine-grace")
+	flag.StringVar(&flagMarkSigningKeyFile, "mark-signing-key-file", os.Getenv("JANITOR_MARK_SIGNING_KEY_FILE"), "File holding the HMAC key used to sign and verify deletion marks. Required with --quarantine; also enables deleting orphan target groups, which are always marked first. Both need --state-file")
+	flag.StringVar(&flagOutput, "output", outputText, describeOutput)
+	flag.StringVar(&flagAWSRegions, "aws-regions", os.Getenv("JANITOR_AWS_REGIONS"), "Comma-separated AWS regions to scan instead of every enabled region; prefix a region with - to exclude it (e.g. -eu-west-3)")
+	flag.StringVar(&flagAWSProfile, "aws-profile", os.Getenv("JANITOR_AWS_PROFILE"), "AWS shared config profile for the base credentials (instead of the access key pair or the default chain)")
+	flag.StringVar(&flagAWSRoleARNs, "aws-role-arns", os.Getenv("JANITOR_AWS_ROLE_ARNS"), "Comma-separated IAM role ARNs to assume via STS; the AWS pass runs once per role and labels its output with the account ID")
+	flag.StringVar(&flagAWSExternalID, "aws-external-id", os.Getenv("JANITOR_AWS_EXTERNAL_ID"), "External ID passed when assuming --aws-role-arns")
+	flag.StringVar(&flagCredentialsFile, "credentials-file", os.Getenv("JANITOR_CREDENTIALS_FILE"), "Named accounts (YAML, or JSON when the name ends in .json) selected with --clouds=<cloud>:<name>, each with its own token and optional age overrides")
+	flag.StringVar(&flagSchedule, "schedule", os.Getenv("JANITOR_SCHEDULE"), "When --action=daemon sweeps: a cron expression (\"0 */2 * * *\", @hourly) or an interval (\"2h\")")
+	flag.StringVar(&flagAPIToken, "api-token", os.Getenv("JANITOR_API_TOKEN"), "Bearer token --action=daemon requires for live (mock=false) runs started with POST /runs; unset, only mock runs can be started over the API")
+	flag.StringVar(&flagSlackWebhook, "slack-webhook", os.Getenv("JANITOR_SLACK_WEBHOOK"), "Slack incoming-webhook URL(s), comma separated, each run's digest is posted to")
+	flag.StringVar(&flagMattermostWebhook, "mattermost-webhook", os.Getenv("JANITOR_MATTERMOST_WEBHOOK"), "Mattermost incoming-webhook URL(s), comma separated, each run's digest is posted to")
+	flag.StringVar(&flagTeamsWebhook, "teams-webhook", os.Getenv("JANITOR_TEAMS_WEBHOOK"), "Microsoft Teams (Workflows) webhook URL(s), comma separated, each run's digest is posted to")
+	flag.StringVar(&flagSMTPServer, "smtp-server", os.Getenv("JANITOR_SMTP_SERVER"), "SMTP relay (host:port) each resource owner is emailed a live run's deletions through; unset, no email is sent")
+	flag.StringVar(&flagSMTPUsername, "smtp-username", os.Getenv("JANITOR_SMTP_USERNAME"), "Username for --smtp-server (PLAIN auth); unset, mail is sent unauthenticated")
+	flag.StringVar(&flagSMTPPassword, "smtp-password", os.Getenv("JANITOR_SMTP_PASSWORD"), "Password for --smtp-username")
+	flag.StringVar(&flagSMTPFrom, "smtp-from", os.Getenv("JANITOR_SMTP_FROM"), "From address of owner emails")
+	flag.StringVar(&flagOwnerEmailDomain, "owner-email-domain", os.Getenv("JANITOR_OWNER_EMAIL_DOMAIN"), "Domain completing owner tag values that are not email addresses (owner=alice becomes alice@<domain>)")
+	flag.StringVar(&flagOwnerFallbackEmail, "owner-fallback-email", os.Getenv("JANITOR_OWNER_FALLBACK_EMAIL"), "Address emailed about resources with no owner tag, or one that is not an email address")
+	flag.BoolVar(&flagEmailMockRuns, "email-mock-runs", strings.ToLower(os.Getenv("JANITOR_EMAIL_MOCK_RUNS")) == "true", "Email owners about mock runs too; by default only live (--mock=false) runs are emailed")
+	flag.StringVar(&flagWarnedFile, "warned-file", os.Getenv("JANITOR_WARNED_FILE"), "File remembering which --warn-before warnings were sent, so each resource is announced once; unset, only for the life of the process")
+	flag.StringVar(&flagStateFile, "state-file", os.Getenv("JANITOR_STATE_FILE"), "State store remembering every resource's first and last sighting, decisions and deletion (a file, created when missing, or memory: for the life of the process); read by `janitor history <vendor-id>`")
+	flag.StringVar(&flagPolicy, "policy", os.Getenv("JANITOR_POLICY"), "Retention policy file (YAML, or JSON when the name ends in .json). Defaults to the built-in policy.")
 
+	listenAddress := ":1234"
+	if os.Getenv("JANITOR_LISTEN") != "" {
+		listenAddress = os.Getenv("JANITOR_LISTEN")
+	}
+	ownerTag := "owner,created-by"
+	if os.Getenv("JANITOR_OWNER_TAG") != "" {
+		ownerTag = os.Getenv("JANITOR_OWNER_TAG")
+	}
+	var warnBefore time.Duration
+	if os.Getenv("JANITOR_WARN_BEFORE") != "" {
+		warnBefore, _ = time.ParseDuration(os.Getenv("JANITOR_WARN_BEFORE"))
+	}
+	keepRuns := 20
+	if os.Getenv("JANITOR_KEEP_RUNS") != "" {
+		parsed, _ := strconv.ParseInt(os.Getenv("JANITOR_KEEP_RUNS"), 10, 0)
+		keepRuns = int(parsed)
+	}
+	awsRoleSessionName := "janitor"
+	if os.Getenv("JANITOR_AWS_ROLE_SESSION_NAME") != "" {
+		awsRoleSessionName = os.Getenv("JANITOR_AWS_ROLE_SESSION_NAME")
+	}
+	awsRegionConcurrency := 8
+	if os.Getenv("JANITOR_AWS_REGION_CONCURRENCY") != "" {
+		parsed, _ := strconv.ParseInt(os.Getenv("JANITOR_AWS_REGION_CONCURRENCY"), 10, 0)
+		awsRegionConcurrency = int(parsed)
+	}
 	var maxAgeNormal, maxAgeLong float64
 	var sshKeysKeepCount int
+	var sshKeysKeepDays float64
+	if os.Getenv("SSH_KEYS_KEEP_DAYS") != "" {
+		sshKeysKeepDays, _ = strconv.ParseFloat(os.Getenv("SSH_KEYS_KEEP_DAYS"), 64)
+	}
+	quarantineGraceDays := 1.0
+	if os.Getenv("JANITOR_QUARANTINE_GRACE") != "" {
+		quarantineGraceDays, _ = strconv.ParseFloat(os.Getenv("JANITOR_QUARANTINE_GRACE"), 64)
+	}
 	if os.Getenv("MAX_AGE_NORMAL") != "" {
 		maxAgeNormal, _ = strconv.ParseFloat(os.Getenv("MAX_AGE_NORMAL"), 64)
 	} else {
@@ -104,6 +580,10 @@ func main() {
 	} else {
 		maxAgeLong = 5.0
 	}
+	maxAgeSnapshot := defaultMaxAgeSnapshot
+	if os.Getenv("MAX_AGE_SNAPSHOT") != "" {
+		maxAgeSnapshot, _ = strconv.ParseFloat(os.Getenv("MAX_AGE_SNAPSHOT"), 64)
+	}
 	if os.Getenv("SSH_KEYS_KEEP_COUNT") != "" {
 		sshKeysKeepCountParsed, _ := strconv.ParseInt(os.Getenv("SSH_KEYS_KEEP_COUNT"), 10, 0)
 		sshKeysKeepCount = int(sshKeysKeepCountParsed)
@@ -116,20 +596,201 @@ func main() {
 
 	flag.Float64Var(&flagMaxAgeNormal, "max-age-regular", maxAgeNormal, "Normal allowed server age (days). Decimal allowed. Anything older will be deleted!")
 	flag.Float64Var(&flagMaxAgeLong, "max-age-long", maxAgeLong, "Long allowed server age (days). Decimal allowed. Anything older will be deleted!")
+	flag.Float64Var(&flagMaxAgeSnapshot, "max-age-snapshot", maxAgeSnapshot, "Allowed snapshot and image age (days). Decimal allowed. Anything older that no image references will be deleted!")
 	flag.IntVar(&flagSshKeysKeepCount, "ssh-keys-keep-count", sshKeysKeepCount, "Number of non-user defined SSH keys to keep.")
-	flag.Parse()
+	flag.Float64Var(&flagSshKeysKeepDays, "ssh-keys-keep-days", sshKeysKeepDays, "Also keep non-user defined SSH keys younger than this many days (0 = off). Ignored for keys without a creation time. Decimal allowed.")
+	flag.Float64Var(&flagQuarantineGrace, "quarantine-grace", quarantineGraceDays, "Days a deletion mark must age before the resource is deleted (quarantine and orphan target groups). Decimal allowed.")
+	flag.StringVar(&flagAWSRoleSessionName, "aws-role-session-name", awsRoleSessionName, "Session name used when assuming --aws-role-arns")
+	flag.IntVar(&flagAWSRegionConcurrency, "aws-region-concurrency", awsRegionConcurrency, "Maximum number of AWS regions scanned in parallel (1 = one region at a time).")
+	flag.StringVar(&flagListen, "listen", listenAddress, "Address the webserver and daemon actions listen on; serves Prometheus metrics at /metrics")
+	flag.IntVar(&flagKeepRuns, "keep-runs", keepRuns, "Number of run reports --action=daemon keeps in memory for /runs")
+	flag.DurationVar(&flagWarnBefore, "warn-before", warnBefore, "Warn, through the webhooks and owner emails, about servers, load balancers and volumes that become eligible for deletion within this long (e.g. 12h); 0 = off")
+	flag.StringVar(&flagOwnerTag, "owner-tag", ownerTag, "Comma-separated tag keys naming a resource's owner for --smtp-server emails, first match wins")
+	// `janitor [flags] history <vendor-id> [flags]` reads the state store
+	// instead of sweeping
+	args := os.Args[1:]
+	historyMode := len(args) > 0 && args[0] == "history"
+	if historyMode {
+		args = args[1:]
+	}
+	_ = flag.CommandLine.Parse(args)
+	if historyMode {
+		if flag.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "usage: janitor history <vendor-id>")
+			os.Exit(1)
+		}
+		vendorID := flag.Arg(0)
+		_ = flag.CommandLine.Parse(flag.Args()[1:])
+		if flag.NArg() > 0 {
+			fmt.Fprintf(os.Stderr, "Unexpected argument %q after the vendor ID\n", flag.Arg(0))
+			os.Exit(1)
+		}
+		os.Exit(historyCommand(os.Stdout, vendorID))
+	}
 
 	if flagAction == actionWebServer {
-		http.HandleFunc("/", handler)
-		res := http.ListenAndServe(":1234", nil)
+		res := http.ListenAndServe(flagListen, newServeMux(runMetrics))
 		fmt.Println(res)
 		os.Exit(0)
 	}
 
+	// the daemon's scheduled sweeps are delete runs: validated, announced and
+	// swept exactly like --action=delete, which the code below keys off.
+	daemonMode := flagAction == actionDaemon
+	var schedule cron.Schedule
+	if daemonMode {
+		flagAction = actionDelete
+		parsed, err := parseSchedule(flagSchedule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --schedule: %s\n", err.Error())
+			os.Exit(1)
+		}
+		schedule = parsed
+		if flagKeepRuns < 1 {
+			fmt.Fprintf(os.Stderr, "--keep-runs must be at least 1, got %d\n", flagKeepRuns)
+			os.Exit(1)
+		}
+	}
+
+	if !validOutputFormat(flagOutput) {
+		fmt.Fprintf(os.Stderr, "Unrecognised output format '%s'\n", flagOutput)
+		os.Exit(1)
+	}
+	if flagOutput != outputText {
+		// stdout carries only records; the human lines move to stderr so
+		// `janitor --output=json | jq` keeps working.
+		out = os.Stderr
+		report = &reporter{format: flagOutput, w: os.Stdout}
+	}
+
+	if flagCredentialsFile != "" {
+		c, err := loadCredentials(flagCredentialsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot load credentials file: %s\n", err.Error())
+			os.Exit(1)
+		}
+		credentials = c
+	}
+
+	for _, hooks := range []struct{ format, value string }{
+		{webhookSlack, flagSlackWebhook},
+		{webhookMattermost, flagMattermostWebhook},
+		{webhookTeams, flagTeamsWebhook},
+	} {
+		parsed, err := parseWebhooks(hooks.format, hooks.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid webhook: %s\n", err.Error())
+			os.Exit(1)
+		}
+		webhooks = append(webhooks, parsed...)
+	}
+
+	if flagSMTPServer != "" {
+		mailer := &ownerMailer{
+			Server:   flagSMTPServer,
+			Username: flagSMTPUsername,
+			Password: flagSMTPPassword,
+			From:     flagSMTPFrom,
+			Fallback: flagOwnerFallbackEmail,
+			TagKeys:  parseTagKeys(flagOwnerTag),
+			Domain:   flagOwnerEmailDomain,
+			now:      time.Now,
+		}
+		if err := mailer.validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid owner email settings: %s\n", err.Error())
+			os.Exit(1)
+		}
+		ownerMail = mailer
+	}
+
+	if flagStateFile != "" {
+		store, err := openStateStore(flagStateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot open state store: %s\n", err.Error())
+			os.Exit(1)
+		}
+		state = store
+		warnings = storeWarnings{store: store}
+	}
+
+	if flagWarnBefore > 0 {
+		if len(webhooks) == 0 && ownerMail == nil {
+			fmt.Fprintln(os.Stderr, "--warn-before needs a webhook or --smtp-server to send the warnings to")
+			os.Exit(1)
+		}
+		// owners are not emailed about mock runs, so email alone would
+		// never carry a warning
+		if len(webhooks) == 0 && flagMock && !flagEmailMockRuns {
+			fmt.Fprintln(os.Stderr, "--warn-before on a mock run with only --smtp-server sends nothing; add a webhook, pass --mock=false, or set --email-mock-runs")
+			os.Exit(1)
+		}
+		if flagWarnedFile != "" && state != nil {
+			fmt.Fprintln(os.Stderr, "--warned-file cannot be combined with --state-file, which remembers warnings itself")
+			os.Exit(1)
+		}
+		if flagWarnedFile != "" {
+			l, err := loadWarningLog(flagWarnedFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot load warned file: %s\n", err.Error())
+				os.Exit(1)
+			}
+			warnings = l
+		}
+	}
+
+	if flagPolicy != "" {
+		p, err := loadPolicy(flagPolicy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot load policy: %s\n", err.Error())
+			os.Exit(1)
+		}
+		loadedPolicy = p
+	}
+
+	if flagAWSRegionConcurrency < 1 {
+		fmt.Fprintf(os.Stderr, "--aws-region-concurrency must be at least 1, got %d\n", flagAWSRegionConcurrency)
+		os.Exit(1)
+	}
+
+	if flagAWSProfile != "" && flagAWSAccessKeyID != "" {
+		fmt.Fprintln(os.Stderr, "--aws-profile cannot be combined with --aws-access-key-id")
+		os.Exit(1)
+	}
+	awsRoleARNs, err := parseRoleARNs(flagAWSRoleARNs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --aws-role-arns: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if flagQuarantine && flagAction != actionDelete {
+		fmt.Fprintln(os.Stderr, "--quarantine only applies to --action=delete")
+		os.Exit(1)
+	}
+	// the key also enables deleting orphan target groups, which always
+	// marks first, so it is loaded whenever a key file is given.
+	if flagQuarantine || flagMarkSigningKeyFile != "" {
+		// only the state store remembers that an owner took a mark off;
+		// without it, or with one gone at exit, the next run marks again.
+		if flagStateFile == "" || flagStateFile == stateMemory {
+			fmt.Fprintln(os.Stderr, "--quarantine and --mark-signing-key-file need a --state-file (not memory:) to remember removed deletion marks")
+			os.Exit(1)
+		}
+		key, err := readMarkSigningKey(flagMarkSigningKeyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot load mark signing key: %s\n", err.Error())
+			os.Exit(1)
+		}
+		markSigningKey = key
+	}
+
 	if flagClouds == "" {
 		fmt.Println("No cloud provider is specified. Use the --clouds option")
 		os.Exit(1)
 	}
+	if err := checkCloudAccounts(strings.Split(flagClouds, ","), credentials); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --clouds: %s\n", err.Error())
+		os.Exit(1)
+	}
 
 	clouds = make(map[string]core.ExecutorInterface)
 	//Just add new clouds here
@@ -146,107 +807,71 @@ func main() {
 	// AWS executor now reads typed ctx keys only (Phase 5 migration complete).
 	ctx = context.WithValue(ctx, core.AWSAccessKeyIDKey, flagAWSAccessKeyID)
 	ctx = context.WithValue(ctx, core.AWSSecretAccessKeyKey, flagAWSSecretAccessKey)
+	ctx = context.WithValue(ctx, core.AWSRegionConcurrencyKey, flagAWSRegionConcurrency)
+	ctx = context.WithValue(ctx, core.AWSRegionsKey, flagAWSRegions)
+	ctx = context.WithValue(ctx, core.AWSProfileKey, flagAWSProfile)
+	ctx = context.WithValue(ctx, core.AWSRoleARNsKey, awsRoleARNs)
+	ctx = context.WithValue(ctx, core.AWSExternalIDKey, flagAWSExternalID)
+	ctx = context.WithValue(ctx, core.AWSRoleSessionNameKey, flagAWSRoleSessionName)
 	// route warnings to stderr so pipes like `janitor ... | tee report` keep
 	// data and diagnostics separate; normal output stays on the `out` sink.
 	ctx = context.WithValue(ctx, core.WarnWriterKey, io.Writer(os.Stderr))
 	ctx = context.WithValue(ctx, core.OutWriterKey, out)
 
-	if flagAction == actionDelete {
+	switch flagAction {
+	case actionDelete, actionStop, actionStart:
 		// guard: --mock=false is destructive; require --yes on the CLI.
 		if msg := requireYesGate(flagMock, flagYes); msg != "" {
 			fmt.Fprintln(os.Stderr, msg)
 			os.Exit(2)
 		}
+		// an empty selector would power on every stopped server in the
+		// account, including ones people stopped on purpose.
+		if flagAction == actionStart && flagStartTag == "" {
+			fmt.Println("--action=start requires --start-tag to select which servers to start")
+			os.Exit(1)
+		}
 		// loud banner: announce live mode + the cloud(s) being targeted so
 		// operators see what's about to happen before any API call fires.
 		if !flagMock {
-			fmt.Fprintf(os.Stderr, "*** LIVE DELETION MODE — clouds=%s ***\n", flagClouds)
+			fmt.Fprintf(os.Stderr, "*** LIVE %s MODE — clouds=%s ***\n", liveModeName(flagAction), flagClouds)
 		}
-		prettyPrint(fmt.Sprintf("[%s ACTION]\n", strings.ToUpper(flagAction)), flagMock)
-		prettyPrint(fmt.Sprintf("NORMAL ALLOWANCE: %.3f days (%.0f hours)\n", flagMaxAgeNormal, flagMaxAgeNormal*24.0), flagMock)
-		prettyPrint(fmt.Sprintf("LONG ALLOWANCE: %.3f days (%.0f hours)\n", flagMaxAgeLong, flagMaxAgeLong*24.0), flagMock)
 
-	} else {
+	default:
 		fmt.Printf("Unrecognised action '%s'\n", flagAction)
 		os.Exit(1)
 	}
 
-	userClouds := strings.Split(flagClouds, ",")
-	for _, userCloud := range userClouds {
-		//Output the cloud
-		fmt.Println()
-		prettyPrint(fmt.Sprintf("[%s]\n", strings.ToUpper(userCloud)), flagMock)
-
-		if _, ok := clouds[userCloud]; !ok {
-			// in live mode, refuse to silently proceed past a typo'd cloud
-			// token (e.g. `--clouds=aws,awz`); a no-op on `awz` with deletes
-			// against `aws` is exactly the failure mode --yes guards against.
-			if !flagMock {
-				fmt.Fprintf(os.Stderr, "Unknown cloud %q in --clouds=%q; refusing to continue in live mode.\n", userCloud, flagClouds)
+	if daemonMode {
+		// sweep refuses an unknown cloud in live mode only when it reaches
+		// it; a daemon refuses at startup rather than on its first run.
+		for _, token := range strings.Split(flagClouds, ",") {
+			if cloud, _ := splitCloudToken(token); clouds[cloud] == nil && !flagMock {
+				fmt.Fprintf(os.Stderr, "Unknown cloud %q in --clouds=%q; refusing to continue in live mode.\n", token, flagClouds)
 				os.Exit(2)
 			}
-			fmt.Printf("Unsupported cloud %q (skipping)\n", userCloud)
-			continue
-		}
-
-		executor := clouds[userCloud]
-		ctx = context.WithValue(ctx, core.ExecutorKey, executor)
-
-		servers, err := executor.ServersGet(ctx, nil, nil)
-		if err != nil {
-			// match the LB/SSH/Volume callers: a provider that does not
-			// implement ServersGet returns ErrUnsupported — treat as silent
-			// skip rather than printing an error.
-			if !errors.Is(err, core.ErrUnsupported) {
-				fmt.Printf("[%s] Cannot get servers due to %s\n", userCloud, err.Error())
-			}
-		} else {
-			prettyPrint(fmt.Sprintf("[%d SERVERS]\n", len(servers)), flagMock)
-			sort.Sort(core.ServerSorter(servers))
-			if flagAction == actionDelete {
-				deleteServers(ctx, userCloud, servers)
-			}
-		}
-
-		if flagAction == actionDelete {
-			loadBalancers, err := executor.LoadBalancersGet(ctx, flagMock)
-			if err != nil {
-				if !errors.Is(err, core.ErrUnsupported) {
-					fmt.Printf("Cannot get load balancers due to %s\n", err.Error())
-				}
-			} else {
-				prettyPrint(fmt.Sprintf("[%d LOAD BALANCERS]\n", len(loadBalancers)), flagMock)
-				sort.Sort(core.LoadBalancerSorter(loadBalancers))
-				deleteLoadBalancers(ctx, loadBalancers)
-			}
 		}
+		os.Exit(runDaemon(ctx, schedule))
+	}
 
-		if flagAction == actionDelete {
-			sshKeys, err := executor.SshKeysGet(ctx)
-			if err != nil {
-				if !errors.Is(err, core.ErrUnsupported) {
-					fmt.Printf("Cannot get SSH keys due to %s\n", err.Error())
-				}
-			} else {
-				prettyPrint(fmt.Sprintf("[%d SSH KEYS]\n", len(sshKeys)), flagMock)
-				sort.Sort(core.SshKeySorter(sshKeys))
-				deleteSshKeys(ctx, sshKeys)
-			}
-		}
+	code := sweep(ctx)
+	if err := report.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write report: %s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
 
-		if flagAction == actionDelete {
-			volumes, err := executor.VolumesGet(ctx)
-			if err != nil {
-				if !errors.Is(err, core.ErrUnsupported) {
-					fmt.Printf("Cannot get volumes due to %s\n", err.Error())
-				}
-			} else {
-				prettyPrint(fmt.Sprintf("[%d VOLUMES]\n", len(volumes)), flagMock)
-				sort.Sort(core.VolumeSorter(volumes))
-				deleteVolumes(ctx, volumes)
-			}
-		}
+// actedColumn names the summary column counting resources the action was
+// carried out on.
+func actedColumn(action string) string {
+	switch action {
+	case actionStop:
+		return "STOPPED"
+	case actionStart:
+		return "STARTED"
 	}
+	return "DELETED"
 }
 
 // nameTokens splits a resource name on the common identifier delimiters
@@ -349,199 +974,558 @@ func hasSampleTag(tags []string) bool {
 }
 
 func deleteServers(ctx context.Context, cloud string, servers []core.Server) {
-	_ = cloud // retained for callers; sample-tag check now applies to all clouds.
+	pol := activePolicy()
+	p := passOf(ctx)
 	for _, server := range servers {
-		if hasSampleTag(server.Tags) {
-			// skip any server with a C66-STACK tag containing "sample" —
-			// previously only checked for vultr, leaving AWS/DO/Hetzner sample
-			// stacks vulnerable to deletion.
-			printServer(server, "SMPL")
-			_, _ = fmt.Fprintf(out, "skipped (sample tag)\n")
-		} else if isPermanent(server.Name, server.Tags) {
-			printServer(server, "PERM")
-			_, _ = fmt.Fprintf(out, "skipped (permanent)\n")
-		} else if server.Age <= 0 {
-			// B10: Age=0 means Created was missing/malformed. do not let a
-			// hasLongName/normal predicate decide deletion based on a
-			// fabricated age. skip and surface the reason.
-			printServer(server, "WARN")
-			_, _ = fmt.Fprintf(out, "skipped (unknown age — malformed Created)\n")
-		} else if hasLongName(server.Name, server.Tags) {
-			printServer(server, "LONG")
-			if server.Age > flagMaxAgeLong {
-				if flagMock {
-					_, _ = fmt.Fprintf(out, "Mock deleted!\n")
-				} else {
-					deleteServer(ctx, server)
-				}
+		if cancelled(ctx) {
+			return
+		}
+		subject := policySubject{
+			Kind:   kindServer,
+			Cloud:  cloud,
+			Region: server.Region,
+			Name:   server.Name,
+			Tags:   server.Tags,
+			State:  server.State,
+			Age:    server.Age,
+		}
+		d := pol.evaluate(subject, p.limits)
+		printServer(p, server, d.State)
+		rec := serverRecord(cloud, server, d)
+		switch d.Action {
+		case policyDelete:
+			if flagQuarantine && !quarantineGate(ctx, rec, server.Tags, func(value string) error {
+				return ctx.Value(core.ExecutorKey).(core.ExecutorInterface).ServerMark(actionContext(ctx), server, value)
+			}) {
+				// marked or still in its grace window; the gate reported it
+			} else if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock deleted!\n")
+				finish(ctx, rec, resultMock, nil)
 			} else {
-				_, _ = fmt.Fprintf(out, "skipped (age)\n")
+				finish(ctx, rec, resultDeleted, deleteServer(ctx, server))
 			}
+		case policyStop:
+			if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock stopped!\n")
+				finish(ctx, rec, resultMock, nil)
+			} else {
+				finish(ctx, rec, resultStopped, stopServer(ctx, server))
+			}
+		default:
+			printKept(p.out, d)
+			checkExpiry(p, pol, subject, &rec)
+			reportSkipped(ctx, rec)
+		}
+	}
+}
+
+// stopServers powers off every server the policy would delete (or stop)
+// instead of destroying it — --action=stop keeps the box for the next day.
+func stopServers(ctx context.Context, cloud string, servers []core.Server) {
+	pol := activePolicy()
+	p := passOf(ctx)
+	for _, server := range servers {
+		if cancelled(ctx) {
+			return
+		}
+		d := pol.evaluate(policySubject{
+			Kind:   kindServer,
+			Cloud:  cloud,
+			Region: server.Region,
+			Name:   server.Name,
+			Tags:   server.Tags,
+			State:  server.State,
+			Age:    server.Age,
+		}, p.limits)
+		printServer(p, server, d.State)
+		rec := serverRecord(cloud, server, d)
+		if d.Action != policyDelete && d.Action != policyStop {
+			printKept(p.out, d)
+			reportSkipped(ctx, rec)
+		} else if server.State == "STOPPED" {
+			_, _ = fmt.Fprintf(p.out, "skipped (already stopped)\n")
+			rec.Decision, rec.Reason = policyKeep, "already stopped"
+			reportSkipped(ctx, rec)
+		} else if p.run.Mock {
+			_, _ = fmt.Fprintf(p.out, "Mock stopped!\n")
+			rec.Decision = policyStop
+			finish(ctx, rec, resultMock, nil)
+		} else {
+			rec.Decision = policyStop
+			finish(ctx, rec, resultStopped, stopServer(ctx, server))
+		}
+	}
+}
+
+// startServers powers on stopped servers carrying --start-tag. the age
+// policy does not apply: the tag is the operator's explicit selection.
+func startServers(ctx context.Context, servers []core.Server) {
+	p := passOf(ctx)
+	cloud := cloudFromContext(ctx)
+	for _, server := range servers {
+		if cancelled(ctx) {
+			return
+		}
+		if !hasTag(server.Tags, flagStartTag) {
+			printServer(p, server, "SKIP")
+			_, _ = fmt.Fprintf(p.out, "skipped (no start tag)\n")
+			reportSkipped(ctx, serverRecord(cloud, server, decision{Action: policyKeep, Reason: "no start tag", State: "SKIP"}))
+		} else if server.State != "STOPPED" {
+			// Vultr restarts an already-running instance on start, so never
+			// send start to anything not known to be powered off.
+			printServer(p, server, "  ON")
+			_, _ = fmt.Fprintf(p.out, "skipped (not stopped)\n")
+			reportSkipped(ctx, serverRecord(cloud, server, decision{Action: policyKeep, Reason: "not stopped", State: "  ON"}))
 		} else {
-			printServer(server, "NORM")
-			if server.Age > flagMaxAgeNormal {
-				if flagMock {
-					_, _ = fmt.Fprintf(out, "Mock deleted!\n")
-				} else {
-					deleteServer(ctx, server)
-				}
+			printServer(p, server, " OFF")
+			rec := serverRecord(cloud, server, decision{Action: actionStart, Reason: "start tag", State: " OFF"})
+			if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock started!\n")
+				finish(ctx, rec, resultMock, nil)
 			} else {
-				_, _ = fmt.Fprintf(out, "skipped (age)\n")
+				finish(ctx, rec, resultStarted, startServer(ctx, server))
 			}
 		}
 	}
 }
 
-func deleteServer(ctx context.Context, server core.Server) {
+func deleteServer(ctx context.Context, server core.Server) error {
+	p := passOf(ctx)
 	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
-	err := executor.ServerDelete(ctx, server)
+	err := executor.ServerDelete(actionContext(ctx), server)
 	if err != nil {
-		_, _ = fmt.Fprintf(out, "ERROR: %s\n", err.Error())
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
 	} else {
-		_, _ = fmt.Fprintf(out, "Deleted!\n")
+		_, _ = fmt.Fprintf(p.out, "Deleted!\n")
 	}
+	return err
 }
 
 func deleteLoadBalancers(ctx context.Context, loadBalancers []core.LoadBalancer) {
-	// minimum age threshold: 1 hour (in days)
-	minAge := 1.0 / 24.0
-
+	pol := activePolicy()
+	p := passOf(ctx)
+	cloud := cloudFromContext(ctx)
 	for _, loadBalancer := range loadBalancers {
-		if isPermanent(loadBalancer.Name, loadBalancer.Tags) {
-			printLoadBalancer(loadBalancer, "PERM")
-			_, _ = fmt.Fprintf(out, "skipped (permanent)\n")
-		} else if loadBalancer.Age <= 0 {
-			// defense-in-depth: zero/negative age means Created was missing or
-			// malformed upstream; never let predicates drive deletion off it.
-			printLoadBalancer(loadBalancer, "WARN")
-			_, _ = fmt.Fprintf(out, "skipped (unknown age — malformed Created)\n")
-		} else if loadBalancer.InstanceCount < 0 {
-			// instance count unknown (health check failed) — skip to be safe
-			printLoadBalancer(loadBalancer, " N/A")
-			_, _ = fmt.Fprintf(out, "skipped (instance count unknown)\n")
-		} else if loadBalancer.InstanceCount > 0 {
-			// skip LBs that still have servers attached
-			printLoadBalancer(loadBalancer, "LIVE")
-			_, _ = fmt.Fprintf(out, "skipped (has %d instances)\n", loadBalancer.InstanceCount)
-		} else if loadBalancer.Age < minAge {
-			// skip recently created LBs that may not have instances yet
-			printLoadBalancer(loadBalancer, " NEW")
-			_, _ = fmt.Fprintf(out, "skipped (less than 1 hour old)\n")
-		} else {
-			// no instances and older than 1 hour — delete it
-			printLoadBalancer(loadBalancer, "DEAD")
-			if flagMock {
-				_, _ = fmt.Fprintf(out, "Mock deleted!\n")
+		if cancelled(ctx) {
+			return
+		}
+		subject := policySubject{
+			Kind:          kindLoadBalancer,
+			Cloud:         cloud,
+			Region:        loadBalancer.Region,
+			Name:          loadBalancer.Name,
+			Tags:          loadBalancer.Tags,
+			Age:           loadBalancer.Age,
+			InstanceCount: loadBalancer.InstanceCount,
+		}
+		d := pol.evaluate(subject, p.limits)
+		printLoadBalancer(p, loadBalancer, d.State)
+		rec := loadBalancerRecord(cloud, loadBalancer, d)
+		if d.Action == policyDelete {
+			if flagQuarantine && !quarantineGate(ctx, rec, loadBalancer.Tags, func(value string) error {
+				return ctx.Value(core.ExecutorKey).(core.ExecutorInterface).LoadBalancerMark(actionContext(ctx), loadBalancer, value)
+			}) {
+				// marked or still in its grace window; the gate reported it
+			} else if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock deleted!\n")
+				finish(ctx, rec, resultMock, nil)
 			} else {
-				deleteLoadBalancer(ctx, loadBalancer)
+				finish(ctx, rec, resultDeleted, deleteLoadBalancer(ctx, loadBalancer))
 			}
+		} else {
+			printKept(p.out, d)
+			checkExpiry(p, pol, subject, &rec)
+			reportSkipped(ctx, rec)
 		}
 	}
 }
 
-func printServer(server core.Server, state string) {
+func printServer(p pass, server core.Server, state string) {
 	ageString := fmt.Sprintf("%.2f days old", server.Age)
-	prettyPrint(fmt.Sprintf("[%s] [%s] [%s] [%s] ▶ ", ageString, server.Region, state, server.Name), flagMock)
+	if server.AgeSource != "" {
+		ageString += " by " + server.AgeSource
+	}
+	fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] [%s] ▶ ", ageString, server.Region, state, server.Name), p.run.Mock)
 }
 
-func printLoadBalancer(loadBalancer core.LoadBalancer, state string) {
+func printLoadBalancer(p pass, loadBalancer core.LoadBalancer, state string) {
 	ageString := fmt.Sprintf("%.2f days old", loadBalancer.Age)
-	prettyPrint(fmt.Sprintf("[%s] [%s] [%s] [%s] [%3d instances] [%s] ▶ ", ageString, loadBalancer.Region, state, loadBalancer.Type, loadBalancer.InstanceCount, loadBalancer.Name), flagMock)
+	fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] [%s] [%3d instances] [%s] ▶ ", ageString, loadBalancer.Region, state, loadBalancer.Type, loadBalancer.InstanceCount, loadBalancer.Name), p.run.Mock)
 }
 
-func deleteLoadBalancer(ctx context.Context, loadBalancer core.LoadBalancer) {
+func stopServer(ctx context.Context, server core.Server) error {
+	p := passOf(ctx)
 	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
-	err := executor.LoadBalancerDelete(ctx, loadBalancer)
+	err := executor.ServerStop(actionContext(ctx), server)
 	if err != nil {
-		_, _ = fmt.Fprintf(out, "ERROR: %s\n", err.Error())
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
 	} else {
-		_, _ = fmt.Fprintf(out, "Deleted!\n")
+		_, _ = fmt.Fprintf(p.out, "Stopped!\n")
 	}
+	return err
 }
 
-func deleteSshKey(ctx context.Context, sshKey core.SshKey) {
+func startServer(ctx context.Context, server core.Server) error {
+	p := passOf(ctx)
 	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
-	err := executor.SshKeyDelete(ctx, sshKey)
+	err := executor.ServerStart(actionContext(ctx), server)
 	if err != nil {
-		_, _ = fmt.Fprintf(out, "ERROR: %s\n", err.Error())
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
 	} else {
-		_, _ = fmt.Fprintf(out, "Deleted!\n")
+		_, _ = fmt.Fprintf(p.out, "Started!\n")
 	}
+	return err
 }
 
-func deleteVolumes(ctx context.Context, volumes []core.Volume) {
-	// minimum age threshold: 1 hour (in days)
-	minAge := 1.0 / 24.0
+func deleteLoadBalancer(ctx context.Context, loadBalancer core.LoadBalancer) error {
+	p := passOf(ctx)
+	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
+	err := executor.LoadBalancerDelete(actionContext(ctx), loadBalancer)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
+	} else {
+		_, _ = fmt.Fprintf(p.out, "Deleted!\n")
+	}
+	return err
+}
 
+func deleteSshKey(ctx context.Context, sshKey core.SshKey) error {
+	p := passOf(ctx)
+	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
+	err := executor.SshKeyDelete(actionContext(ctx), sshKey)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
+	} else {
+		_, _ = fmt.Fprintf(p.out, "Deleted!\n")
+	}
+	return err
+}
+
+func deleteVolumes(ctx context.Context, volumes []core.Volume) {
+	pol := activePolicy()
+	p := passOf(ctx)
+	cloud := cloudFromContext(ctx)
 	for _, volume := range volumes {
-		printVolume(volume)
-		if isPermanent(volume.Name, volume.Tags) {
-			_, _ = fmt.Fprintf(out, "skipped (permanent)\n")
-		} else if hasSampleTag(volume.Tags) {
-			// sample-stack volumes must be spared along with their owning
-			// servers (panel finding A#8).
-			_, _ = fmt.Fprintf(out, "skipped (sample tag)\n")
-		} else if volume.Age <= 0 {
-			// defense-in-depth: malformed/missing Created → skip.
-			_, _ = fmt.Fprintf(out, "skipped (unknown age — malformed Created)\n")
-		} else if volume.Attached {
-			// skip volumes that are attached to an instance
-			_, _ = fmt.Fprintf(out, "skipped (attached to instance)\n")
-		} else if volume.Age < minAge {
-			// skip recently created volumes that may not have been attached yet
-			_, _ = fmt.Fprintf(out, "skipped (too new)\n")
+		if cancelled(ctx) {
+			return
+		}
+		printVolume(p, volume)
+		subject := policySubject{
+			Kind:     kindVolume,
+			Cloud:    cloud,
+			Region:   volume.Region,
+			Name:     volume.Name,
+			Tags:     volume.Tags,
+			Age:      volume.Age,
+			Attached: volume.Attached,
+		}
+		d := pol.evaluate(subject, p.limits)
+		rec := volumeRecord(cloud, volume, d)
+		if d.Action == policyDelete {
+			if flagQuarantine && !quarantineGate(ctx, rec, volume.Tags, func(value string) error {
+				return ctx.Value(core.ExecutorKey).(core.ExecutorInterface).VolumeMark(actionContext(ctx), volume, value)
+			}) {
+				// marked or still in its grace window; the gate reported it
+			} else if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock deleted!\n")
+				finish(ctx, rec, resultMock, nil)
+			} else {
+				finish(ctx, rec, resultDeleted, deleteVolume(ctx, volume))
+			}
 		} else {
-			if flagMock {
-				_, _ = fmt.Fprintf(out, "Mock deleted!\n")
+			printKept(p.out, d)
+			checkExpiry(p, pol, subject, &rec)
+			reportSkipped(ctx, rec)
+		}
+	}
+}
+
+func deleteVolume(ctx context.Context, volume core.Volume) error {
+	p := passOf(ctx)
+	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
+	err := executor.VolumeDelete(actionContext(ctx), volume)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
+	} else {
+		_, _ = fmt.Fprintf(p.out, "Deleted!\n")
+	}
+	return err
+}
+
+// deleteTargetGroups deletes the orphan target groups the policy selects.
+// target groups carry no creation time, so the deletion mark is their only
+// clock: one is marked on first sighting and deleted once the mark is past
+// --quarantine-grace, with or without --quarantine. without a mark signing
+// key nothing is deleted.
+func deleteTargetGroups(ctx context.Context, targetGroups []core.TargetGroup) {
+	pol := activePolicy()
+	p := passOf(ctx)
+	cloud := cloudFromContext(ctx)
+	for _, targetGroup := range targetGroups {
+		if cancelled(ctx) {
+			return
+		}
+		subject := policySubject{
+			Kind:   kindTargetGroup,
+			Cloud:  cloud,
+			Region: targetGroup.Region,
+			Name:   targetGroup.Name,
+			Tags:   targetGroup.Tags,
+		}
+		d := pol.evaluate(subject, p.limits)
+		printTargetGroup(p, targetGroup, d.State)
+		rec := targetGroupRecord(cloud, targetGroup, d)
+		if d.Action == policyDelete {
+			if markSigningKey == nil {
+				_, _ = fmt.Fprintf(p.out, "skipped (no mark signing key)\n")
+				rec.Decision, rec.Reason = policyKeep, "no mark signing key"
+				reportSkipped(ctx, rec)
+			} else if !quarantineGate(ctx, rec, targetGroup.Tags, func(value string) error {
+				return ctx.Value(core.ExecutorKey).(core.ExecutorInterface).TargetGroupMark(actionContext(ctx), targetGroup, value)
+			}) {
+				// marked or still in its grace window; the gate reported it
+			} else if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock deleted!\n")
+				finish(ctx, rec, resultMock, nil)
 			} else {
-				deleteVolume(ctx, volume)
+				finish(ctx, rec, resultDeleted, deleteTargetGroup(ctx, targetGroup))
 			}
+		} else {
+			printKept(p.out, d)
+			reportSkipped(ctx, rec)
 		}
 	}
 }
 
-func deleteVolume(ctx context.Context, volume core.Volume) {
+func deleteTargetGroup(ctx context.Context, targetGroup core.TargetGroup) error {
+	p := passOf(ctx)
 	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
-	err := executor.VolumeDelete(ctx, volume)
+	err := executor.TargetGroupDelete(actionContext(ctx), targetGroup)
 	if err != nil {
-		_, _ = fmt.Fprintf(out, "ERROR: %s\n", err.Error())
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
 	} else {
-		_, _ = fmt.Fprintf(out, "Deleted!\n")
+		_, _ = fmt.Fprintf(p.out, "Deleted!\n")
 	}
+	return err
+}
+
+func printTargetGroup(p pass, targetGroup core.TargetGroup, state string) {
+	fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] ▶ ", targetGroup.Region, state, targetGroup.Name), p.run.Mock)
 }
 
-func printVolume(volume core.Volume) {
+func printVolume(p pass, volume core.Volume) {
 	ageString := fmt.Sprintf("%.2f days old", volume.Age)
-	prettyPrint(fmt.Sprintf("[%s] [%s] [%s] ▶ ", ageString, volume.Region, volume.Name), flagMock)
+	fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] ▶ ", ageString, volume.Region, volume.Name), p.run.Mock)
 }
 
-func deleteSshKeys(ctx context.Context, sshKeys []core.SshKey) {
-	// IMPORTANT: This implementation assumes that sorting by VendorID is equivalent to sorting by the creation date (some clouds don't return `created_at` for SSH keys)
-	// Since there is no `created_at` field, keep last `flagSshKeysKeepCount` to avoid deleting an SSH key before it is used
-
-	nonUserDefinedSshKeyCount := 0
-	for _, sshKey := range sshKeys {
-		if strings.HasPrefix(sshKey.Name, "c66-") {
-			nonUserDefinedSshKeyCount += 1
-		}
-	}
-
-	deletedSshKeys := 0
-	for _, sshKey := range sshKeys {
-		prettyPrint(fmt.Sprintf("[%s] [%s] ▶ ", sshKey.VendorID, sshKey.Name), flagMock)
-		if strings.HasPrefix(sshKey.Name, "c66-") {
-			if (nonUserDefinedSshKeyCount - flagSshKeysKeepCount) > deletedSshKeys {
-				deletedSshKeys += 1
-				if flagMock {
-					_, _ = fmt.Fprintf(out, "Mock deleted!\n")
-				} else {
-					deleteSshKey(ctx, sshKey)
-				}
+// snapshotReferenced is the reason recorded for a snapshot a registered image
+// still depends on.
+const snapshotReferenced = "referenced by image"
+
+func deleteSnapshots(ctx context.Context, snapshots []core.Snapshot) {
+	pol := activePolicy()
+	p := passOf(ctx)
+	cloud := cloudFromContext(ctx)
+	for _, snapshot := range snapshots {
+		if cancelled(ctx) {
+			return
+		}
+		printSnapshot(p, snapshot)
+		// checked ahead of the policy so no rule, however broad, can delete
+		// the backing store of an image that is still registered.
+		if snapshot.InUse {
+			d := decision{Action: policyKeep, Reason: snapshotReferenced, State: "LIVE"}
+			printKept(p.out, d)
+			reportSkipped(ctx, snapshotRecord(cloud, snapshot, d))
+			continue
+		}
+		d := pol.evaluate(policySubject{
+			Kind:   kindSnapshot,
+			Cloud:  cloud,
+			Region: snapshot.Region,
+			Name:   snapshot.Name,
+			Tags:   snapshot.Tags,
+			Age:    snapshot.Age,
+		}, p.limits)
+		rec := snapshotRecord(cloud, snapshot, d)
+		if d.Action == policyDelete {
+			if flagQuarantine {
+				// snapshots have no mark support yet; under --quarantine
+				// nothing may be deleted without a grace window.
+				_, _ = fmt.Fprintf(p.out, "skipped (marking unsupported)\n")
+				rec.Decision, rec.Reason = policyKeep, "marking unsupported"
+				reportSkipped(ctx, rec)
+			} else if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock deleted!\n")
+				finish(ctx, rec, resultMock, nil)
+			} else {
+				finish(ctx, rec, resultDeleted, deleteSnapshot(ctx, snapshot))
+			}
+		} else {
+			printKept(p.out, d)
+			reportSkipped(ctx, rec)
+		}
+	}
+}
+
+func deleteSnapshot(ctx context.Context, snapshot core.Snapshot) error {
+	p := passOf(ctx)
+	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
+	err := executor.SnapshotDelete(actionContext(ctx), snapshot)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
+	} else {
+		_, _ = fmt.Fprintf(p.out, "Deleted!\n")
+	}
+	return err
+}
+
+func printSnapshot(p pass, snapshot core.Snapshot) {
+	ageString := fmt.Sprintf("%.2f days old", snapshot.Age)
+	fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] [%s] ▶ ", ageString, snapshot.Region, snapshot.Type, snapshot.Name), p.run.Mock)
+}
+
+func releaseIPAddresses(ctx context.Context, ipAddresses []core.IPAddress) {
+	pol := activePolicy()
+	p := passOf(ctx)
+	cloud := cloudFromContext(ctx)
+	for _, ipAddress := range ipAddresses {
+		if cancelled(ctx) {
+			return
+		}
+		// most providers report no allocation time; the state store's
+		// first sighting stands in for it from the second run on.
+		ageSource := ""
+		if ipAddress.Age <= 0 {
+			key := stateKey{VendorID: ipAddress.VendorID, Cloud: cloud, Account: p.account, Kind: kindIPAddress}
+			if age, ok := firstSeenAge(ctx, key, time.Now()); ok {
+				ipAddress.Age, ageSource = age, ageSourceFirstSeen
+			}
+		}
+		printIPAddress(p, ipAddress, ageSource)
+		d := pol.evaluate(policySubject{
+			Kind:     kindIPAddress,
+			Cloud:    cloud,
+			Region:   ipAddress.Region,
+			Name:     ipAddress.Name,
+			Tags:     ipAddress.Tags,
+			Age:      ipAddress.Age,
+			Attached: ipAddress.Assigned,
+		}, p.limits)
+		rec := ipAddressRecord(cloud, ipAddress, d)
+		rec.AgeSource = ageSource
+		if d.Action == policyDelete {
+			if flagQuarantine {
+				// addresses have no mark support; see deleteSnapshots.
+				_, _ = fmt.Fprintf(p.out, "skipped (marking unsupported)\n")
+				rec.Decision, rec.Reason = policyKeep, "marking unsupported"
+				reportSkipped(ctx, rec)
+			} else if p.run.Mock {
+				_, _ = fmt.Fprintf(p.out, "Mock released!\n")
+				finish(ctx, rec, resultMock, nil)
 			} else {
-				_, _ = fmt.Fprintf(out, "skipped (keep last %d)\n", flagSshKeysKeepCount)
+				finish(ctx, rec, resultReleased, releaseIPAddress(ctx, ipAddress))
 			}
 		} else {
-			_, _ = fmt.Fprintf(out, "skipped (name)\n")
+			printKept(p.out, d)
+			reportSkipped(ctx, rec)
 		}
 	}
 }
+
+func releaseIPAddress(ctx context.Context, ipAddress core.IPAddress) error {
+	p := passOf(ctx)
+	executor := ctx.Value(core.ExecutorKey).(core.ExecutorInterface)
+	err := executor.IPAddressRelease(actionContext(ctx), ipAddress)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
+	} else {
+		_, _ = fmt.Fprintf(p.out, "Released!\n")
+	}
+	return err
+}
+
+// printIPAddress prints the address line. most providers report no
+// allocation time, which is called out rather than shown as 0.00 days.
+func printIPAddress(p pass, ipAddress core.IPAddress, ageSource string) {
+	ageString := "age unknown"
+	if ipAddress.Age > 0 {
+		ageString = fmt.Sprintf("%.2f days old", ipAddress.Age)
+	}
+	if ageSource != "" {
+		ageString += " by " + ageSource
+	}
+	fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] [%s] ▶ ", ageString, ipAddress.Region, ipAddress.Address, ipAddress.Name), p.run.Mock)
+}
+
+func deleteSshKeys(ctx context.Context, sshKeys []core.SshKey) {
+	// IMPORTANT: keep-last-N relies on list order. core.SshKeySorter orders each region oldest first — by creation
+	// time where the provider exposes it, otherwise by VendorID, which is only *assumed* to follow creation order
+	// (DO has no `created_at`); such keys are flagged "age unknown" in the output.
+	// Keep the last `flagSshKeysKeepCount` delete candidates of each region, plus any candidate younger than
+	// `flagSshKeysKeepDays`, to avoid deleting an SSH key before it is used.
+	// Key pairs are regional on AWS: a key kept in one region does nothing for a stack launching in another.
+	pol := activePolicy()
+	p := passOf(ctx)
+	cloud := cloudFromContext(ctx)
+
+	decisions := make([]decision, len(sshKeys))
+	candidateCount := map[string]int{}
+	for i, sshKey := range sshKeys {
+		decisions[i] = pol.evaluate(policySubject{
+			Kind:   kindSshKey,
+			Cloud:  cloud,
+			Region: sshKey.Region,
+			Name:   sshKey.Name,
+			Tags:   sshKey.Tags,
+			Age:    sshKey.Age,
+		}, p.limits)
+		if decisions[i].Action == policyDelete {
+			candidateCount[sshKey.Region] += 1
+		}
+	}
+
+	seenCandidates := map[string]int{}
+	for i, sshKey := range sshKeys {
+		if cancelled(ctx) {
+			return
+		}
+		printSshKey(p, sshKey)
+		rec := sshKeyRecord(cloud, sshKey, decisions[i])
+		if decisions[i].Action != policyDelete {
+			printKept(p.out, decisions[i])
+			reportSkipped(ctx, rec)
+			continue
+		}
+		seenCandidates[sshKey.Region] += 1
+		if seenCandidates[sshKey.Region] > candidateCount[sshKey.Region]-flagSshKeysKeepCount {
+			_, _ = fmt.Fprintf(p.out, "skipped (keep last %d)\n", flagSshKeysKeepCount)
+			rec.Decision, rec.Reason = policyKeep, fmt.Sprintf("keep last %d", flagSshKeysKeepCount)
+			reportSkipped(ctx, rec)
+		} else if !sshKey.Created.IsZero() && sshKey.Age < flagSshKeysKeepDays {
+			reason := fmt.Sprintf("younger than %g days", flagSshKeysKeepDays)
+			_, _ = fmt.Fprintf(p.out, "skipped (%s)\n", reason)
+			rec.Decision, rec.Reason = policyKeep, reason
+			reportSkipped(ctx, rec)
+		} else if p.run.Mock {
+			_, _ = fmt.Fprintf(p.out, "Mock deleted!\n")
+			finish(ctx, rec, resultMock, nil)
+		} else {
+			finish(ctx, rec, resultDeleted, deleteSshKey(ctx, sshKey))
+		}
+	}
+}
+
+// printSshKey prints the key line. keys without a creation time say so: their
+// keep-last-N position comes from VendorID order, not from their real age.
+func printSshKey(p pass, sshKey core.SshKey) {
+	ageString := "age unknown, ordered by ID"
+	if !sshKey.Created.IsZero() {
+		ageString = fmt.Sprintf("%.2f days old", sshKey.Age)
+	}
+	if sshKey.Region != "" {
+		fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] [%s] ▶ ", sshKey.VendorID, sshKey.Region, ageString, sshKey.Name), p.run.Mock)
+		return
+	}
+	fprettyPrint(p.out, fmt.Sprintf("[%s] [%s] [%s] ▶ ", sshKey.VendorID, ageString, sshKey.Name), p.run.Mock)
+}
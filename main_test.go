@@ -5,6 +5,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cloud66/janitor/core"
 )
@@ -27,22 +28,26 @@ func captureOutput(t *testing.T, fn func()) string {
 // withFlags sets the global test-affecting flags and registers a t.Cleanup
 // to restore their prior values. Use this instead of mutating flagMock /
 // flagMaxAgeNormal / flagMaxAgeLong directly so tests don't leak state
-// across runs (especially with -shuffle=on).
+// across runs (especially with -shuffle=on). --max-age-snapshot is reset
+// to its default; tests that need another value set it after the call.
 func withFlags(t *testing.T, mock bool, normal, long float64) {
 	t.Helper()
 	// capture previous values
 	prevMock := flagMock
 	prevNormal := flagMaxAgeNormal
 	prevLong := flagMaxAgeLong
+	prevSnapshot := flagMaxAgeSnapshot
 	// apply new values
 	flagMock = mock
 	flagMaxAgeNormal = normal
 	flagMaxAgeLong = long
+	flagMaxAgeSnapshot = defaultMaxAgeSnapshot
 	// restore on test end
 	t.Cleanup(func() {
 		flagMock = prevMock
 		flagMaxAgeNormal = prevNormal
 		flagMaxAgeLong = prevLong
+		flagMaxAgeSnapshot = prevSnapshot
 	})
 }
 
@@ -726,3 +731,223 @@ func TestDeleteVolumes_SkipPathsDoNotInvokeDelete(t *testing.T) {
 		t.Fatalf("expected no VolumeDelete calls on skip-only input, got %d: %+v", len(fe.deletedVolumes), fe.deletedVolumes)
 	}
 }
+
+// --- deleteSnapshots classification tests ---
+
+func TestDeleteSnapshots_CallLog(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	fe := &fakeExecutor{}
+	ctx := ctxWithExec(fe)
+
+	snapshots := []core.Snapshot{
+		{VendorID: "s1", Name: "nightly", Age: 2.0, Region: "us", Type: core.SnapshotTypeSnapshot, InUse: true}, // backs an image → skip
+		{VendorID: "s2", Name: "permanent-golden", Age: 9.0, Region: "us", Type: core.SnapshotTypeImage},        // PERM → skip
+		{VendorID: "s3", Name: "broken", Age: 0, Region: "us", Type: core.SnapshotTypeSnapshot},                 // WARN → skip
+		{VendorID: "s4", Name: "fresh", Age: 0.1, Region: "us", Type: core.SnapshotTypeSnapshot},                // NORM, young → skip
+		{VendorID: "s5", Name: "stale", Age: 31.0, Region: "us", Type: core.SnapshotTypeSnapshot},               // delete
+		{VendorID: "s6", Name: "weekly", Age: 2.0, Region: "us", Type: core.SnapshotTypeSnapshot},               // past --max-age-regular only → skip
+		{VendorID: "s7", Name: "long-soak", Age: 6.0, Region: "us", Type: core.SnapshotTypeImage},               // past --max-age-long only → skip
+	}
+	got := captureOutput(t, func() { deleteSnapshots(ctx, snapshots) })
+
+	if len(fe.deletedSnaps) != 1 || fe.deletedSnaps[0].VendorID != "s5" {
+		t.Fatalf("expected only s5 deleted, got %+v", fe.deletedSnaps)
+	}
+	for _, want := range []string{"skipped (referenced by image)", "skipped (permanent)", "skipped (unknown age", "[image] [permanent-golden]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q, got %q", want, got)
+		}
+	}
+}
+
+// TestDeleteSnapshots_InUseBeatsPolicy pins that a referenced snapshot is
+// kept even by a policy that deletes every snapshot.
+func TestDeleteSnapshots_InUseBeatsPolicy(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withPolicy(t, &policy{Rules: []policyRule{
+		{Kind: kindSnapshot, Action: policyDelete, Reason: "all", State: "DEAD"},
+	}})
+	buf := captureReport(t, outputNDJSON)
+	fe := &fakeExecutor{}
+
+	captureOutput(t, func() {
+		deleteSnapshots(ctxWithExec(fe), []core.Snapshot{{VendorID: "snap-1", Age: 30, InUse: true}})
+	})
+	if len(fe.deletedSnaps) != 0 {
+		t.Fatalf("referenced snapshot must not be deleted, got %+v", fe.deletedSnaps)
+	}
+	records := decodeNDJSON(t, buf)
+	if len(records) != 1 || records[0].Kind != kindSnapshot || records[0].Decision != policyKeep || records[0].Reason != snapshotReferenced || records[0].State != "LIVE" {
+		t.Errorf("want LIVE keep record, got %+v", records)
+	}
+}
+
+func TestDeleteSnapshots_QuarantineNeverDeletes(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withQuarantine(t, 0)
+	fe := &fakeExecutor{}
+
+	got := captureOutput(t, func() {
+		deleteSnapshots(ctxWithExec(fe), []core.Snapshot{{VendorID: "snap-1", Name: "stale", Age: 45}})
+	})
+	if len(fe.deletedSnaps) != 0 || !strings.Contains(got, "skipped (marking unsupported)") {
+		t.Errorf("snapshots cannot be marked, so --quarantine must skip them; deletes=%+v output=%q", fe.deletedSnaps, got)
+	}
+}
+
+// --- releaseIPAddresses classification tests ---
+
+func TestReleaseIPAddresses_CallLog(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	fe := &fakeExecutor{}
+
+	ips := []core.IPAddress{
+		{VendorID: "ip-1", Address: "192.0.2.1", Age: 2.0, Assigned: true},                        // assigned → skip
+		{VendorID: "ip-2", Address: "192.0.2.2", Age: 2.0, Tags: []string{"lifecycle=permanent"}}, // PERM → skip
+		{VendorID: "ip-3", Address: "192.0.2.3", Age: 0},                                          // no allocation time → skip
+		{VendorID: "ip-4", Address: "192.0.2.4", Age: 0.1},                                        // too new → skip
+		{VendorID: "ip-5", Address: "192.0.2.5", Age: 2.0},                                        // release
+	}
+	got := captureOutput(t, func() { releaseIPAddresses(ctxWithExec(fe), ips) })
+
+	if len(fe.releasedIPs) != 1 || fe.releasedIPs[0].VendorID != "ip-5" {
+		t.Fatalf("expected only ip-5 released, got %+v", fe.releasedIPs)
+	}
+	for _, want := range []string{"skipped (assigned)", "skipped (permanent)", "[age unknown] [] [192.0.2.3] [] ▶ skipped (unknown age)", "skipped (too new)", "Released!"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q, got %q", want, got)
+		}
+	}
+}
+
+func TestReleaseIPAddresses_MockDoesNotRelease(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	buf := captureReport(t, outputNDJSON)
+	fe := &fakeExecutor{}
+
+	got := captureOutput(t, func() {
+		releaseIPAddresses(ctxWithExec(fe), []core.IPAddress{{VendorID: "ip-1", Age: 2.0}})
+	})
+	if len(fe.releasedIPs) != 0 || !strings.Contains(got, "Mock released!") {
+		t.Errorf("mock must only print, got released=%+v output=%q", fe.releasedIPs, got)
+	}
+	records := decodeNDJSON(t, buf)
+	if len(records) != 1 || records[0].Kind != kindIPAddress || records[0].Decision != policyDelete || records[0].Result != resultMock {
+		t.Errorf("want mock delete record, got %+v", records)
+	}
+}
+
+// AWS reports no allocation time for Elastic IPs: an unassigned one is
+// unknown-age on its first sighting and aged from it on later runs.
+func TestReleaseIPAddresses_UnassignedElasticIPAgedByFirstSighting(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	store, _ := openStateStore(stateMemory)
+	withState(t, store)
+	buf := captureReport(t, outputNDJSON)
+	eip := core.IPAddress{VendorID: "eipalloc-1", Address: "192.0.2.10", Region: "us-east-1"}
+	ctx := context.WithValue(ctxWithExec(&fakeExecutor{}), core.CloudKey, "aws")
+
+	got := captureOutput(t, func() { releaseIPAddresses(ctx, []core.IPAddress{eip}) })
+	if !strings.Contains(got, "[age unknown]") || !strings.Contains(got, "skipped (unknown age)") {
+		t.Errorf("want the first sighting kept as unknown age, got %q", got)
+	}
+
+	// pretend that sighting was two days ago
+	store, _ = openStateStore(stateMemory)
+	withState(t, store)
+	store.Record(record{Cloud: "aws", Kind: kindIPAddress, VendorID: "eipalloc-1", Decision: policyKeep, Result: resultSkipped}, time.Now().Add(-48*time.Hour))
+	got = captureOutput(t, func() { releaseIPAddresses(ctx, []core.IPAddress{eip}) })
+	if !strings.Contains(got, "days old by first seen") || !strings.Contains(got, "Mock released!") {
+		t.Errorf("want the address aged from its first sighting and released, got %q", got)
+	}
+
+	records := decodeNDJSON(t, buf)
+	if len(records) != 2 || records[0].State != stateWarn || records[0].AgeSource != "" {
+		t.Fatalf("want an unknown-age first record, got %+v", records)
+	}
+	if r := records[1]; r.Decision != policyDelete || r.Result != resultMock || r.AgeSource != ageSourceFirstSeen || r.AgeDays < 1.9 {
+		t.Errorf("want a mock release aged two days by first seen, got %+v", r)
+	}
+
+	// an address that is still assigned stays live whatever its age
+	eip.Assigned = true
+	got = captureOutput(t, func() { releaseIPAddresses(ctx, []core.IPAddress{eip}) })
+	if !strings.Contains(got, "skipped (assigned)") {
+		t.Errorf("want an assigned address kept, got %q", got)
+	}
+}
+
+// --- stop / start actions ---
+
+// withStartTag swaps flagStartTag for the test and restores it on cleanup.
+func withStartTag(t *testing.T, tag string) {
+	t.Helper()
+	prev := flagStartTag
+	flagStartTag = tag
+	t.Cleanup(func() { flagStartTag = prev })
+}
+
+func TestStopServers_OnlyOverAgeNonPermanentRunning(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	fe := &fakeExecutor{}
+	ctx := ctxWithExec(fe)
+
+	servers := []core.Server{
+		{Name: "old-box", Age: 2, Region: "us", State: "RUNNING"},                                      // stop
+		{Name: "young-box", Age: 0.1, Region: "us", State: "RUNNING"},                                  // age
+		{Name: "permanent-box", Age: 9, Region: "us", State: "RUNNING"},                                // permanent
+		{Name: "already-off", Age: 9, Region: "us", State: "STOPPED"},                                  // already stopped
+		{Name: "long-box", Age: 2, Region: "us", State: "RUNNING"},                                     // long allowance
+		{Name: "sample", Age: 9, Region: "us", State: "RUNNING", Tags: []string{"C66-STACK=x-sample"}}, // sample
+	}
+	got := captureOutput(t, func() { stopServers(ctx, "aws", servers) })
+
+	if len(fe.stoppedServers) != 1 || fe.stoppedServers[0].Name != "old-box" {
+		t.Fatalf("want only old-box stopped, got %+v", fe.stoppedServers)
+	}
+	if len(fe.deletedLBs)+len(fe.deletedVolumes)+len(fe.deletedKeys) != 0 {
+		t.Errorf("stop must never delete anything")
+	}
+	for _, want := range []string{"Stopped!", "skipped (already stopped)", "skipped (permanent)", "skipped (sample tag)", "skipped (age)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q: %q", want, got)
+		}
+	}
+}
+
+func TestStartServers_TaggedStoppedOnly(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withStartTag(t, "schedule=office-hours")
+	fe := &fakeExecutor{}
+	ctx := ctxWithExec(fe)
+
+	servers := []core.Server{
+		{Name: "qa-1", Age: 3, Region: "us", State: "STOPPED", Tags: []string{"schedule=office-hours"}}, // start
+		{Name: "qa-2", Age: 3, Region: "us", State: "RUNNING", Tags: []string{"schedule=office-hours"}}, // already on
+		{Name: "other", Age: 3, Region: "us", State: "STOPPED", Tags: []string{"schedule=never"}},       // not selected
+	}
+	got := captureOutput(t, func() { startServers(ctx, servers) })
+
+	if len(fe.startedServers) != 1 || fe.startedServers[0].Name != "qa-1" {
+		t.Fatalf("want only qa-1 started, got %+v", fe.startedServers)
+	}
+	if !strings.Contains(got, "skipped (not stopped)") || !strings.Contains(got, "skipped (no start tag)") {
+		t.Errorf("missing skip reasons in %q", got)
+	}
+}
+
+func TestStartServers_MockDoesNotCallExecutor(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	// bare tag form, as DigitalOcean tags carry no value
+	withStartTag(t, "office-hours")
+	fe := &fakeExecutor{}
+	got := captureOutput(t, func() {
+		startServers(ctxWithExec(fe), []core.Server{{Name: "qa", Age: 1, State: "STOPPED", Tags: []string{"office-hours"}}})
+	})
+	if !strings.Contains(got, "Mock started!") {
+		t.Errorf("want Mock started!, got %q", got)
+	}
+	if len(fe.startedServers) != 0 {
+		t.Errorf("mock must not call ServerStart, got %d calls", len(fe.startedServers))
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// resourceKey groups the records of a run for the janitor_resources gauge.
+type resourceKey struct {
+	Cloud, Account, Kind, Region, Decision string
+}
+
+// metrics is the Prometheus view of the sweeps run by this process. the
+// resources gauge shows the last finished run; counters and histograms
+// accumulate across runs so rate() and increase() work as usual.
+type metrics struct {
+	registry *prometheus.Registry
+
+	resources      *prometheus.GaugeVec
+	resourceAge    *prometheus.HistogramVec
+	actions        *prometheus.CounterVec
+	listFailures   *prometheus.CounterVec
+	lastRun        prometheus.Gauge
+	lastSuccess    prometheus.Gauge
+	lastExitCode   prometheus.Gauge
+	apiCallLatency *prometheus.HistogramVec
+	apiCallErrors  *prometheus.CounterVec
+
+	mu sync.Mutex
+	// seen counts the in-flight run's records; it replaces the resources
+	// gauge in finishRun so a scrape never sees a half-finished run.
+	seen map[resourceKey]int
+	now  func() time.Time
+}
+
+// runMetrics is the process's registry: the daemon publishes its runs to it
+// and both --action=webserver and --action=daemon serve it at /metrics.
+var runMetrics = newMetrics()
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		resources: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "janitor_resources",
+			Help: "Resources seen by the last finished run, by decision (delete, keep, report, ...).",
+		}, []string{"cloud", "account", "kind", "region", "decision"}),
+		resourceAge: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "janitor_resource_age_days",
+			Help:    "Age in days of every resource a run classified.",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 5, 7, 14, 30, 90, 365},
+		}, []string{"cloud", "kind"}),
+		actions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "janitor_actions_total",
+			Help: "Actions attempted on resources, by result (deleted, released, stopped, started, marked, mock, failed).",
+		}, []string{"cloud", "kind", "result"}),
+		listFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "janitor_listing_failures_total",
+			Help: "Provider listings that failed, leaving that kind unswept.",
+		}, []string{"cloud", "kind"}),
+		lastRun: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "janitor_last_run_timestamp_seconds",
+			Help: "Unix time the last run finished, whatever its outcome.",
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "janitor_last_success_timestamp_seconds",
+			Help: "Unix time the last run finished with every listing and action succeeding.",
+		}),
+		lastExitCode: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "janitor_last_run_exit_code",
+			Help: "Exit code of the last run (0 clean, 3 listing failed, 4 actions failed).",
+		}),
+		apiCallLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "janitor_api_call_duration_seconds",
+			Help:    "Latency of provider API calls.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"cloud", "operation"}),
+		apiCallErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "janitor_api_call_errors_total",
+			Help: "Provider API calls that returned an error.",
+		}, []string{"cloud", "operation"}),
+		seen: map[resourceKey]int{},
+		now:  time.Now,
+	}
+	m.registry.MustRegister(
+		m.resources, m.resourceAge, m.actions, m.listFailures,
+		m.lastRun, m.lastSuccess, m.lastExitCode,
+		m.apiCallLatency, m.apiCallErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// record counts one decision record of the in-flight run.
+func (m *metrics) record(rec record) {
+	if rec.Kind == kindRegion {
+		// skipped regions are not resources
+		return
+	}
+	m.mu.Lock()
+	m.seen[resourceKey{Cloud: rec.Cloud, Account: rec.Account, Kind: rec.Kind, Region: rec.Region, Decision: rec.Decision}]++
+	m.mu.Unlock()
+	m.resourceAge.WithLabelValues(rec.Cloud, rec.Kind).Observe(rec.AgeDays)
+	if rec.Result != resultSkipped {
+		m.actions.WithLabelValues(rec.Cloud, rec.Kind, rec.Result).Inc()
+	}
+}
+
+// listFailed counts a failed provider listing.
+func (m *metrics) listFailed(cloud, kind string) {
+	m.listFailures.WithLabelValues(cloud, kind).Inc()
+}
+
+// finishRun publishes the run's resource counts and stamps its outcome.
+// code is the run's exit code as returned by summary.exitCode.
+func (m *metrics) finishRun(code int) {
+	m.mu.Lock()
+	seen := m.seen
+	m.seen = map[resourceKey]int{}
+	m.mu.Unlock()
+
+	m.resources.Reset()
+	for key, n := range seen {
+		m.resources.WithLabelValues(key.Cloud, key.Account, key.Kind, key.Region, key.Decision).Set(float64(n))
+	}
+	finished := float64(m.now().Unix())
+	m.lastRun.Set(finished)
+	m.lastExitCode.Set(float64(code))
+	if code == exitClean {
+		m.lastSuccess.Set(finished)
+	}
+}
+
+// ObserveAPICall implements core.APIObserver.
+func (m *metrics) ObserveAPICall(cloud, operation string, took time.Duration, err error) {
+	m.apiCallLatency.WithLabelValues(cloud, operation).Observe(took.Seconds())
+	if err != nil {
+		m.apiCallErrors.WithLabelValues(cloud, operation).Inc()
+	}
+}
+
+// handler serves the registry in the Prometheus text format.
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the /metrics body served for m.
+func scrape(t *testing.T, m *metrics) string {
+	t.Helper()
+	ts := httptest.NewServer(newServeMux(m))
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading /metrics: %v", err)
+	}
+	return string(b)
+}
+
+// wantLines fails the test for every line missing from body.
+func wantLines(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in /metrics:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetrics_RunPublishedOnFinish(t *testing.T) {
+	m := newMetrics()
+	m.now = func() time.Time { return time.Unix(1700000000, 0) }
+	prev := report
+	report = &reporter{format: outputText, metrics: m}
+	t.Cleanup(func() { report = prev })
+
+	report.add(record{Cloud: "aws", Kind: kindServer, Region: "us-east-1", AgeDays: 3, Decision: policyDelete, Result: resultDeleted})
+	report.add(record{Cloud: "aws", Kind: kindServer, Region: "us-east-1", AgeDays: 0.2, Decision: policyKeep, Result: resultSkipped})
+	report.add(record{Cloud: "aws", Kind: kindVolume, Region: "eu-west-1", AgeDays: 40, Decision: policyDelete, Result: resultFailed})
+	report.add(record{Cloud: "aws", Kind: kindRegion, Region: "me-south-1", Decision: policyKeep, Result: resultSkipped})
+	report.listFailed("hetzner", "", kindSnapshot, errors.New("boom"))
+
+	// nothing of the in-flight run is published before it finishes
+	if body := scrape(t, m); strings.Contains(body, "janitor_resources{") {
+		t.Errorf("resources published mid-run:\n%s", body)
+	}
+	m.finishRun(report.summary.exitCode())
+
+	body := scrape(t, m)
+	wantLines(t, body,
+		`janitor_resources{account="",cloud="aws",decision="delete",kind="server",region="us-east-1"} 1`,
+		`janitor_resources{account="",cloud="aws",decision="keep",kind="server",region="us-east-1"} 1`,
+		`janitor_resources{account="",cloud="aws",decision="delete",kind="volume",region="eu-west-1"} 1`,
+		`janitor_actions_total{cloud="aws",kind="server",result="deleted"} 1`,
+		`janitor_actions_total{cloud="aws",kind="volume",result="failed"} 1`,
+		`janitor_listing_failures_total{cloud="hetzner",kind="snapshot"} 1`,
+		`janitor_resource_age_days_count{cloud="aws",kind="server"} 2`,
+		`janitor_resource_age_days_bucket{cloud="aws",kind="server",le="0.25"} 1`,
+		`janitor_last_run_timestamp_seconds 1.7e+09`,
+		`janitor_last_run_exit_code 3`,
+		`janitor_last_success_timestamp_seconds 0`,
+	)
+	if strings.Contains(body, `kind="region"`) {
+		t.Errorf("skipped regions counted as resources:\n%s", body)
+	}
+
+	// a clean run replaces the resource counts and stamps the success time
+	m.now = func() time.Time { return time.Unix(1700003600, 0) }
+	report.add(record{Cloud: "aws", Kind: kindServer, Region: "us-east-1", Decision: policyKeep, Result: resultSkipped})
+	m.finishRun(exitClean)
+	body = scrape(t, m)
+	wantLines(t, body,
+		`janitor_resources{account="",cloud="aws",decision="keep",kind="server",region="us-east-1"} 1`,
+		`janitor_last_success_timestamp_seconds 1.7000036e+09`,
+		`janitor_last_run_exit_code 0`,
+	)
+	if strings.Contains(body, `kind="volume",region="eu-west-1"} 1`) {
+		t.Errorf("previous run's resources still published:\n%s", body)
+	}
+}
+
+func TestMetrics_ObserveAPICall(t *testing.T) {
+	m := newMetrics()
+	m.ObserveAPICall("digitalocean", "GET droplets", 120*time.Millisecond, nil)
+	m.ObserveAPICall("digitalocean", "GET droplets", 80*time.Millisecond, errors.New("HTTP 500"))
+
+	wantLines(t, scrape(t, m),
+		`janitor_api_call_duration_seconds_count{cloud="digitalocean",operation="GET droplets"} 2`,
+		`janitor_api_call_duration_seconds_sum{cloud="digitalocean",operation="GET droplets"} 0.2`,
+		`janitor_api_call_errors_total{cloud="digitalocean",operation="GET droplets"} 1`,
+	)
+}
+
+func TestServeMux_KeepsRootHandler(t *testing.T) {
+	ts := httptest.NewServer(newServeMux(newMetrics()))
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if string(b) != "Its a TRAP!" {
+		t.Errorf("GET / = %q", b)
+	}
+}
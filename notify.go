@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// webhook formats accepted by the --*-webhook flags.
+const (
+	webhookSlack      = "slack"
+	webhookMattermost = "mattermost"
+	webhookTeams      = "teams"
+)
+
+const (
+	// maxDigestItems caps each resource list of a digest; chat webhooks
+	// reject oversized messages, and a long tail is in the run's output.
+	maxDigestItems = 25
+	// webhookTimeout bounds one webhook post.
+	webhookTimeout = 10 * time.Second
+)
+
+// webhook is one incoming-webhook URL and the chat format it expects.
+type webhook struct {
+	Format string
+	URL    string
+}
+
+// webhooks are the configured notification targets, from the --*-webhook
+// flags. empty means runs are not announced.
+var webhooks []webhook
+
+// parseWebhooks reads a comma-separated list of webhook URLs for format.
+func parseWebhooks(format, value string) ([]webhook, error) {
+	var parsed []webhook
+	for i, raw := range strings.Split(value, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		// webhook URLs embed their secret, so errors name the position only
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("%s webhook %d is not an http(s) URL", format, i+1)
+		}
+		parsed = append(parsed, webhook{Format: format, URL: raw})
+	}
+	return parsed, nil
+}
+
+// digest is what a run announces: the summary rows and the notable records,
+// the same data the summary table and exit code come from.
+type digest struct {
+	Action    string
+	Clouds    string
+	Mock      bool
+	Cancelled bool
+	ExitCode  int
+	Rows      []summaryEntry
+	Acted     []record
+	Failed    []record
+	Warned    []record
+	// Expiring are the kept resources becoming eligible within
+	// --warn-before that no earlier run announced.
+	Expiring []record
+}
+
+// newDigest builds the digest of the run s summarizes, swept with opts.
+func newDigest(s *summary, opts runOptions, code int, cancelled bool) digest {
+	d := digest{
+		Action:    flagAction,
+		Clouds:    opts.Clouds,
+		Mock:      opts.Mock,
+		Cancelled: cancelled,
+		ExitCode:  code,
+		Rows:      s.entries(),
+	}
+	for _, rec := range s.notable {
+		switch {
+		case rec.Result == resultFailed:
+			d.Failed = append(d.Failed, rec)
+		case rec.Result == resultSkipped:
+			d.Warned = append(d.Warned, rec)
+		default:
+			d.Acted = append(d.Acted, rec)
+		}
+	}
+	for _, rec := range s.expiring {
+		if !warnings.announced(rec) {
+			d.Expiring = append(d.Expiring, rec)
+		}
+	}
+	return d
+}
+
+// digestSection is a heading and its lines, rendered per chat format.
+type digestSection struct {
+	Heading string
+	Lines   []string
+}
+
+// title is the one-line outcome, labelled when nothing was really touched.
+func (d digest) title() string {
+	acted, failed := 0, 0
+	for _, row := range d.Rows {
+		acted += row.Acted + row.Mock
+		failed += row.Failed
+	}
+	verb := strings.ToLower(actedColumn(d.Action))
+	if d.Mock {
+		verb = "would be " + verb
+	}
+	title := fmt.Sprintf("janitor %s on %s: %d %s, %d failed, %d warnings", d.Action, d.Clouds, acted, verb, failed, len(d.Warned))
+	if len(d.Expiring) > 0 {
+		title += fmt.Sprintf(", %d expiring soon", len(d.Expiring))
+	}
+	switch {
+	case d.Cancelled:
+		title += " (cancelled)"
+	case d.ExitCode == exitListingFailed:
+		title += " (incomplete: a listing failed)"
+	}
+	if d.Mock {
+		title = "[MOCK] " + title
+	}
+	return title
+}
+
+// sections renders the counts and the resource lists as markdown-ish lines
+// every supported chat format displays.
+func (d digest) sections() []digestSection {
+	acted := strings.ToLower(actedColumn(d.Action))
+	counts := digestSection{Heading: "Counts"}
+	for _, row := range d.Rows {
+		where := row.Cloud
+		if row.Account != "" {
+			where += "/" + row.Account
+		}
+		switch {
+		case row.ListError != "":
+			counts.Lines = append(counts.Lines, fmt.Sprintf("%s %s: listing failed: %s", where, row.Kind, row.ListError))
+		case row.Unsupported:
+			// nothing to say
+		default:
+			kept := 0
+			for _, n := range row.Kept {
+				kept += n
+			}
+			line := fmt.Sprintf("%s %s: %d scanned, %d kept, %d %s, %d mock, %d marked, %d failed",
+				where, row.Kind, row.Scanned, kept, row.Acted, acted, row.Mock, row.Marked, row.Failed)
+			if len(row.FailedRegions) > 0 {
+				line += "; listing failed in " + strings.Join(row.FailedRegions, ", ")
+			}
+			counts.Lines = append(counts.Lines, line)
+		}
+	}
+
+	resource := func(rec record) string {
+		where := rec.Cloud
+		if rec.Account != "" {
+			where += "/" + rec.Account
+		}
+		region := rec.Region
+		if region == "" {
+			region = "-"
+		}
+		return fmt.Sprintf("%s %s %s (%s) %s, %.2f days", where, rec.Kind, backticks(rec.Name), rec.VendorID, region, rec.AgeDays)
+	}
+	capped := func(heading string, recs []record, line func(record) string) digestSection {
+		section := digestSection{Heading: heading}
+		for i, rec := range recs {
+			if i == maxDigestItems {
+				section.Lines = append(section.Lines, fmt.Sprintf("…and %d more", len(recs)-maxDigestItems))
+				break
+			}
+			section.Lines = append(section.Lines, line(rec))
+		}
+		return section
+	}
+
+	sections := []digestSection{counts}
+	if len(d.Acted) > 0 {
+		sections = append(sections, capped("Acted on", d.Acted, func(rec record) string {
+			return resource(rec) + ": " + rec.Result
+		}))
+	}
+	if len(d.Failed) > 0 {
+		sections = append(sections, capped("Failed", d.Failed, func(rec record) string {
+			return resource(rec) + ": " + rec.Error
+		}))
+	}
+	if len(d.Warned) > 0 {
+		sections = append(sections, capped("Warnings", d.Warned, func(rec record) string {
+			return resource(rec) + ": " + rec.Reason
+		}))
+	}
+	if len(d.Expiring) > 0 {
+		sections = append(sections, capped("Expiring soon", d.Expiring, func(rec record) string {
+			return fmt.Sprintf("%s: eligible for deletion at %s; %s", resource(rec), formatTimestamp(*rec.ExpiresAt), expiryHint(rec))
+		}))
+	}
+	return sections
+}
+
+// escapeSlack escapes the three characters Slack's mrkdwn reserves.
+var escapeSlack = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// backticks quotes s as inline code.
+func backticks(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "'") + "`"
+}
+
+// payload renders d as the JSON body format's incoming webhooks take.
+func (d digest) payload(format string) interface{} {
+	switch format {
+	case webhookSlack:
+		var text strings.Builder
+		text.WriteString("*" + escapeSlack.Replace(d.title()) + "*")
+		for _, section := range d.sections() {
+			text.WriteString("\n*" + section.Heading + "*")
+			for _, line := range section.Lines {
+				text.WriteString("\n• " + escapeSlack.Replace(line))
+			}
+		}
+		return map[string]string{"text": text.String()}
+	case webhookMattermost:
+		var text strings.Builder
+		text.WriteString("#### " + d.title())
+		for _, section := range d.sections() {
+			text.WriteString("\n**" + section.Heading + "**")
+			for _, line := range section.Lines {
+				text.WriteString("\n- " + line)
+			}
+		}
+		return map[string]string{"text": text.String(), "username": "janitor"}
+	}
+	// teams: an Adaptive Card, which Workflows webhooks require. a TextBlock
+	// only breaks lines on a blank line.
+	body := []map[string]interface{}{{"type": "TextBlock", "text": d.title(), "weight": "Bolder", "size": "Medium", "wrap": true}}
+	for _, section := range d.sections() {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": section.Heading, "weight": "Bolder", "wrap": true})
+		if len(section.Lines) > 0 {
+			body = append(body, map[string]interface{}{"type": "TextBlock", "text": "- " + strings.Join(section.Lines, "\n\n- "), "wrap": true})
+		}
+	}
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// post sends d to hook.
+func (hook webhook) post(ctx context.Context, d digest) error {
+	b, err := json.Marshal(d.payload(hook.Format))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// *url.Error quotes the URL, secret included
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s webhook: %w", hook.Format, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%s webhook answered %s", hook.Format, resp.Status)
+	}
+	return nil
+}
+
+// notifyRun posts the digest of the run ctx carries to every webhook and
+// emails it to the resource owners. mock is the default, so owners only hear
+// about mock runs with --email-mock-runs. a failed post or email is only a
+// warning: it never changes the run's exit code. both are detached from ctx
+// so a run cut short by SIGTERM is still announced. expiring resources are
+// logged as announced once anything was delivered.
+func notifyRun(ctx context.Context, code int) {
+	p := passOf(ctx)
+	mailer := ownerMail
+	if p.run.Mock && !flagEmailMockRuns {
+		mailer = nil
+	}
+	if len(webhooks) == 0 && mailer == nil {
+		return
+	}
+	d := newDigest(&p.report.summary, p.run, code, cancelled(ctx))
+	ctx = context.WithoutCancel(ctx)
+	delivered := false
+	for _, hook := range webhooks {
+		if err := hook.post(ctx, d); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot post run digest: %s\n", err.Error())
+			continue
+		}
+		delivered = true
+	}
+	if mailer != nil {
+		sent, errs := mailer.mailOwners(ctx, d)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Cannot email run digest: %s\n", err.Error())
+		}
+		delivered = delivered || sent > 0
+	}
+	if delivered && len(d.Expiring) > 0 {
+		if err := warnings.remember(d.Expiring, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot save warned resources: %s\n", err.Error())
+		}
+	}
+}
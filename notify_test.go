@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	hooks, err := parseWebhooks(webhookSlack, "https://hooks.slack.com/services/T0/B0/x, http://localhost:8080/hook,")
+	if err != nil {
+		t.Fatalf("parseWebhooks: %v", err)
+	}
+	if len(hooks) != 2 || hooks[1].URL != "http://localhost:8080/hook" || hooks[1].Format != webhookSlack {
+		t.Errorf("unexpected webhooks %+v", hooks)
+	}
+	if hooks, err := parseWebhooks(webhookTeams, ""); err != nil || hooks != nil {
+		t.Errorf("empty value: want no webhooks, got %+v, %v", hooks, err)
+	}
+	_, err = parseWebhooks(webhookTeams, "https://ok.example/hook,ftp://files.example/s3cret")
+	if err == nil || !strings.Contains(err.Error(), "teams webhook 2") {
+		t.Fatalf("want the second webhook rejected by position, got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error leaks the webhook URL: %v", err)
+	}
+}
+
+// webhookReceiver is a local incoming webhook that keeps every payload.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+	status   int
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	rcv.mu.Lock()
+	rcv.payloads = append(rcv.payloads, payload)
+	rcv.mu.Unlock()
+	if rcv.status != 0 {
+		w.WriteHeader(rcv.status)
+	}
+}
+
+// withWebhooks points notifications at one receiver per format and returns
+// them by format.
+func withWebhooks(t *testing.T) map[string]*webhookReceiver {
+	t.Helper()
+	prev := webhooks
+	webhooks = nil
+	receivers := map[string]*webhookReceiver{}
+	for _, format := range []string{webhookSlack, webhookMattermost, webhookTeams} {
+		rcv := &webhookReceiver{}
+		ts := httptest.NewServer(rcv)
+		t.Cleanup(ts.Close)
+		receivers[format] = rcv
+		webhooks = append(webhooks, webhook{Format: format, URL: ts.URL + "/hooks/" + format})
+	}
+	t.Cleanup(func() { webhooks = prev })
+	return receivers
+}
+
+// digestRun reports a small run: one deletion, one failure, one WARN and a
+// quietly kept server.
+func digestRun(t *testing.T, mock bool) {
+	t.Helper()
+	withFlags(t, mock, 0.38, 5.0)
+	prevAction, prevClouds := flagAction, flagClouds
+	flagAction, flagClouds = actionDelete, "aws"
+	t.Cleanup(func() { flagAction, flagClouds = prevAction, prevClouds })
+	captureReport(t, outputText)
+
+	acted := resultDeleted
+	if mock {
+		acted = resultMock
+	}
+	report.listed("aws", "", kindServer)
+	report.add(record{Cloud: "aws", Kind: kindServer, VendorID: "i-old", Name: "demo-box", Region: "us-east-1", AgeDays: 3.5, State: "DEAD", Decision: policyDelete, Result: acted})
+	report.add(record{Cloud: "aws", Kind: kindServer, VendorID: "i-perm", Name: "db", Region: "us-east-1", AgeDays: 90, State: "PERM", Decision: policyKeep, Reason: "permanent", Result: resultSkipped})
+	report.add(record{Cloud: "aws", Kind: kindVolume, VendorID: "vol-1", Name: "scratch", Region: "eu-west-1", AgeDays: 12, State: "DEAD", Decision: policyDelete, Result: resultFailed, Error: "VolumeInUse"})
+	report.add(record{Cloud: "aws", Kind: kindSnapshot, VendorID: "snap-1", Name: "<odd>", Region: "us-east-1", State: stateWarn, Decision: policyKeep, Reason: "unknown age — malformed Created", Result: resultSkipped})
+}
+
+// onlyText is the text of the receiver's only payload.
+func onlyText(t *testing.T, rcv *webhookReceiver) string {
+	t.Helper()
+	if len(rcv.payloads) != 1 {
+		t.Fatalf("want one payload, got %d", len(rcv.payloads))
+	}
+	text, _ := rcv.payloads[0]["text"].(string)
+	return text
+}
+
+func TestNotifyRun_PostsDigestInEveryFormat(t *testing.T) {
+	receivers := withWebhooks(t)
+	digestRun(t, false)
+
+	notifyRun(context.Background(), report.summary.exitCode())
+
+	slack := onlyText(t, receivers[webhookSlack])
+	for _, want := range []string{
+		"*janitor delete on aws: 1 deleted, 1 failed, 1 warnings*",
+		"• aws server: 2 scanned, 1 kept, 1 deleted, 0 mock, 0 marked, 0 failed",
+		"*Acted on*\n• aws server `demo-box` (i-old) us-east-1, 3.50 days: deleted",
+		"*Failed*\n• aws volume `scratch` (vol-1) eu-west-1, 12.00 days: VolumeInUse",
+		"*Warnings*\n• aws snapshot `&lt;odd&gt;` (snap-1) us-east-1, 0.00 days: unknown age — malformed Created",
+	} {
+		if !strings.Contains(slack, want) {
+			t.Errorf("slack digest lacks %q:\n%s", want, slack)
+		}
+	}
+	if strings.Contains(slack, "i-perm") {
+		t.Errorf("quietly kept resources belong in the counts only:\n%s", slack)
+	}
+
+	mattermost := onlyText(t, receivers[webhookMattermost])
+	for _, want := range []string{
+		"#### janitor delete on aws: 1 deleted, 1 failed, 1 warnings",
+		"**Failed**\n- aws volume `scratch` (vol-1) eu-west-1, 12.00 days: VolumeInUse",
+		"`<odd>`",
+	} {
+		if !strings.Contains(mattermost, want) {
+			t.Errorf("mattermost digest lacks %q:\n%s", want, mattermost)
+		}
+	}
+
+	teams := receivers[webhookTeams].payloads
+	if len(teams) != 1 {
+		t.Fatalf("want one teams payload, got %d", len(teams))
+	}
+	card, _ := json.Marshal(teams[0])
+	for _, want := range []string{
+		`"contentType":"application/vnd.microsoft.card.adaptive"`,
+		`"text":"janitor delete on aws: 1 deleted, 1 failed, 1 warnings"`,
+		"demo-box",
+	} {
+		if !strings.Contains(string(card), want) {
+			t.Errorf("teams card lacks %q:\n%s", want, card)
+		}
+	}
+}
+
+func TestNotifyRun_LabelsMockRuns(t *testing.T) {
+	receivers := withWebhooks(t)
+	digestRun(t, true)
+
+	notifyRun(context.Background(), report.summary.exitCode())
+
+	slack := onlyText(t, receivers[webhookSlack])
+	if !strings.HasPrefix(slack, "*[MOCK] janitor delete on aws: 1 would be deleted,") {
+		t.Errorf("want the mock run labelled, got:\n%s", slack)
+	}
+	if !strings.Contains(slack, "demo-box` (i-old) us-east-1, 3.50 days: mock") {
+		t.Errorf("want the mock deletion listed, got:\n%s", slack)
+	}
+}
+
+func TestDigest_CapsLongLists(t *testing.T) {
+	s := &summary{}
+	for i := 0; i < maxDigestItems+3; i++ {
+		s.add(record{Cloud: "hetzner", Kind: kindSnapshot, VendorID: fmt.Sprint(i), Result: resultDeleted})
+	}
+	d := newDigest(s, runOptions{Clouds: "hetzner"}, exitClean, false)
+	acted := d.sections()[1]
+	if len(acted.Lines) != maxDigestItems+1 || acted.Lines[maxDigestItems] != "…and 3 more" {
+		t.Errorf("want %d lines and a tail note, got %d: %q", maxDigestItems+1, len(acted.Lines), acted.Lines[len(acted.Lines)-1])
+	}
+}
+
+func TestWebhookPost_ReportsFailures(t *testing.T) {
+	rcv := &webhookReceiver{status: http.StatusNotFound}
+	ts := httptest.NewServer(rcv)
+	defer ts.Close()
+
+	err := webhook{Format: webhookSlack, URL: ts.URL + "/services/s3cret"}.post(context.Background(), digest{Action: actionDelete})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("want the status reported, got %v", err)
+	}
+
+	// an unreachable webhook must not leak its URL into the logs
+	ts.Close()
+	err = webhook{Format: webhookSlack, URL: ts.URL + "/services/s3cret"}.post(context.Background(), digest{Action: actionDelete})
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("want a connection error without the URL, got %v", err)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cloud66/janitor/core"
+	"go.yaml.in/yaml/v2"
+)
+
+// resource kinds understood by the policy engine. a rule with an empty kind
+// applies to every kind.
+const (
+	kindServer       = "server"
+	kindLoadBalancer = "load_balancer"
+	kindVolume       = "volume"
+	kindSshKey       = "ssh_key"
+	kindSnapshot     = "snapshot"
+	kindIPAddress    = "ip_address"
+	kindTargetGroup  = "target_group"
+)
+
+// policy actions. keep and report never touch the resource; report only
+// differs in the printed verb so operators can grep for it.
+const (
+	policyKeep   = "keep"
+	policyDelete = "delete"
+	policyStop   = "stop"
+	policyReport = "report"
+)
+
+// symbolic age references accepted in place of a number of days. they
+// resolve against --max-age-regular / --max-age-long / --max-age-snapshot at
+// evaluation time so the built-in policy keeps honouring the flags and
+// MAX_AGE_* env vars.
+const (
+	ageRefRegular  = "max-age-regular"
+	ageRefLong     = "max-age-long"
+	ageRefSnapshot = "max-age-snapshot"
+)
+
+// stateUnmatched is printed in the state slot when no rule matched. the
+// resource is kept — an incomplete policy file must never delete by default.
+const stateUnmatched = "NONE"
+
+// stateWarn is the state of resources kept because their age is unknown,
+// usually a malformed Created timestamp. run digests call them out.
+const stateWarn = "WARN"
+
+// policy is an ordered rule list; the first matching rule decides.
+type policy struct {
+	Rules []policyRule `json:"rules" yaml:"rules"`
+}
+
+// policyRule scopes a match to a resource kind / cloud / region and maps it
+// to an action. Reason is printed as "skipped (<reason>)" for keep and may
+// reference {instances} for load balancers. State is the short code shown in
+// the state slot of the output line (PERM, LONG, DEAD, ...).
+type policyRule struct {
+	Kind    string      `json:"kind" yaml:"kind"`
+	Clouds  []string    `json:"clouds,omitempty" yaml:"clouds,omitempty"`
+	Regions []string    `json:"regions,omitempty" yaml:"regions,omitempty"`
+	Match   policyMatch `json:"match" yaml:"match"`
+	Action  string      `json:"action" yaml:"action"`
+	Reason  string      `json:"reason" yaml:"reason"`
+	State   string      `json:"state" yaml:"state"`
+}
+
+// policyMatch holds the predicates of a rule. every field that is set must
+// hold (AND); list fields are any-of except Tags, which is all-of.
+//   - Markers: a name token OR a tag-value token equals the marker, with the
+//     same word-boundary semantics as isPermanent / hasLongName.
+//   - NameTokens: a name token equals one of the entries.
+//   - NamePrefix: case-sensitive name prefix (e.g. "c66-").
+//   - Tags: "key=value" requires that exact tag (case-insensitive); a bare
+//     "key" only requires the key (or an identical bare tag) to be present.
+//   - SampleTag: the hardened C66-STACK sample check (hasSampleTag).
+//   - AgeUnknown: Age <= 0, i.e. Created was missing or malformed.
+//   - OlderThan / YoungerThan: strict Age comparisons in days.
+//   - Instances: load balancers only — "none", "some" or "unknown".
+type policyMatch struct {
+	Markers     []string   `json:"markers,omitempty" yaml:"markers,omitempty"`
+	NameTokens  []string   `json:"name_tokens,omitempty" yaml:"name_tokens,omitempty"`
+	NamePrefix  string     `json:"name_prefix,omitempty" yaml:"name_prefix,omitempty"`
+	Tags        []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	SampleTag   *bool      `json:"sample_tag,omitempty" yaml:"sample_tag,omitempty"`
+	States      []string   `json:"states,omitempty" yaml:"states,omitempty"`
+	Attached    *bool      `json:"attached,omitempty" yaml:"attached,omitempty"`
+	AgeUnknown  *bool      `json:"age_unknown,omitempty" yaml:"age_unknown,omitempty"`
+	OlderThan   *policyAge `json:"older_than,omitempty" yaml:"older_than,omitempty"`
+	YoungerThan *policyAge `json:"younger_than,omitempty" yaml:"younger_than,omitempty"`
+	Instances   string     `json:"instances,omitempty" yaml:"instances,omitempty"`
+}
+
+// policyAge is an age threshold in days: either a literal number or one of
+// the symbolic references (ageRefRegular / ageRefLong / ageRefSnapshot).
+type policyAge struct {
+	Days float64
+	Ref  string
+}
+
+// UnmarshalJSON accepts either a number or a symbolic reference string.
+func (a *policyAge) UnmarshalJSON(b []byte) error {
+	var days float64
+	if err := json.Unmarshal(b, &days); err == nil {
+		*a = policyAge{Days: days}
+		return nil
+	}
+	var ref string
+	if err := json.Unmarshal(b, &ref); err != nil {
+		return fmt.Errorf("age must be a number of days or %q/%q/%q", ageRefRegular, ageRefLong, ageRefSnapshot)
+	}
+	return a.setRef(ref)
+}
+
+// UnmarshalYAML accepts either a number or a symbolic reference string.
+func (a *policyAge) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var days float64
+	if err := unmarshal(&days); err == nil {
+		*a = policyAge{Days: days}
+		return nil
+	}
+	var ref string
+	if err := unmarshal(&ref); err != nil {
+		return fmt.Errorf("age must be a number of days or %q/%q/%q", ageRefRegular, ageRefLong, ageRefSnapshot)
+	}
+	return a.setRef(ref)
+}
+
+func (a *policyAge) setRef(ref string) error {
+	switch ref {
+	case ageRefRegular, ageRefLong, ageRefSnapshot:
+		*a = policyAge{Ref: ref}
+		return nil
+	}
+	// also accept a quoted number ("0.5") — common when templating YAML.
+	days, err := strconv.ParseFloat(ref, 64)
+	if err != nil {
+		return fmt.Errorf("unknown age reference %q (want a number of days, %q, %q or %q)", ref, ageRefRegular, ageRefLong, ageRefSnapshot)
+	}
+	*a = policyAge{Days: days}
+	return nil
+}
+
+// resolve returns the threshold in days for the given limits.
+func (a policyAge) resolve(limits ageLimits) float64 {
+	switch a.Ref {
+	case ageRefRegular:
+		return limits.Normal
+	case ageRefLong:
+		return limits.Long
+	case ageRefSnapshot:
+		return limits.Snapshot
+	}
+	return a.Days
+}
+
+// ageLimits carries the flag-derived thresholds symbolic ages resolve to.
+type ageLimits struct {
+	Normal   float64
+	Long     float64
+	Snapshot float64
+}
+
+// policySubject is the cloud-agnostic view of a resource the policy matches
+// against. InstanceCount is only meaningful for load balancers.
+type policySubject struct {
+	Kind          string
+	Cloud         string
+	Region        string
+	Name          string
+	Tags          []string
+	State         string
+	Age           float64
+	Attached      bool
+	InstanceCount int
+}
+
+// decision is the outcome of evaluating a subject against a policy.
+type decision struct {
+	Action string
+	Reason string
+	State  string
+}
+
+// evaluate returns the decision of the first rule matching s. when nothing
+// matches the resource is kept.
+func (p policy) evaluate(s policySubject, limits ageLimits) decision {
+	for _, rule := range p.Rules {
+		if !rule.matches(s, limits) {
+			continue
+		}
+		reason := strings.ReplaceAll(rule.Reason, "{instances}", strconv.Itoa(s.InstanceCount))
+		return decision{Action: rule.Action, Reason: reason, State: rule.State}
+	}
+	return decision{Action: policyKeep, Reason: "no policy rule matched", State: stateUnmatched}
+}
+
+func (r policyRule) matches(s policySubject, limits ageLimits) bool {
+	if r.Kind != "" && r.Kind != s.Kind {
+		return false
+	}
+	if len(r.Clouds) > 0 && !containsFold(r.Clouds, s.Cloud) {
+		return false
+	}
+	if len(r.Regions) > 0 && !containsFold(r.Regions, s.Region) {
+		return false
+	}
+	m := r.Match
+	if len(m.Markers) > 0 {
+		found := false
+		for _, marker := range m.Markers {
+			marker = strings.ToLower(marker)
+			if nameMatchesToken(s.Name, marker) || tagValueMatchesToken(s.Tags, marker) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(m.NameTokens) > 0 {
+		found := false
+		for _, token := range m.NameTokens {
+			if nameMatchesToken(s.Name, strings.ToLower(token)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if m.NamePrefix != "" && !strings.HasPrefix(s.Name, m.NamePrefix) {
+		return false
+	}
+	for _, want := range m.Tags {
+		if !hasTag(s.Tags, want) {
+			return false
+		}
+	}
+	if m.SampleTag != nil && hasSampleTag(s.Tags) != *m.SampleTag {
+		return false
+	}
+	if len(m.States) > 0 && !containsFold(m.States, s.State) {
+		return false
+	}
+	if m.Attached != nil && s.Attached != *m.Attached {
+		return false
+	}
+	if m.AgeUnknown != nil && (s.Age <= 0) != *m.AgeUnknown {
+		return false
+	}
+	if m.OlderThan != nil && !(s.Age > m.OlderThan.resolve(limits)) {
+		return false
+	}
+	if m.YoungerThan != nil && !(s.Age < m.YoungerThan.resolve(limits)) {
+		return false
+	}
+	switch m.Instances {
+	case "none":
+		if s.InstanceCount != 0 {
+			return false
+		}
+	case "some":
+		if s.InstanceCount <= 0 {
+			return false
+		}
+	case "unknown":
+		if s.InstanceCount >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTag reports whether tags contains want. want is either "key=value"
+// (both halves compared case-insensitively) or a bare "key", which matches a
+// key=anything tag as well as a bare tag (DigitalOcean tags have no value).
+// keys are stripped of whitespace / zero-width characters like hasSampleTag.
+func hasTag(tags []string, want string) bool {
+	wantKey, wantValue, hasValue := strings.Cut(want, "=")
+	wantKey = strings.ToLower(stripInvisibleAndSpace(wantKey))
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok && hasValue {
+			continue
+		}
+		if strings.ToLower(stripInvisibleAndSpace(key)) != wantKey {
+			continue
+		}
+		if !hasValue || strings.EqualFold(value, wantValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate rejects rules that could never be applied safely: unknown kinds
+// and actions, stop on anything but servers, and instance predicates outside
+// load balancers.
+func (p policy) validate() error {
+	if len(p.Rules) == 0 {
+		return errors.New("policy has no rules")
+	}
+	for i, rule := range p.Rules {
+		switch rule.Kind {
+		case "", kindServer, kindLoadBalancer, kindVolume, kindSshKey, kindSnapshot, kindIPAddress, kindTargetGroup:
+		default:
+			return fmt.Errorf("rule %d: unknown kind %q", i+1, rule.Kind)
+		}
+		switch rule.Action {
+		case policyKeep, policyDelete, policyReport:
+		case policyStop:
+			if rule.Kind != kindServer {
+				return fmt.Errorf("rule %d: action %q is only valid for kind %q", i+1, rule.Action, kindServer)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown action %q (want keep|delete|stop|report)", i+1, rule.Action)
+		}
+		switch rule.Match.Instances {
+		case "":
+		case "none", "some", "unknown":
+			if rule.Kind != kindLoadBalancer {
+				return fmt.Errorf("rule %d: instances is only valid for kind %q", i+1, kindLoadBalancer)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown instances value %q (want none|some|unknown)", i+1, rule.Match.Instances)
+		}
+		if rule.Reason == "" {
+			return fmt.Errorf("rule %d: reason is required", i+1)
+		}
+	}
+	return nil
+}
+
+// loadPolicy reads a policy file. ".json" files are decoded as JSON, anything
+// else as YAML. unknown fields are rejected in both formats so a typo such as
+// `older_then` fails loudly instead of silently widening a delete rule.
+func loadPolicy(path string) (*policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p policy
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	} else if err := yaml.UnmarshalStrict(b, &p); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// defaultPolicy is the built-in policy used when --policy is not given. it
+// encodes the historical hard-coded classification of deleteServers,
+// deleteLoadBalancers, deleteVolumes and deleteSshKeys rule for rule, so
+// output and behaviour are unchanged for existing users.
+func defaultPolicy() policy {
+	regular := &policyAge{Ref: ageRefRegular}
+	long := &policyAge{Ref: ageRefLong}
+	snapshot := &policyAge{Ref: ageRefSnapshot}
+	// load balancers and volumes get a 1 hour grace period so resources
+	// that have not been attached yet are not reaped mid-provisioning.
+	oneHour := &policyAge{Days: 1.0 / 24.0}
+
+	return policy{Rules: []policyRule{
+		// servers
+		{Kind: kindServer, Match: policyMatch{SampleTag: boolPtr(true)}, Action: policyKeep, Reason: "sample tag", State: "SMPL"},
+		{Kind: kindServer, Match: policyMatch{Markers: []string{core.TagPermanent}}, Action: policyKeep, Reason: "permanent", State: "PERM"},
+		// Age<=0 means Created was missing/malformed — never let an age
+		// predicate decide deletion based on a fabricated age (B10).
+		{Kind: kindServer, Match: policyMatch{AgeUnknown: boolPtr(true)}, Action: policyKeep, Reason: "unknown age — malformed Created", State: stateWarn},
+		{Kind: kindServer, Match: policyMatch{Markers: []string{core.TagLong}, OlderThan: long}, Action: policyDelete, Reason: "age", State: "LONG"},
+		{Kind: kindServer, Match: policyMatch{Markers: []string{core.TagLong}}, Action: policyKeep, Reason: "age", State: "LONG"},
+		{Kind: kindServer, Match: policyMatch{OlderThan: regular}, Action: policyDelete, Reason: "age", State: "NORM"},
+		{Kind: kindServer, Action: policyKeep, Reason: "age", State: "NORM"},
+
+		// load balancers
+		{Kind: kindLoadBalancer, Match: policyMatch{Markers: []string{core.TagPermanent}}, Action: policyKeep, Reason: "permanent", State: "PERM"},
+		{Kind: kindLoadBalancer, Match: policyMatch{AgeUnknown: boolPtr(true)}, Action: policyKeep, Reason: "unknown age — malformed Created", State: stateWarn},
+		// instance count unknown (health check failed) — skip to be safe
+		{Kind: kindLoadBalancer, Match: policyMatch{Instances: "unknown"}, Action: policyKeep, Reason: "instance count unknown", State: " N/A"},
+		{Kind: kindLoadBalancer, Match: policyMatch{Instances: "some"}, Action: policyKeep, Reason: "has {instances} instances", State: "LIVE"},
+		{Kind: kindLoadBalancer, Match: policyMatch{YoungerThan: oneHour}, Action: policyKeep, Reason: "less than 1 hour old", State: " NEW"},
+		{Kind: kindLoadBalancer, Action: policyDelete, Reason: "no instances", State: "DEAD"},
+
+		// volumes
+		{Kind: kindVolume, Match: policyMatch{Markers: []string{core.TagPermanent}}, Action: policyKeep, Reason: "permanent", State: "PERM"},
+		// sample-stack volumes must be spared along with their owning
+		// servers (panel finding A#8).
+		{Kind: kindVolume, Match: policyMatch{SampleTag: boolPtr(true)}, Action: policyKeep, Reason: "sample tag", State: "SMPL"},
+		{Kind: kindVolume, Match: policyMatch{AgeUnknown: boolPtr(true)}, Action: policyKeep, Reason: "unknown age — malformed Created", State: stateWarn},
+		{Kind: kindVolume, Match: policyMatch{Attached: boolPtr(true)}, Action: policyKeep, Reason: "attached to instance", State: "LIVE"},
+		{Kind: kindVolume, Match: policyMatch{YoungerThan: oneHour}, Action: policyKeep, Reason: "too new", State: " NEW"},
+		{Kind: kindVolume, Action: policyDelete, Reason: "unattached", State: "DEAD"},
+
+		// ssh keys — only janitor-created c66-* keys are candidates; the
+		// keep-last-N window is applied on top by deleteSshKeys. a permanent
+		// name token or label spares a key the same way it spares a server.
+		{Kind: kindSshKey, Match: policyMatch{Markers: []string{core.TagPermanent}}, Action: policyKeep, Reason: "permanent", State: "PERM"},
+		{Kind: kindSshKey, Match: policyMatch{NamePrefix: "c66-"}, Action: policyDelete, Reason: "name", State: "C66K"},
+		{Kind: kindSshKey, Action: policyKeep, Reason: "name", State: "USER"},
+
+		// snapshots and images are backups: they are held to
+		// --max-age-snapshot, long tag or not. ones still referenced by a
+		// registered image never reach the policy — see deleteSnapshots.
+		{Kind: kindSnapshot, Match: policyMatch{SampleTag: boolPtr(true)}, Action: policyKeep, Reason: "sample tag", State: "SMPL"},
+		{Kind: kindSnapshot, Match: policyMatch{Markers: []string{core.TagPermanent}}, Action: policyKeep, Reason: "permanent", State: "PERM"},
+		{Kind: kindSnapshot, Match: policyMatch{AgeUnknown: boolPtr(true)}, Action: policyKeep, Reason: "unknown age — malformed Created", State: stateWarn},
+		{Kind: kindSnapshot, Match: policyMatch{OlderThan: snapshot}, Action: policyDelete, Reason: "age", State: "NORM"},
+		{Kind: kindSnapshot, Action: policyKeep, Reason: "age", State: "NORM"},
+
+		// ip addresses — only unassigned ones past --max-age-regular are
+		// released. AWS, DigitalOcean and Vultr report no allocation time, so
+		// their addresses are aged from the state store's first sighting and
+		// stay WARN until there is one (or without --state-file).
+		{Kind: kindIPAddress, Match: policyMatch{Markers: []string{core.TagPermanent}}, Action: policyKeep, Reason: "permanent", State: "PERM"},
+		{Kind: kindIPAddress, Match: policyMatch{SampleTag: boolPtr(true)}, Action: policyKeep, Reason: "sample tag", State: "SMPL"},
+		{Kind: kindIPAddress, Match: policyMatch{Attached: boolPtr(true)}, Action: policyKeep, Reason: "assigned", State: "LIVE"},
+		{Kind: kindIPAddress, Match: policyMatch{AgeUnknown: boolPtr(true)}, Action: policyKeep, Reason: "unknown age", State: stateWarn},
+		{Kind: kindIPAddress, Match: policyMatch{OlderThan: regular}, Action: policyDelete, Reason: "unassigned", State: "DEAD"},
+		{Kind: kindIPAddress, Action: policyKeep, Reason: "too new", State: " NEW"},
+
+		// target groups — only ones no load balancer references are listed.
+		// they have no age: deleteTargetGroups deletes through the signed
+		// deletion mark alone.
+		{Kind: kindTargetGroup, Match: policyMatch{Markers: []string{core.TagPermanent}}, Action: policyKeep, Reason: "permanent", State: "PERM"},
+		{Kind: kindTargetGroup, Match: policyMatch{SampleTag: boolPtr(true)}, Action: policyKeep, Reason: "sample tag", State: "SMPL"},
+		{Kind: kindTargetGroup, Action: policyDelete, Reason: "orphaned", State: "DEAD"},
+	}}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// withPolicy installs p as the loaded --policy file for the duration of the
+// test and restores the previous value (usually nil → built-in default).
+func withPolicy(t *testing.T, p *policy) {
+	t.Helper()
+	prev := loadedPolicy
+	loadedPolicy = p
+	t.Cleanup(func() { loadedPolicy = prev })
+}
+
+// writePolicyFile drops body into a temp file with the given name.
+func writePolicyFile(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("writing policy: %v", err)
+	}
+	return path
+}
+
+func TestLoadPolicy_YAMLAndJSON(t *testing.T) {
+	t.Parallel()
+	yamlPath := writePolicyFile(t, "janitor.yaml", `
+rules:
+  - kind: server
+    clouds: [hetzner]
+    match:
+      tags: ["team=qa"]
+      older_than: max-age-long
+    action: stop
+    reason: qa overnight
+    state: STOP
+  - kind: volume
+    match:
+      attached: false
+      older_than: 2
+    action: delete
+    reason: orphaned
+    state: DEAD
+`)
+	jsonPath := writePolicyFile(t, "janitor.json", `{"rules":[
+		{"kind":"load_balancer","match":{"instances":"none","older_than":"0.5"},"action":"report","reason":"empty","state":"DEAD"}
+	]}`)
+
+	p, err := loadPolicy(yamlPath)
+	if err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	if len(p.Rules) != 2 {
+		t.Fatalf("yaml: want 2 rules, got %d", len(p.Rules))
+	}
+	if got := p.Rules[0].Match.OlderThan; got == nil || got.Ref != ageRefLong {
+		t.Errorf("yaml: want symbolic %q age, got %+v", ageRefLong, got)
+	}
+	if got := p.Rules[1].Match.OlderThan; got == nil || got.Days != 2 {
+		t.Errorf("yaml: want literal 2 day age, got %+v", got)
+	}
+
+	p, err = loadPolicy(jsonPath)
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if got := p.Rules[0].Match.OlderThan; got == nil || got.Days != 0.5 {
+		t.Errorf("json: quoted number should parse as 0.5 days, got %+v", got)
+	}
+}
+
+func TestLoadPolicy_Rejects(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc    string
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"empty rule list", "p.yaml", "rules: []\n", "no rules"},
+		{"unknown action", "p.yaml", "rules:\n  - kind: server\n    action: nuke\n    reason: x\n", "unknown action"},
+		{"stop on volume", "p.yaml", "rules:\n  - kind: volume\n    action: stop\n    reason: x\n", "only valid for kind"},
+		{"instances on server", "p.yaml", "rules:\n  - kind: server\n    match: {instances: none}\n    action: keep\n    reason: x\n", "instances is only valid"},
+		{"unknown kind", "p.yaml", "rules:\n  - kind: bucket\n    action: keep\n    reason: x\n", "unknown kind"},
+		{"missing reason", "p.yaml", "rules:\n  - kind: server\n    action: keep\n", "reason is required"},
+		// typo'd predicate must not silently widen a delete rule
+		{"unknown yaml field", "p.yaml", "rules:\n  - kind: server\n    match: {older_then: 1}\n    action: delete\n    reason: x\n", "older_then"},
+		{"unknown json field", "p.json", `{"rules":[{"kind":"server","match":{"older_then":1},"action":"delete","reason":"x"}]}`, "older_then"},
+		{"bad age reference", "p.yaml", "rules:\n  - kind: server\n    match: {older_than: forever}\n    action: delete\n    reason: x\n", "forever"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := loadPolicy(writePolicyFile(t, tt.name, tt.body))
+			if err == nil {
+				t.Fatalf("want error containing %q, got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Evaluate_Scoping(t *testing.T) {
+	t.Parallel()
+	p := policy{Rules: []policyRule{
+		{Kind: kindServer, Clouds: []string{"aws"}, Regions: []string{"eu-west-1"}, Action: policyDelete, Reason: "eu sweep", State: "EU"},
+		{Kind: kindServer, Match: policyMatch{Tags: []string{"Team=QA"}}, Action: policyStop, Reason: "qa", State: "QA"},
+		{Kind: kindServer, Match: policyMatch{NameTokens: []string{"ci"}, States: []string{"running"}}, Action: policyReport, Reason: "ci", State: "CI"},
+	}}
+	limits := ageLimits{Normal: 0.38, Long: 5}
+	tests := []struct {
+		desc      string
+		subject   policySubject
+		wantState string
+	}{
+		{"cloud and region match", policySubject{Kind: kindServer, Cloud: "aws", Region: "eu-west-1"}, "EU"},
+		{"region mismatch falls through", policySubject{Kind: kindServer, Cloud: "aws", Region: "us-east-1", Tags: []string{"team=qa"}}, "QA"},
+		{"tag key/value case-insensitive", policySubject{Kind: kindServer, Cloud: "hetzner", Tags: []string{"TEAM=qa"}}, "QA"},
+		{"bare tag key does not match key=value rule", policySubject{Kind: kindServer, Cloud: "hetzner", Tags: []string{"team"}}, stateUnmatched},
+		{"name token and state", policySubject{Kind: kindServer, Name: "my-ci-box", State: "RUNNING"}, "CI"},
+		{"state mismatch", policySubject{Kind: kindServer, Name: "my-ci-box", State: "STOPPED"}, stateUnmatched},
+		{"kind mismatch", policySubject{Kind: kindVolume, Cloud: "aws", Region: "eu-west-1"}, stateUnmatched},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+			d := p.evaluate(tt.subject, limits)
+			if d.State != tt.wantState {
+				t.Errorf("state: want %q, got %q (%+v)", tt.wantState, d.State, d)
+			}
+			// nothing matched → must be kept, never deleted
+			if tt.wantState == stateUnmatched && d.Action != policyKeep {
+				t.Errorf("unmatched subject must be kept, got action %q", d.Action)
+			}
+		})
+	}
+}
+
+// TestDefaultPolicy_SymbolicAgesFollowFlags pins that the built-in policy
+// resolves its thresholds at evaluation time, so --max-age-* and MAX_AGE_*
+// keep working without a policy file.
+func TestDefaultPolicy_SymbolicAgesFollowFlags(t *testing.T) {
+	t.Parallel()
+	p := defaultPolicy()
+	s := policySubject{Kind: kindServer, Name: "my-server", Age: 1.0}
+	if d := p.evaluate(s, ageLimits{Normal: 0.5, Long: 5}); d.Action != policyDelete {
+		t.Errorf("age 1.0 > regular 0.5: want delete, got %+v", d)
+	}
+	if d := p.evaluate(s, ageLimits{Normal: 2, Long: 5}); d.Action != policyKeep || d.Reason != "age" {
+		t.Errorf("age 1.0 < regular 2: want keep (age), got %+v", d)
+	}
+	// snapshots answer to --max-age-snapshot alone, long tag or not
+	limits := ageLimits{Normal: 0.38, Long: 5, Snapshot: 30}
+	for _, snap := range []policySubject{
+		{Kind: kindSnapshot, Name: "weekly", Age: 7},
+		{Kind: kindSnapshot, Name: "soak", Age: 7, Tags: []string{"lifecycle=long"}},
+	} {
+		if d := p.evaluate(snap, limits); d.Action != policyKeep {
+			t.Errorf("%s: age 7 < snapshot 30: want keep, got %+v", snap.Name, d)
+		}
+		snap.Age = 31
+		if d := p.evaluate(snap, limits); d.Action != policyDelete {
+			t.Errorf("%s: age 31 > snapshot 30: want delete, got %+v", snap.Name, d)
+		}
+	}
+	var age policyAge
+	if err := age.setRef(ageRefSnapshot); err != nil || age.resolve(limits) != 30 {
+		t.Errorf("want %q to resolve to the snapshot limit, got %+v (%v)", ageRefSnapshot, age, err)
+	}
+	lb := policySubject{Kind: kindLoadBalancer, Name: "lb", Age: 2, InstanceCount: 4}
+	if d := p.evaluate(lb, ageLimits{}); d.Reason != "has 4 instances" {
+		t.Errorf("want {instances} expanded, got %q", d.Reason)
+	}
+}
+
+func TestDeleteServers_PolicyFileStopAndReport(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	withPolicy(t, &policy{Rules: []policyRule{
+		{Kind: kindServer, Clouds: []string{"hetzner"}, Match: policyMatch{OlderThan: &policyAge{Ref: ageRefRegular}}, Action: policyStop, Reason: "overnight", State: "STOP"},
+		{Kind: kindServer, Action: policyReport, Reason: "audit", State: "AUDT"},
+	}})
+
+	got := captureOutput(t, func() {
+		deleteServers(nil, "hetzner", []core.Server{{Name: "qa-box", Age: 1, Region: "fsn1"}})
+		deleteServers(nil, "aws", []core.Server{{Name: "qa-box", Age: 1, Region: "us-east-1"}})
+	})
+	if !strings.Contains(got, "] [STOP] [") || !strings.Contains(got, "Mock stopped!") {
+		t.Errorf("expected hetzner server to be mock-stopped, got %q", got)
+	}
+	if !strings.Contains(got, "] [AUDT] [") || !strings.Contains(got, "reported (audit)") {
+		t.Errorf("expected aws server to be reported, got %q", got)
+	}
+	if strings.Contains(got, "Mock deleted!") {
+		t.Errorf("no delete rule matched, got %q", got)
+	}
+}
+
+func TestDeleteVolumes_PolicyFileScopedByCloud(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withPolicy(t, &policy{Rules: []policyRule{
+		{Kind: kindVolume, Clouds: []string{"vultr"}, Match: policyMatch{Attached: boolPtr(false)}, Action: policyDelete, Reason: "orphan", State: "DEAD"},
+	}})
+	volumes := []core.Volume{{Name: "orphan", Age: 2, Region: "ewr", Attached: false}}
+
+	for _, tc := range []struct {
+		cloud       string
+		wantDeletes int
+	}{{"vultr", 1}, {"hetzner", 0}} {
+		fe := &fakeExecutor{}
+		ctx := context.WithValue(ctxWithExec(fe), core.CloudKey, tc.cloud)
+		got := captureOutput(t, func() { deleteVolumes(ctx, volumes) })
+		if len(fe.deletedVolumes) != tc.wantDeletes {
+			t.Errorf("%s: want %d deletes, got %d (output %q)", tc.cloud, tc.wantDeletes, len(fe.deletedVolumes), got)
+		}
+	}
+}
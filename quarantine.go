@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// decisionQuarantine is the record decision for a resource that was marked
+// this run instead of deleted.
+const decisionQuarantine = "quarantine"
+
+// minMarkSigningKeyLen rejects keys too short to make the HMAC meaningful.
+const minMarkSigningKeyLen = 16
+
+// markSigningKey is the HMAC key read from --mark-signing-key-file. only set
+// when --quarantine is on.
+var markSigningKey []byte
+
+// readMarkSigningKey loads the quarantine HMAC key. surrounding whitespace
+// (the trailing newline of `openssl rand -hex 32 > key`) is not part of it.
+func readMarkSigningKey(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("--quarantine requires --mark-signing-key-file")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(strings.TrimSpace(string(raw)))
+	if len(key) < minMarkSigningKeyLen {
+		return nil, fmt.Errorf("mark signing key in %s is %d bytes; need at least %d", path, len(key), minMarkSigningKeyLen)
+	}
+	return key, nil
+}
+
+// quarantineGrace is --quarantine-grace as a duration.
+func quarantineGrace() time.Duration {
+	return time.Duration(flagQuarantineGrace * 24 * float64(time.Hour))
+}
+
+// quarantineGate runs in place of an immediate delete under --quarantine. it
+// returns true only when the resource carries a verified mark older than the
+// grace window, in which case the caller deletes it. otherwise it has marked
+// the resource (first sighting, or a mark that failed verification) or
+// skipped it, and has printed and reported the outcome itself.
+//
+// removing the mark objects to the deletion: a resource the state store
+// remembers janitor marking that no longer carries a mark is kept rather
+// than re-marked. main refuses marks without a persistent --state-file, as
+// a removed mark would otherwise look like a first sighting.
+func quarantineGate(ctx context.Context, rec record, tags []string, mark func(value string) error) bool {
+	p := passOf(ctx)
+	if rec.Account == "" {
+		rec.Account = p.account
+	}
+	now := time.Now()
+	var markedAt time.Time
+	for _, value := range core.FindMarks(tags) {
+		at, err := core.VerifyMark(markSigningKey, rec.Kind, rec.VendorID, value, now)
+		if err != nil {
+			core.Warnf(ctx, "ignoring deletion mark on %s %q: %v", rec.Kind, rec.Name, err)
+			continue
+		}
+		// with several valid marks honour the newest: the longer wait.
+		if at.After(markedAt) {
+			markedAt = at
+		}
+	}
+
+	if !markedAt.IsZero() {
+		due := markedAt.Add(quarantineGrace())
+		if !now.Before(due) {
+			return true
+		}
+		reason := fmt.Sprintf("quarantined until %s", due.UTC().Format(time.RFC3339))
+		_, _ = fmt.Fprintf(p.out, "skipped (%s)\n", reason)
+		rec.Decision, rec.Reason = policyKeep, reason
+		reportSkipped(ctx, rec)
+		return false
+	}
+
+	if len(core.FindMarks(tags)) == 0 && markRemoved(ctx, rec) {
+		_, _ = fmt.Fprintf(p.out, "skipped (mark removed)\n")
+		rec.Decision, rec.Reason = policyKeep, "mark removed"
+		reportSkipped(ctx, rec)
+		return false
+	}
+
+	rec.Decision = decisionQuarantine
+	if p.run.Mock {
+		_, _ = fmt.Fprintf(p.out, "Mock marked!\n")
+		finish(ctx, rec, resultMock, nil)
+		return false
+	}
+	err := mark(core.SignMark(markSigningKey, rec.Kind, rec.VendorID, now))
+	if errors.Is(err, core.ErrUnsupported) {
+		// no way to record the grace window → never delete outright.
+		_, _ = fmt.Fprintf(p.out, "skipped (marking unsupported)\n")
+		rec.Decision, rec.Reason = policyKeep, "marking unsupported"
+		reportSkipped(ctx, rec)
+		return false
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(p.out, "ERROR: %s\n", err.Error())
+	} else {
+		_, _ = fmt.Fprintf(p.out, "Marked for deletion!\n")
+	}
+	finish(ctx, rec, resultMarked, err)
+	return false
+}
+
+// markRemoved reports whether the state store remembers janitor marking the
+// resource of rec. the caller has seen no mark on it, so someone took it off.
+func markRemoved(ctx context.Context, rec record) bool {
+	if state == nil {
+		return false
+	}
+	s, ok, err := state.Get(keyOf(rec))
+	if err != nil {
+		core.Warnf(ctx, "cannot read the state of %s %q: %v", rec.Kind, rec.Name, err)
+		return false
+	}
+	return ok && s.MarkedAt != nil
+}
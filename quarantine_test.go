@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+)
+
+var testMarkKey = []byte("test-signing-key-0123456789")
+
+// withQuarantine turns on --quarantine with the given grace (days) and the
+// test signing key, restoring the previous settings afterwards.
+func withQuarantine(t *testing.T, graceDays float64) {
+	t.Helper()
+	prevOn, prevGrace, prevKey := flagQuarantine, flagQuarantineGrace, markSigningKey
+	flagQuarantine, flagQuarantineGrace, markSigningKey = true, graceDays, testMarkKey
+	t.Cleanup(func() {
+		flagQuarantine, flagQuarantineGrace, markSigningKey = prevOn, prevGrace, prevKey
+	})
+}
+
+// markTag is a mark tag on volume id signed at `at` with key.
+func markTag(key []byte, id string, at time.Time) string {
+	return core.MarkTagKey + "=" + core.SignMark(key, kindVolume, id, at)
+}
+
+func TestQuarantine_Volumes(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		desc        string
+		tags        []string
+		wantDeletes int
+		wantMarked  bool
+		wantOutput  string
+	}{
+		{"first sighting is marked, not deleted", nil, 0, true, "Marked for deletion!"},
+		{"mark inside grace is skipped", []string{markTag(testMarkKey, "v1", now.Add(-12*time.Hour))}, 0, false, "skipped (quarantined until "},
+		{"mark past grace is deleted", []string{markTag(testMarkKey, "v1", now.Add(-25*time.Hour))}, 1, false, "Deleted!"},
+		// someone copied an old-looking mark or forged one with another key:
+		// it must not shortcut the grace window.
+		{"forged mark is re-marked", []string{markTag([]byte("attacker-chosen-key-000"), "v1", now.Add(-30*24*time.Hour))}, 0, true, "Marked for deletion!"},
+		{"mark from another volume is re-marked", []string{markTag(testMarkKey, "v2", now.Add(-30*24*time.Hour))}, 0, true, "Marked for deletion!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			withFlags(t, false, 0.38, 5.0)
+			withQuarantine(t, 1)
+			fe := &fakeExecutor{}
+			volumes := []core.Volume{{VendorID: "v1", Name: "orphan", Age: 3, Region: "fsn1", Tags: tt.tags}}
+
+			got := captureOutput(t, func() { deleteVolumes(ctxWithExec(fe), volumes) })
+			if len(fe.deletedVolumes) != tt.wantDeletes {
+				t.Errorf("want %d deletes, got %d (output %q)", tt.wantDeletes, len(fe.deletedVolumes), got)
+			}
+			if !strings.Contains(got, tt.wantOutput) {
+				t.Errorf("output missing %q, got %q", tt.wantOutput, got)
+			}
+			if !tt.wantMarked {
+				if len(fe.marks) != 0 {
+					t.Errorf("want no mark, got %v", fe.marks)
+				}
+				return
+			}
+			if len(fe.marks) != 1 {
+				t.Fatalf("want exactly one mark, got %v", fe.marks)
+			}
+			id, value, _ := strings.Cut(fe.marks[0], "=")
+			if _, err := core.VerifyMark(testMarkKey, kindVolume, id, value, time.Now()); err != nil || id != "v1" {
+				t.Errorf("applied mark %q does not verify for v1: %v", fe.marks[0], err)
+			}
+		})
+	}
+}
+
+func TestQuarantine_MarkingUnsupportedNeverDeletes(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withQuarantine(t, 0)
+	fe := &fakeExecutor{markErr: core.ErrUnsupported}
+	lbs := []core.LoadBalancer{{Name: "empty", LoadBalancerArn: "lb-1", Age: 3, Region: "ewr", Type: "vultr"}}
+
+	got := captureOutput(t, func() { deleteLoadBalancers(ctxWithExec(fe), lbs) })
+	if len(fe.deletedLBs) != 0 {
+		t.Errorf("an unmarkable LB must not be deleted, got %d deletes", len(fe.deletedLBs))
+	}
+	if !strings.Contains(got, "skipped (marking unsupported)") {
+		t.Errorf("want unsupported skip, got %q", got)
+	}
+}
+
+func TestQuarantine_MarkErrorIsReported(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withQuarantine(t, 1)
+	buf := captureReport(t, outputNDJSON)
+	fe := &fakeExecutor{markErr: errors.New("label quota exceeded")}
+
+	captureOutput(t, func() {
+		deleteVolumes(ctxWithExec(fe), []core.Volume{{VendorID: "v1", Name: "orphan", Age: 3}})
+	})
+	records := decodeNDJSON(t, buf)
+	if len(records) != 1 || records[0].Decision != decisionQuarantine || records[0].Result != resultFailed || records[0].Error != "label quota exceeded" {
+		t.Errorf("want failed quarantine record, got %+v", records)
+	}
+}
+
+func TestQuarantine_MockDoesNotMark(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	withQuarantine(t, 1)
+	fe := &fakeExecutor{}
+
+	got := captureOutput(t, func() {
+		deleteVolumes(ctxWithExec(fe), []core.Volume{{VendorID: "v1", Name: "orphan", Age: 3}})
+	})
+	if len(fe.marks) != 0 || !strings.Contains(got, "Mock marked!") {
+		t.Errorf("mock must only print, got marks=%v output=%q", fe.marks, got)
+	}
+}
+
+func TestQuarantine_RemovedMarkIsKept(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withQuarantine(t, 1)
+	store, _ := openStateStore(stateMemory)
+	withState(t, store)
+	buf := captureReport(t, outputNDJSON)
+	fe := &fakeExecutor{}
+	volume := core.Volume{VendorID: "v1", Name: "orphan", Age: 3, Region: "fsn1"}
+
+	// first run marks v1; someone then deletes the mark tag
+	captureOutput(t, func() { deleteVolumes(ctxWithExec(fe), []core.Volume{volume}) })
+	if len(fe.marks) != 1 {
+		t.Fatalf("want v1 marked on the first run, got %v", fe.marks)
+	}
+	got := captureOutput(t, func() { deleteVolumes(ctxWithExec(fe), []core.Volume{volume}) })
+
+	if len(fe.marks) != 1 || len(fe.deletedVolumes) != 0 {
+		t.Errorf("want v1 neither re-marked nor deleted, got marks=%v deletes=%d", fe.marks, len(fe.deletedVolumes))
+	}
+	if !strings.Contains(got, "skipped (mark removed)") {
+		t.Errorf("want the removed mark called out, got %q", got)
+	}
+	records := decodeNDJSON(t, buf)
+	if len(records) != 2 || records[1].Decision != policyKeep || records[1].Reason != "mark removed" {
+		t.Errorf("want the second run to keep v1, got %+v", records)
+	}
+
+	// a mark that fails verification is not a removal: it is replaced
+	volume.Tags = []string{markTag([]byte("attacker-chosen-key-000"), "v1", time.Now())}
+	captureOutput(t, func() { deleteVolumes(ctxWithExec(fe), []core.Volume{volume}) })
+	if len(fe.marks) != 2 {
+		t.Errorf("want an invalid mark replaced, got %v", fe.marks)
+	}
+}
+
+// TestQuarantine_ServerMarkUsesServerKind pins that marks are bound to the
+// resource kind — a server mark can't be replayed onto a volume with the
+// same ID.
+func TestQuarantine_ServerMarkUsesServerKind(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withQuarantine(t, 1)
+	fe := &fakeExecutor{}
+	ctx := context.WithValue(ctxWithExec(fe), core.CloudKey, "hetzner")
+
+	captureOutput(t, func() {
+		deleteServers(ctx, "hetzner", []core.Server{{VendorID: "42", Name: "old", Age: 2}})
+	})
+	if len(fe.marks) != 1 {
+		t.Fatalf("want one mark, got %v", fe.marks)
+	}
+	_, value, _ := strings.Cut(fe.marks[0], "=")
+	if _, err := core.VerifyMark(testMarkKey, kindServer, "42", value, time.Now()); err != nil {
+		t.Errorf("server mark must verify as kind server: %v", err)
+	}
+	if _, err := core.VerifyMark(testMarkKey, kindVolume, "42", value, time.Now()); err == nil {
+		t.Error("server mark must not verify as a volume mark")
+	}
+}
+
+// TestTargetGroups_AlwaysGoThroughTheMark pins that orphan target groups,
+// which have no age, are only deleted through a verified mark past the grace
+// window, --quarantine or not, and never without a signing key.
+func TestTargetGroups_AlwaysGoThroughTheMark(t *testing.T) {
+	now := time.Now()
+	tgMark := func(at time.Time) string {
+		return core.MarkTagKey + "=" + core.SignMark(testMarkKey, kindTargetGroup, "arn:tg/orphan", at)
+	}
+	tests := []struct {
+		desc        string
+		key         []byte
+		tags        []string
+		wantDeletes int
+		wantMarked  bool
+		wantOutput  string
+	}{
+		{"no signing key keeps it", nil, []string{tgMark(now.Add(-30 * 24 * time.Hour))}, 0, false, "skipped (no mark signing key)"},
+		{"first sighting is marked", testMarkKey, nil, 0, true, "Marked for deletion!"},
+		{"mark inside grace is skipped", testMarkKey, []string{tgMark(now.Add(-time.Hour))}, 0, false, "skipped (quarantined until "},
+		{"mark past grace is deleted", testMarkKey, []string{tgMark(now.Add(-25 * time.Hour))}, 1, false, "Deleted!"},
+		{"permanent is kept", testMarkKey, []string{"lifecycle=permanent"}, 0, false, "skipped (permanent)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			withFlags(t, false, 0.38, 5.0)
+			withQuarantine(t, 1)
+			// a key file alone enables target group deletion
+			flagQuarantine, markSigningKey = false, tt.key
+			fe := &fakeExecutor{}
+			groups := []core.TargetGroup{{VendorID: "arn:tg/orphan", Name: "orphan", Region: "us-east-1", Tags: tt.tags}}
+
+			got := captureOutput(t, func() { deleteTargetGroups(ctxWithExec(fe), groups) })
+			if len(fe.deletedTGs) != tt.wantDeletes {
+				t.Errorf("want %d deletes, got %d (output %q)", tt.wantDeletes, len(fe.deletedTGs), got)
+			}
+			if !strings.Contains(got, tt.wantOutput) {
+				t.Errorf("output missing %q, got %q", tt.wantOutput, got)
+			}
+			if marked := len(fe.marks) == 1; marked != tt.wantMarked {
+				t.Fatalf("want marked=%v, got %v", tt.wantMarked, fe.marks)
+			}
+			if tt.wantMarked {
+				id, value, _ := strings.Cut(fe.marks[0], "=")
+				if _, err := core.VerifyMark(testMarkKey, kindTargetGroup, id, value, time.Now()); err != nil {
+					t.Errorf("applied mark %q does not verify: %v", fe.marks[0], err)
+				}
+			}
+		})
+	}
+}
+
+func TestReadMarkSigningKey(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good")
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(good, []byte("  0123456789abcdef0123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(short, []byte("tooshort\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := readMarkSigningKey(good)
+	if err != nil || string(key) != "0123456789abcdef0123" {
+		t.Errorf("want trimmed key, got %q (%v)", key, err)
+	}
+	if _, err := readMarkSigningKey(short); err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("want short key rejected, got %v", err)
+	}
+	if _, err := readMarkSigningKey(""); err == nil {
+		t.Error("want missing key file rejected")
+	}
+}
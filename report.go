@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// values accepted by --output. text is the historical bracketed format;
+// json emits one array at the end of the run, ndjson one object per line as
+// decisions are made (so a long AWS sweep can be tailed).
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+// result values describing what happened to a resource after its decision.
+const (
+	resultSkipped  = "skipped" // keep / report — nothing was attempted
+	resultMock     = "mock"    // the action would have run without --mock
+	resultDeleted  = "deleted"
+	resultStopped  = "stopped"
+	resultStarted  = "started"
+	resultReleased = "released"
+	resultMarked   = "marked" // quarantine mark applied; deletion comes later
+	resultFailed   = "failed" // the provider call returned an error
+)
+
+// record is the machine-readable form of one classification decision. field
+// names are part of the --output=json contract; add, don't rename.
+type record struct {
+	Cloud    string   `json:"cloud"`
+	Kind     string   `json:"kind"`
+	VendorID string   `json:"vendor_id"`
+	Name     string   `json:"name"`
+	Region   string   `json:"region"`
+	AgeDays  float64  `json:"age_days"`
+	Tags     []string `json:"tags"`
+	State    string   `json:"state"`
+	Decision string   `json:"decision"`
+	Reason   string   `json:"reason"`
+	Mock     bool     `json:"mock"`
+	Result   string   `json:"result"`
+	Error    string   `json:"error,omitempty"`
+	// Account is the cloud account the record was swept in; empty for
+	// single-account runs.
+	Account string `json:"account,omitempty"`
+	// MaxAgeDays is the --max-age-regular or --max-age-long limit in force
+	// when the record was made, per-account overrides included; 0 for kinds
+	// the age flags do not govern.
+	MaxAgeDays float64 `json:"max_age_days,omitempty"`
+	// AgeSource is the timestamp AgeDays was measured from when the
+	// provider has several, see core.Server.AgeSource.
+	AgeSource string `json:"age_source,omitempty"`
+	// ExpiresAt is when a kept resource becomes eligible for deletion, set
+	// when that is within --warn-before.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// reporter receives every record of a run. in text mode it is a no-op; the
+// bracketed lines on `out` remain the only output.
+type reporter struct {
+	format  string
+	w       io.Writer
+	records []record
+	// summary tallies every record, whatever the format, for the
+	// end-of-run table and the exit code.
+	summary summary
+	// metrics, when set, also counts every record for /metrics.
+	metrics *metrics
+	// progress, when set, receives every record as it is added; the daemon
+	// files them under the run so GET /runs/{id} shows a sweep in flight.
+	progress func(rec record)
+}
+
+// report is the package-level record sink, configured from --output in main.
+// tests swap it via captureReport.
+var report = &reporter{format: outputText, w: os.Stdout}
+
+// add emits rec (ndjson) or buffers it until flush (json).
+func (r *reporter) add(rec record) {
+	if rec.Tags == nil {
+		// encode as [] rather than null so consumers needn't special-case it
+		rec.Tags = []string{}
+	}
+	r.summary.add(rec)
+	// every decision of the delete loops reaches the state store here, with
+	// its account stamped
+	if state != nil && rec.Kind != kindRegion {
+		state.Record(rec, time.Now())
+	}
+	if r.metrics != nil {
+		r.metrics.record(rec)
+	}
+	if r.format == outputNDJSON {
+		// best-effort write — same rationale as prettyPrint.
+		_ = json.NewEncoder(r.w).Encode(rec)
+	}
+	if r.format == outputJSON {
+		r.records = append(r.records, rec)
+	}
+	if r.progress != nil {
+		r.progress(rec)
+	}
+}
+
+// listed notes in the summary that cloud listed kind in account, so the kind
+// gets a row even when nothing of it exists.
+func (r *reporter) listed(cloud, account, kind string) {
+	r.summary.row(summaryKey{Cloud: cloud, Account: account, Kind: kind})
+}
+
+// listFailed notes in the summary that listing kind failed in cloud.
+func (r *reporter) listFailed(cloud, account, kind string, err error) {
+	r.summary.listFailed(summaryKey{Cloud: cloud, Account: account, Kind: kind}, err)
+	if r.metrics != nil {
+		r.metrics.listFailed(cloud, kind)
+	}
+}
+
+// listPartial notes in the summary that listing kind failed in some regions
+// of cloud. it counts as a failed listing: the run exits exitListingFailed.
+func (r *reporter) listPartial(cloud, account, kind string, regions []string) {
+	r.summary.listPartial(summaryKey{Cloud: cloud, Account: account, Kind: kind}, regions)
+	if r.metrics != nil {
+		r.metrics.listFailed(cloud, kind)
+	}
+}
+
+// listUnsupported notes in the summary that cloud cannot list kind.
+func (r *reporter) listUnsupported(cloud, account, kind string) {
+	r.summary.listUnsupported(summaryKey{Cloud: cloud, Account: account, Kind: kind})
+}
+
+// flush writes the buffered records as a single JSON array. called once at
+// the end of a run; a no-op for text and ndjson.
+func (r *reporter) flush() error {
+	if r.format != outputJSON {
+		return nil
+	}
+	records := r.records
+	if records == nil {
+		records = []record{}
+	}
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// validOutputFormat reports whether f is an accepted --output value.
+func validOutputFormat(f string) bool {
+	switch f {
+	case outputText, outputJSON, outputNDJSON:
+		return true
+	}
+	return false
+}
+
+// finish stamps the outcome of an attempted action onto rec and reports it.
+// success is the result to record when err is nil. the pass ctx runs under
+// supplies the account, the age limit the decision was held to and whether
+// the run is a mock one.
+func finish(ctx context.Context, rec record, success string, err error) {
+	p := passOf(ctx)
+	if rec.Account == "" {
+		rec.Account = p.account
+	}
+	rec.MaxAgeDays = p.limits.maxAgeFor(rec.Kind, rec.State)
+	rec.Mock = p.run.Mock
+	rec.Result = success
+	if err != nil {
+		rec.Result = resultFailed
+		rec.Error = err.Error()
+	}
+	p.report.add(rec)
+}
+
+// newRecord fills the decision half of a record; callers add the resource.
+func newRecord(cloud, kind string, d decision) record {
+	return record{
+		Cloud:    cloud,
+		Kind:     kind,
+		State:    strings.TrimSpace(d.State),
+		Decision: d.Action,
+		Reason:   d.Reason,
+	}
+}
+
+// maxAgeFor is the age limit the built-in policy holds kind to in state:
+// snapshots get the snapshot limit, servers marked long the long one, and
+// everything else aged by the flags the regular one.
+func (l ageLimits) maxAgeFor(kind, state string) float64 {
+	switch kind {
+	case kindSnapshot:
+		return l.Snapshot
+	case kindServer, kindIPAddress:
+	default:
+		return 0
+	}
+	if state == "LONG" {
+		return l.Long
+	}
+	return l.Normal
+}
+
+func serverRecord(cloud string, server core.Server, d decision) record {
+	rec := newRecord(cloud, kindServer, d)
+	rec.VendorID = server.VendorID
+	rec.Name = server.Name
+	rec.Region = server.Region
+	rec.AgeDays = server.Age
+	rec.AgeSource = server.AgeSource
+	rec.Tags = server.Tags
+	return rec
+}
+
+func loadBalancerRecord(cloud string, loadBalancer core.LoadBalancer, d decision) record {
+	rec := newRecord(cloud, kindLoadBalancer, d)
+	// ARN doubles as the vendor ID on every provider but classic ELB, which
+	// is addressed by name.
+	rec.VendorID = loadBalancer.LoadBalancerArn
+	if rec.VendorID == "" {
+		rec.VendorID = loadBalancer.Name
+	}
+	rec.Name = loadBalancer.Name
+	rec.Region = loadBalancer.Region
+	rec.AgeDays = loadBalancer.Age
+	rec.Tags = loadBalancer.Tags
+	return rec
+}
+
+func volumeRecord(cloud string, volume core.Volume, d decision) record {
+	rec := newRecord(cloud, kindVolume, d)
+	rec.VendorID = volume.VendorID
+	rec.Name = volume.Name
+	rec.Region = volume.Region
+	rec.AgeDays = volume.Age
+	rec.Tags = volume.Tags
+	return rec
+}
+
+func sshKeyRecord(cloud string, sshKey core.SshKey, d decision) record {
+	rec := newRecord(cloud, kindSshKey, d)
+	rec.VendorID = sshKey.VendorID
+	rec.Name = sshKey.Name
+	rec.Region = sshKey.Region
+	rec.AgeDays = sshKey.Age
+	rec.Tags = sshKey.Tags
+	return rec
+}
+
+func snapshotRecord(cloud string, snapshot core.Snapshot, d decision) record {
+	rec := newRecord(cloud, kindSnapshot, d)
+	rec.VendorID = snapshot.VendorID
+	rec.Name = snapshot.Name
+	rec.Region = snapshot.Region
+	rec.AgeDays = snapshot.Age
+	rec.Tags = snapshot.Tags
+	return rec
+}
+
+func ipAddressRecord(cloud string, ipAddress core.IPAddress, d decision) record {
+	rec := newRecord(cloud, kindIPAddress, d)
+	rec.VendorID = ipAddress.VendorID
+	rec.Name = ipAddress.Name
+	rec.Region = ipAddress.Region
+	rec.AgeDays = ipAddress.Age
+	rec.Tags = ipAddress.Tags
+	return rec
+}
+
+func targetGroupRecord(cloud string, targetGroup core.TargetGroup, d decision) record {
+	rec := newRecord(cloud, kindTargetGroup, d)
+	rec.VendorID = targetGroup.VendorID
+	rec.Name = targetGroup.Name
+	rec.Region = targetGroup.Region
+	rec.Tags = targetGroup.Tags
+	return rec
+}
+
+// kindRegion marks records for regions left out of a run. it only appears in
+// reports; policies cannot match it.
+const kindRegion = "region"
+
+func regionRecord(cloud string, region core.SkippedRegion, d decision) record {
+	rec := newRecord(cloud, kindRegion, d)
+	rec.VendorID = region.Region
+	rec.Name = region.Region
+	rec.Region = region.Region
+	return rec
+}
+
+// reportSkipped records a decision that left the resource alone.
+func reportSkipped(ctx context.Context, rec record) {
+	finish(ctx, rec, resultSkipped, nil)
+}
+
+// describeOutput is the --output flag help text.
+var describeOutput = fmt.Sprintf("Output format: %s|%s|%s. json/ndjson write records to stdout and move the human-readable lines to stderr.", outputText, outputJSON, outputNDJSON)
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// captureReport installs a reporter of the given format for the test and
+// returns the buffer it writes to.
+func captureReport(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	prev := report
+	buf := &bytes.Buffer{}
+	report = &reporter{format: format, w: buf}
+	t.Cleanup(func() { report = prev })
+	return buf
+}
+
+// decodeNDJSON parses one record per line.
+func decodeNDJSON(t *testing.T, buf *bytes.Buffer) []record {
+	t.Helper()
+	var records []record
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var rec record
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("decoding ndjson: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestReport_NDJSONServersCarryDecisionAndResult(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	buf := captureReport(t, outputNDJSON)
+
+	servers := []core.Server{
+		{VendorID: "i-1", Name: "old-box", Age: 2, AgeSource: core.AgeSourceLaunch, Region: "us-east-1", Tags: []string{"team=qa"}},
+		{VendorID: "i-2", Name: "permanent-box", Age: 9, Region: "us-east-1"},
+	}
+	text := captureOutput(t, func() { deleteServers(nil, "aws", servers) })
+	raw := buf.String()
+	if !strings.Contains(text, "[2.00 days old by launch time] [us-east-1]") || !strings.Contains(text, "[9.00 days old] [us-east-1]") {
+		t.Errorf("want the age source on the line of the server that has one, got:\n%s", text)
+	}
+
+	records := decodeNDJSON(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("want 2 records, got %d: %s", len(records), raw)
+	}
+	want := record{
+		Cloud: "aws", Kind: kindServer, VendorID: "i-1", Name: "old-box", Region: "us-east-1",
+		AgeDays: 2, AgeSource: core.AgeSourceLaunch, Tags: []string{"team=qa"}, State: "NORM", Decision: policyDelete,
+		Reason: "age", Mock: true, Result: resultMock,
+	}
+	got := records[0]
+	if got.Cloud != want.Cloud || got.Kind != want.Kind || got.VendorID != want.VendorID ||
+		got.State != want.State || got.Decision != want.Decision || got.Result != want.Result ||
+		!got.Mock || got.AgeDays != want.AgeDays || got.AgeSource != want.AgeSource || !sliceEqual(got.Tags, want.Tags) {
+		t.Errorf("record 0:\nwant %+v\ngot  %+v", want, got)
+	}
+	if records[1].State != "PERM" || records[1].Decision != policyKeep || records[1].Result != resultSkipped {
+		t.Errorf("permanent server should be kept/skipped, got %+v", records[1])
+	}
+	// untagged server must encode [] rather than null
+	if !strings.Contains(raw, `"tags":[]`) || strings.Count(raw, `"age_source"`) != 1 {
+		t.Errorf("empty tags should encode as [] and a missing age source not at all, got %s", raw)
+	}
+}
+
+func TestReport_JSONRecordsDeleteError(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	buf := captureReport(t, outputJSON)
+	fe := &fakeExecutor{deleteErr: errors.New("volume is busy")}
+	ctx := context.WithValue(ctxWithExec(fe), core.CloudKey, "hetzner")
+
+	captureOutput(t, func() {
+		deleteVolumes(ctx, []core.Volume{{VendorID: "42", Name: "data", Age: 3, Region: "fsn1"}})
+	})
+	if buf.Len() != 0 {
+		t.Fatalf("json mode must buffer until flush, got %s", buf.String())
+	}
+	if err := report.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	var records []record
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("decoding json array: %v (%s)", err, buf.String())
+	}
+	if len(records) != 1 {
+		t.Fatalf("want 1 record, got %d", len(records))
+	}
+	got := records[0]
+	if got.Kind != kindVolume || got.Cloud != "hetzner" || got.State != "DEAD" {
+		t.Errorf("unexpected record identity: %+v", got)
+	}
+	if got.Result != resultFailed || got.Error != "volume is busy" {
+		t.Errorf("want failed result with error text, got %+v", got)
+	}
+}
+
+func TestReport_SshKeyKeepLastIsKept(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	withSshKeepCount(t, 1)
+	buf := captureReport(t, outputNDJSON)
+	fe := &fakeExecutor{}
+
+	captureOutput(t, func() {
+		deleteSshKeys(ctxWithExec(fe), []core.SshKey{{VendorID: "1", Name: "c66-a"}, {VendorID: "2", Name: "c66-b"}})
+	})
+	records := decodeNDJSON(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("want 2 records, got %d", len(records))
+	}
+	if records[0].Result != resultDeleted {
+		t.Errorf("first key should be deleted, got %+v", records[0])
+	}
+	// the policy said delete, but keep-last-N overrode it — the record must
+	// say what actually happened.
+	if records[1].Decision != policyKeep || records[1].Reason != "keep last 1" || records[1].Result != resultSkipped {
+		t.Errorf("second key should be kept by keep-last-N, got %+v", records[1])
+	}
+}
+
+func TestReport_TextModeEmitsNothing(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	buf := captureReport(t, outputText)
+	captureOutput(t, func() {
+		deleteServers(nil, "aws", []core.Server{{Name: "box", Age: 2}})
+	})
+	if err := report.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("text mode must not write records, got %s", buf.String())
+	}
+}
+
+func sliceEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReport_SkippedRegionsAreRecordedOnce(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	buf := captureReport(t, outputNDJSON)
+
+	text := captureOutput(t, func() {
+		reportSkippedRegions(context.Background(), "aws", []core.SkippedRegion{
+			{Region: "af-south-1", Reason: "not opted in"},
+			{Region: "us-esat-2", Reason: "unknown region"},
+		})
+	})
+	if !strings.Contains(text, "[2 SKIPPED REGIONS]") || !strings.Contains(text, "[af-south-1] ▶ skipped (not opted in)") {
+		t.Errorf("unexpected text output:\n%s", text)
+	}
+	records := decodeNDJSON(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("want one record per region, got %d", len(records))
+	}
+	got := records[1]
+	if got.Kind != kindRegion || got.Cloud != "aws" || got.VendorID != "us-esat-2" || got.Region != "us-esat-2" ||
+		got.Reason != "unknown region" || got.Decision != policyKeep || got.Result != resultSkipped {
+		t.Errorf("unexpected region record: %+v", got)
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// stateMemory selects the in-process store: state lives as long as the
+	// process, which suits --action=daemon without a disk.
+	stateMemory = "memory:"
+	// maxStateChanges caps the decision history kept per resource.
+	maxStateChanges = 100
+	// stateLockTimeout bounds the wait for another janitor holding the file.
+	stateLockTimeout = 5 * time.Second
+)
+
+// stateBucket holds one JSON resourceState per resource.
+var stateBucket = []byte("resources")
+
+// stateKey identifies a resource across runs. the vendor ID leads so every
+// resource with one ID sits together.
+type stateKey struct {
+	VendorID string
+	Cloud    string
+	Account  string
+	Kind     string
+}
+
+func keyOf(rec record) stateKey {
+	return stateKey{VendorID: rec.VendorID, Cloud: rec.Cloud, Account: rec.Account, Kind: rec.Kind}
+}
+
+// bytes encodes k for the bolt store. vendor IDs never contain NUL.
+func (k stateKey) bytes() []byte {
+	return []byte(strings.Join([]string{k.VendorID, k.Cloud, k.Account, k.Kind}, "\x00"))
+}
+
+// stateChange is one entry of a resource's decision history.
+type stateChange struct {
+	At       time.Time `json:"at"`
+	Decision string    `json:"decision"`
+	Reason   string    `json:"reason"`
+	Result   string    `json:"result"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// resourceState is what janitor remembers about one resource.
+type resourceState struct {
+	Cloud     string    `json:"cloud"`
+	Account   string    `json:"account,omitempty"`
+	Kind      string    `json:"kind"`
+	VendorID  string    `json:"vendor_id"`
+	Name      string    `json:"name"`
+	Region    string    `json:"region"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Last is the latest decision; Changes only grows when the decision,
+	// reason or result differ from the one before.
+	Last    stateChange   `json:"last"`
+	Changes []stateChange `json:"changes"`
+	// DeletedAt is when a delete or release succeeded.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// MarkedAt is when janitor last applied a quarantine mark.
+	MarkedAt *time.Time `json:"marked_at,omitempty"`
+	// WarnedAt is when an expiry warning was sent, announcing ExpiresAt.
+	WarnedAt  *time.Time `json:"warned_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// apply folds a decision made at into s.
+func (s *resourceState) apply(rec record, at time.Time) {
+	if s.FirstSeen.IsZero() {
+		s.FirstSeen = at
+	}
+	s.Cloud, s.Account, s.Kind, s.VendorID = rec.Cloud, rec.Account, rec.Kind, rec.VendorID
+	s.Name, s.Region = rec.Name, rec.Region
+	s.LastSeen = at
+	change := stateChange{At: at, Decision: rec.Decision, Reason: rec.Reason, Result: rec.Result, Error: rec.Error}
+	if n := len(s.Changes); n == 0 || s.Changes[n-1].Decision != change.Decision ||
+		s.Changes[n-1].Reason != change.Reason || s.Changes[n-1].Result != change.Result {
+		s.Changes = append(s.Changes, change)
+		if len(s.Changes) > maxStateChanges {
+			s.Changes = s.Changes[len(s.Changes)-maxStateChanges:]
+		}
+	}
+	s.Last = change
+	if rec.Result == resultDeleted || rec.Result == resultReleased {
+		s.DeletedAt = &at
+	}
+	if rec.Result == resultMarked {
+		s.MarkedAt = &at
+	}
+}
+
+// warned notes that the expiry of rec was announced at.
+func (s *resourceState) warned(rec record, at time.Time) {
+	s.Cloud, s.Account, s.Kind, s.VendorID = rec.Cloud, rec.Account, rec.Kind, rec.VendorID
+	expires := *rec.ExpiresAt
+	s.WarnedAt, s.ExpiresAt = &at, &expires
+}
+
+// ageSourceFirstSeen is the record age source of resources aged from their
+// first sighting, see firstSeenAge.
+const ageSourceFirstSeen = "first seen"
+
+// firstSeenAge is how many days ago the state store first saw the resource
+// of key, for resources whose provider reports no creation time. it is a
+// lower bound on the real age; false when nothing is known about it yet.
+func firstSeenAge(ctx context.Context, key stateKey, now time.Time) (float64, bool) {
+	if state == nil {
+		return 0, false
+	}
+	s, ok, err := state.Get(key)
+	if err != nil {
+		core.Warnf(ctx, "cannot read the state of %s %q: %v", key.Kind, key.VendorID, err)
+		return 0, false
+	}
+	if !ok || s.FirstSeen.IsZero() {
+		return 0, false
+	}
+	return now.Sub(s.FirstSeen).Hours() / 24.0, true
+}
+
+// stateStore keeps what janitor has seen across runs. every decision
+// reaches it through the reporter; Flush persists a run's worth at its end.
+type stateStore interface {
+	// Record notes one decision made at.
+	Record(rec record, at time.Time)
+	// Warned notes that rec's expiry was announced at.
+	Warned(rec record, at time.Time)
+	// Flush persists everything noted since the last flush.
+	Flush() error
+	// Get returns the state of one resource, flushed or not.
+	Get(key stateKey) (resourceState, bool, error)
+	// Lookup returns every resource with vendorID, in any cloud or account.
+	Lookup(vendorID string) ([]resourceState, error)
+}
+
+// state is the store opened from --state-file; nil keeps nothing.
+var state stateStore
+
+// openStateStore opens the store spec names: memory: for the in-process
+// store, anything else is the path of a bolt file, created when missing.
+func openStateStore(spec string) (stateStore, error) {
+	if spec == stateMemory {
+		return &memoryStore{resources: map[stateKey]*resourceState{}}, nil
+	}
+	s := &boltStore{path: spec}
+	// open once up front so a bad path fails before anything is swept
+	if err := s.update(func(*bolt.Bucket) error { return nil }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// memoryStore is the in-process stateStore.
+type memoryStore struct {
+	mu        sync.Mutex
+	resources map[stateKey]*resourceState
+}
+
+func (m *memoryStore) resource(key stateKey) *resourceState {
+	s, ok := m.resources[key]
+	if !ok {
+		s = &resourceState{}
+		m.resources[key] = s
+	}
+	return s
+}
+
+func (m *memoryStore) Record(rec record, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resource(keyOf(rec)).apply(rec, at)
+}
+
+func (m *memoryStore) Warned(rec record, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resource(keyOf(rec)).warned(rec, at)
+}
+
+func (m *memoryStore) Flush() error { return nil }
+
+func (m *memoryStore) Get(key stateKey) (resourceState, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.resources[key]
+	if !ok {
+		return resourceState{}, false, nil
+	}
+	return *s, true, nil
+}
+
+func (m *memoryStore) Lookup(vendorID string) ([]resourceState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var found []resourceState
+	for key, s := range m.resources {
+		if key.VendorID == vendorID {
+			found = append(found, *s)
+		}
+	}
+	sortStates(found)
+	return found, nil
+}
+
+// boltStore is the file-backed stateStore. it opens the file only for the
+// length of a transaction, so `janitor history` can read it while a daemon
+// runs. noted decisions wait in memory until Flush writes them in one
+// transaction.
+type boltStore struct {
+	path    string
+	mu      sync.Mutex
+	pending []stateOp
+}
+
+// stateOp is one noted decision or warning awaiting Flush.
+type stateOp struct {
+	rec    record
+	at     time.Time
+	warned bool
+}
+
+func (b *boltStore) Record(rec record, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, stateOp{rec: rec, at: at})
+}
+
+func (b *boltStore) Warned(rec record, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, stateOp{rec: rec, at: at, warned: true})
+}
+
+// open opens the file, waiting up to stateLockTimeout for another janitor.
+func (b *boltStore) open(readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(b.path, 0o600, &bolt.Options{Timeout: stateLockTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is locked by another janitor", b.path)
+	}
+	return db, err
+}
+
+// update runs fn on the resources bucket in a write transaction.
+func (b *boltStore) update(fn func(*bolt.Bucket) error) error {
+	db, err := b.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(stateBucket)
+		if err != nil {
+			return err
+		}
+		return fn(bucket)
+	})
+}
+
+// view runs fn on the resources bucket in a read transaction; fn is not
+// called when nothing was ever written.
+func (b *boltStore) view(fn func(*bolt.Bucket) error) error {
+	db, err := b.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(stateBucket); bucket != nil {
+			return fn(bucket)
+		}
+		return nil
+	})
+}
+
+func getState(bucket *bolt.Bucket, key stateKey) (resourceState, bool, error) {
+	var s resourceState
+	raw := bucket.Get(key.bytes())
+	if raw == nil {
+		return s, false, nil
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return s, false, fmt.Errorf("resource %q: %w", key.VendorID, err)
+	}
+	return s, true, nil
+}
+
+func (b *boltStore) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) == 0 {
+		return nil
+	}
+	err := b.update(func(bucket *bolt.Bucket) error {
+		for _, op := range b.pending {
+			key := keyOf(op.rec)
+			s, _, err := getState(bucket, key)
+			if err != nil {
+				return err
+			}
+			if op.warned {
+				s.warned(op.rec, op.at)
+			} else {
+				s.apply(op.rec, op.at)
+			}
+			raw, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key.bytes(), raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		b.pending = nil
+	}
+	return err
+}
+
+// Get reads the flushed state of key and folds in what is still pending.
+func (b *boltStore) Get(key stateKey) (resourceState, bool, error) {
+	var s resourceState
+	var found bool
+	err := b.view(func(bucket *bolt.Bucket) error {
+		var err error
+		s, found, err = getState(bucket, key)
+		return err
+	})
+	if err != nil {
+		return s, false, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, op := range b.pending {
+		if keyOf(op.rec) != key {
+			continue
+		}
+		found = true
+		if op.warned {
+			s.warned(op.rec, op.at)
+		} else {
+			s.apply(op.rec, op.at)
+		}
+	}
+	return s, found, nil
+}
+
+func (b *boltStore) Lookup(vendorID string) ([]resourceState, error) {
+	var found []resourceState
+	prefix := append([]byte(vendorID), 0)
+	err := b.view(func(bucket *bolt.Bucket) error {
+		c := bucket.Cursor()
+		for k, raw := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, raw = c.Next() {
+			var s resourceState
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return fmt.Errorf("resource %q: %w", vendorID, err)
+			}
+			found = append(found, s)
+		}
+		return nil
+	})
+	sortStates(found)
+	return found, err
+}
+
+// sortStates orders resources sharing a vendor ID by cloud, account, kind.
+func sortStates(states []resourceState) {
+	key := func(s resourceState) string { return s.Cloud + "\x00" + s.Account + "\x00" + s.Kind }
+	sort.Slice(states, func(i, j int) bool { return key(states[i]) < key(states[j]) })
+}
+
+// storeWarnings is the warning memory kept in the state store, used instead
+// of --warned-file when --state-file is set.
+type storeWarnings struct {
+	store stateStore
+}
+
+func (w storeWarnings) announced(rec record) bool {
+	s, ok, err := w.store.Get(keyOf(rec))
+	if err != nil || !ok || s.ExpiresAt == nil {
+		return false
+	}
+	return sameExpiry(*s.ExpiresAt, *rec.ExpiresAt)
+}
+
+func (w storeWarnings) remember(recs []record, now time.Time) error {
+	for _, rec := range recs {
+		w.store.Warned(rec, now)
+	}
+	// persisted with the run's decisions
+	return nil
+}
+
+// flushState persists the run's decisions. a failure is only a warning: the
+// sweep itself succeeded.
+func flushState() {
+	if state == nil {
+		return
+	}
+	if err := state.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot save state: %s\n", err.Error())
+	}
+}
+
+// historyCommand prints what the --state-file store knows about vendorID to
+// w, as text or, with --output, as json / ndjson. it returns the exit code.
+func historyCommand(w io.Writer, vendorID string) int {
+	switch {
+	case flagStateFile == "":
+		fmt.Fprintln(os.Stderr, "janitor history needs --state-file")
+		return 1
+	case flagStateFile == stateMemory:
+		fmt.Fprintln(os.Stderr, "--state-file=memory: keeps nothing between processes")
+		return 1
+	case !validOutputFormat(flagOutput):
+		fmt.Fprintf(os.Stderr, "Unrecognised output format '%s'\n", flagOutput)
+		return 1
+	}
+	if _, err := os.Stat(flagStateFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read state store: %s\n", err.Error())
+		return 1
+	}
+	// read-only: never blocks a sweep for longer than the read
+	states, err := (&boltStore{path: flagStateFile}).Lookup(vendorID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read state store: %s\n", err.Error())
+		return 1
+	}
+	if len(states) == 0 {
+		fmt.Fprintf(os.Stderr, "No resource %q in %s\n", vendorID, flagStateFile)
+		return 1
+	}
+	if err := writeHistory(w, states, flagOutput); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write history: %s\n", err.Error())
+		return 1
+	}
+	return exitClean
+}
+
+// writeHistory prints states in format.
+func writeHistory(w io.Writer, states []resourceState, format string) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(states)
+	case outputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, s := range states {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, s := range states {
+		if i > 0 {
+			_, _ = fmt.Fprintln(tw)
+		}
+		where := s.Cloud
+		if s.Account != "" {
+			where += "/" + s.Account
+		}
+		_, _ = fmt.Fprintf(tw, "%s %s %s (%s) %s\n", where, s.Kind, s.VendorID, s.Name, orDash(s.Region))
+		_, _ = fmt.Fprintf(tw, "  first seen\t%s\n", formatTimestamp(s.FirstSeen))
+		_, _ = fmt.Fprintf(tw, "  last seen\t%s\n", formatTimestamp(s.LastSeen))
+		if s.DeletedAt != nil {
+			_, _ = fmt.Fprintf(tw, "  deleted\t%s\n", formatTimestamp(*s.DeletedAt))
+		}
+		if s.WarnedAt != nil {
+			_, _ = fmt.Fprintf(tw, "  warned\t%s, eligible at %s\n", formatTimestamp(*s.WarnedAt), formatTimestamp(*s.ExpiresAt))
+		}
+		for _, change := range s.Changes {
+			line := fmt.Sprintf("  %s\t%s\t%s\t%s", formatTimestamp(change.At), change.Decision, orDash(change.Reason), change.Result)
+			if change.Error != "" {
+				line += ": " + change.Error
+			}
+			_, _ = fmt.Fprintln(tw, line)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud66/janitor/core"
+)
+
+// withState routes the reporter's decisions into store.
+func withState(t *testing.T, store stateStore) {
+	t.Helper()
+	prevState, prevWarnings := state, warnings
+	state, warnings = store, storeWarnings{store: store}
+	t.Cleanup(func() { state, warnings = prevState, prevWarnings })
+}
+
+// boltAt opens a bolt state store in a fresh temp dir.
+func boltAt(t *testing.T) (stateStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "state.db")
+	store, err := openStateStore(path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	return store, path
+}
+
+func TestStateStore_TracksResourcesAcrossRuns(t *testing.T) {
+	for _, backend := range []string{"bolt", "memory"} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := openStateStore(stateMemory)
+			if backend == "bolt" {
+				store, _ = boltAt(t)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			day := func(n int) time.Time { return time.Date(2026, 10, n, 9, 0, 0, 0, time.UTC) }
+			vol := record{Cloud: "digitalocean", Kind: kindVolume, VendorID: "vol-1", Name: "scratch", Region: "nyc1", Decision: policyKeep, Reason: "attached to instance", Result: resultSkipped}
+
+			store.Record(vol, day(1))
+			store.Record(vol, day(2))
+			if err := store.Flush(); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+			vol.Decision, vol.Reason = policyDelete, "unattached"
+			store.Record(withResult(vol, resultFailed, "volume busy"), day(3))
+			store.Record(withResult(vol, resultDeleted, ""), day(4))
+
+			// Get sees decisions not flushed yet
+			got, ok, err := store.Get(keyOf(vol))
+			if err != nil || !ok {
+				t.Fatalf("get: %v, %v", ok, err)
+			}
+			if !got.FirstSeen.Equal(day(1)) || !got.LastSeen.Equal(day(4)) || got.DeletedAt == nil || !got.DeletedAt.Equal(day(4)) {
+				t.Errorf("want first seen day 1, last seen and deleted day 4, got %+v", got)
+			}
+			if err := store.Flush(); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+
+			states, err := store.Lookup("vol-1")
+			if err != nil || len(states) != 1 {
+				t.Fatalf("lookup: %+v, %v", states, err)
+			}
+			var results []string
+			for _, change := range states[0].Changes {
+				results = append(results, change.Result)
+			}
+			// the unchanged day 2 decision is folded into day 1
+			if strings.Join(results, ",") != "skipped,failed,deleted" || states[0].Changes[1].Error != "volume busy" {
+				t.Errorf("want the decision changes only, got %+v", states[0].Changes)
+			}
+			if states[0].Last.Result != resultDeleted {
+				t.Errorf("want the last decision, got %+v", states[0].Last)
+			}
+		})
+	}
+}
+
+func withResult(rec record, result, errText string) record {
+	rec.Result, rec.Error = result, errText
+	return rec
+}
+
+func TestBoltStore_PersistsAndLooksUpByVendorID(t *testing.T) {
+	store, path := boltAt(t)
+	at := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	for _, rec := range []record{
+		{Cloud: "aws", Account: "123456789012", Kind: kindServer, VendorID: "i-1", Result: resultSkipped},
+		{Cloud: "aws", Kind: kindServer, VendorID: "i-1", Result: resultSkipped},
+		{Cloud: "aws", Kind: kindServer, VendorID: "i-10", Result: resultSkipped},
+	} {
+		store.Record(rec, at)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	reopened, err := openStateStore(path)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	states, err := reopened.Lookup("i-1")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	// i-10 shares the prefix but not the ID
+	if len(states) != 2 || states[0].Account != "" || states[1].Account != "123456789012" {
+		t.Errorf("want i-1 in both accounts, got %+v", states)
+	}
+	if states, _ := reopened.Lookup("i-2"); len(states) != 0 {
+		t.Errorf("want nothing for i-2, got %+v", states)
+	}
+}
+
+func TestStateStore_RecordsEveryDecisionOfARun(t *testing.T) {
+	store, _ := openStateStore(stateMemory)
+	withState(t, store)
+	withFlags(t, false, 0.38, 5.0)
+	captureReport(t, outputText)
+	ctx := ctxWithExec(&fakeExecutor{})
+
+	captureOutput(t, func() {
+		ctx := withPass(ctx, pass{account: "ci", limits: currentAgeLimits(), out: out, report: report})
+		reportSkippedRegions(ctx, "aws", []core.SkippedRegion{{Region: "ap-east-1", Reason: "not opted in"}})
+		deleteVolumes(ctx, []core.Volume{
+			{VendorID: "vol-old", Name: "old", Age: 3},
+			{VendorID: "vol-used", Name: "used", Age: 3, Attached: true},
+		})
+	})
+
+	for vendorID, want := range map[string]string{"vol-old": resultDeleted, "vol-used": resultSkipped} {
+		states, _ := store.Lookup(vendorID)
+		if len(states) != 1 || states[0].Last.Result != want || states[0].Account != "ci" {
+			t.Errorf("%s: want one %s state in account ci, got %+v", vendorID, want, states)
+		}
+	}
+	if states, _ := store.Lookup("ap-east-1"); len(states) != 0 {
+		t.Errorf("skipped regions are not resources, got %+v", states)
+	}
+}
+
+func TestStoreWarnings_RememberAnnouncements(t *testing.T) {
+	store, _ := boltAt(t)
+	w := storeWarnings{store: store}
+	expires := time.Now().Add(3 * time.Hour)
+	rec := record{Cloud: "hetzner", Kind: kindServer, VendorID: "42", ExpiresAt: &expires}
+	if w.announced(rec) {
+		t.Fatal("announced before anything was sent")
+	}
+	if err := w.remember([]record{rec}, time.Now()); err != nil {
+		t.Fatalf("remember: %v", err)
+	}
+	if !w.announced(rec) {
+		t.Error("want the announcement visible before the flush")
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if !w.announced(rec) {
+		t.Error("want the announcement remembered")
+	}
+	later := expires.Add(96 * time.Hour)
+	rec.ExpiresAt = &later
+	if w.announced(rec) {
+		t.Error("a moved eligibility time is news again")
+	}
+}
+
+func TestHistoryCommand(t *testing.T) {
+	store, path := boltAt(t)
+	prevFile, prevOutput := flagStateFile, flagOutput
+	t.Cleanup(func() { flagStateFile, flagOutput = prevFile, prevOutput })
+	flagStateFile, flagOutput = path, outputText
+
+	first := time.Date(2026, 10, 10, 9, 0, 0, 0, time.UTC)
+	last := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	rec := record{Cloud: "aws", Kind: kindServer, VendorID: "i-1", Name: "demo-box", Region: "us-east-1", Decision: policyKeep, Reason: "age", Result: resultSkipped}
+	store.Record(rec, first)
+	rec.Decision = policyDelete
+	store.Record(withResult(rec, resultDeleted, ""), last)
+	if err := store.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if code := historyCommand(&buf, "i-1"); code != exitClean {
+		t.Fatalf("want exit 0, got %d", code)
+	}
+	text := buf.String()
+	for _, want := range []string{
+		"aws server i-1 (demo-box) us-east-1\n",
+		"first seen",
+		"deleted",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("history lacks %q:\n%s", want, text)
+		}
+	}
+	if !containsRow(text, "2026-10-10", "09:00", "UTC", "keep", "age", "skipped") ||
+		!containsRow(text, "2026-10-17", "09:00", "UTC", "delete", "age", "deleted") ||
+		!containsRow(text, "deleted", "2026-10-17", "09:00", "UTC") {
+		t.Errorf("want both decisions and the deletion listed:\n%s", text)
+	}
+
+	flagOutput = outputJSON
+	buf.Reset()
+	historyCommand(&buf, "i-1")
+	var states []resourceState
+	if err := json.Unmarshal(buf.Bytes(), &states); err != nil || len(states) != 1 || len(states[0].Changes) != 2 {
+		t.Errorf("want the state as JSON, got %s (%v)", buf.String(), err)
+	}
+
+	if code := historyCommand(&buf, "i-unknown"); code != 1 {
+		t.Errorf("unknown vendor ID: want exit 1, got %d", code)
+	}
+	flagStateFile = filepath.Join(t.TempDir(), "missing.db")
+	if code := historyCommand(&buf, "i-1"); code != 1 {
+		t.Errorf("missing state file: want exit 1, got %d", code)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// exit codes main returns after a run. 1 (usage) and 2 (--yes refusal) are
+// returned before anything is swept.
+const (
+	exitClean         = 0
+	exitListingFailed = 3 // a provider listing failed; the run saw only part of the estate
+	exitActionsFailed = 4 // every listing worked, but some delete / stop / mark calls failed
+)
+
+// kindAccount is the summary kind for an account listing that failed before
+// any resource kind could be listed.
+const kindAccount = "account"
+
+// summaryKey groups results per cloud, account and kind.
+type summaryKey struct {
+	Cloud   string
+	Account string
+	Kind    string
+}
+
+// summaryRow tallies the records of one summaryKey.
+type summaryRow struct {
+	Scanned int
+	Kept    map[string]int // keep / report decisions by reason
+	Acted   int            // deleted, released, stopped or started
+	Marked  int
+	Mock    int
+	Failed  int
+	// Unsupported is set when the provider cannot list the kind at all;
+	// ListError when the listing itself failed. FailedRegions names the
+	// regions a partial listing could not reach; the rest were swept.
+	Unsupported   bool
+	ListError     string
+	FailedRegions []string
+}
+
+// summary collects every record of a run into per cloud / account / kind
+// rows, in the order they were first seen. the zero value is ready to use.
+type summary struct {
+	order []summaryKey
+	rows  map[summaryKey]*summaryRow
+	// notable keeps the records a run digest lists one by one: acted on
+	// (or would have been, under --mock), failed, or kept with a WARN.
+	notable []record
+	// expiring keeps the kept records that become eligible for deletion
+	// within --warn-before.
+	expiring []record
+}
+
+func (s *summary) row(key summaryKey) *summaryRow {
+	if s.rows == nil {
+		s.rows = map[summaryKey]*summaryRow{}
+	}
+	row, ok := s.rows[key]
+	if !ok {
+		row = &summaryRow{Kept: map[string]int{}}
+		s.rows[key] = row
+		s.order = append(s.order, key)
+	}
+	return row
+}
+
+// add tallies one decision record.
+func (s *summary) add(rec record) {
+	row := s.row(summaryKey{Cloud: rec.Cloud, Account: rec.Account, Kind: rec.Kind})
+	row.Scanned++
+	switch rec.Result {
+	case resultSkipped:
+		row.Kept[rec.Reason]++
+	case resultDeleted, resultReleased, resultStopped, resultStarted:
+		row.Acted++
+	case resultMarked:
+		row.Marked++
+	case resultMock:
+		row.Mock++
+	case resultFailed:
+		row.Failed++
+	}
+	if rec.Result != resultSkipped || rec.State == stateWarn {
+		s.notable = append(s.notable, rec)
+	}
+	if rec.ExpiresAt != nil {
+		s.expiring = append(s.expiring, rec)
+	}
+}
+
+// listFailed records that listing kind failed, so nothing of it was swept.
+func (s *summary) listFailed(key summaryKey, err error) {
+	s.row(key).ListError = err.Error()
+}
+
+// listPartial records that listing kind failed in regions, so only the
+// other regions were swept.
+func (s *summary) listPartial(key summaryKey, regions []string) {
+	row := s.row(key)
+	row.FailedRegions = append(row.FailedRegions, regions...)
+}
+
+// listUnsupported records that the provider has no listing for kind.
+func (s *summary) listUnsupported(key summaryKey) {
+	s.row(key).Unsupported = true
+}
+
+// exitCode maps the run's outcome to the process exit code. a failed
+// listing outranks failed actions: it means the run was incomplete.
+func (s *summary) exitCode() int {
+	code := exitClean
+	for _, row := range s.rows {
+		if row.ListError != "" || len(row.FailedRegions) > 0 {
+			return exitListingFailed
+		}
+		if row.Failed > 0 {
+			code = exitActionsFailed
+		}
+	}
+	return code
+}
+
+// write prints the summary table. acted names the action column (DELETED,
+// STOPPED or STARTED); NOTES carries kept reasons, most frequent first, or
+// why a kind was not swept.
+func (s *summary) write(w io.Writer, acted string) {
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "[SUMMARY]")
+	if len(s.order) == 0 {
+		_, _ = fmt.Fprintln(w, "nothing scanned")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "CLOUD\tACCOUNT\tKIND\tSCANNED\tKEPT\t%s\tMARKED\tMOCK\tFAILED\tNOTES\n", acted)
+	for _, key := range s.order {
+		row := s.rows[key]
+		account := key.Account
+		if account == "" {
+			account = "-"
+		}
+		switch {
+		case row.ListError != "":
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\t-\t-\t-\t-\tlisting failed: %s\n", key.Cloud, account, key.Kind, row.ListError)
+			continue
+		case row.Unsupported:
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\t-\t-\t-\t-\tunsupported\n", key.Cloud, account, key.Kind)
+			continue
+		}
+		kept, notes := 0, []string{}
+		for _, n := range row.Kept {
+			kept += n
+		}
+		if len(row.FailedRegions) > 0 {
+			notes = append(notes, "listing failed in "+strings.Join(row.FailedRegions, ", "))
+		}
+		if kept > 0 {
+			notes = append(notes, "kept: "+keptReasons(row.Kept))
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			key.Cloud, account, key.Kind, row.Scanned, kept, row.Acted, row.Marked, row.Mock, row.Failed, strings.Join(notes, "; "))
+	}
+	_ = tw.Flush()
+}
+
+// summaryEntry is the JSON form of a summary row, served by the daemon.
+type summaryEntry struct {
+	Cloud         string         `json:"cloud"`
+	Account       string         `json:"account,omitempty"`
+	Kind          string         `json:"kind"`
+	Scanned       int            `json:"scanned"`
+	Kept          map[string]int `json:"kept"`
+	Acted         int            `json:"acted"`
+	Marked        int            `json:"marked"`
+	Mock          int            `json:"mock"`
+	Failed        int            `json:"failed"`
+	Unsupported   bool           `json:"unsupported,omitempty"`
+	ListError     string         `json:"list_error,omitempty"`
+	FailedRegions []string       `json:"failed_regions,omitempty"`
+}
+
+// entries returns the rows in table order.
+func (s *summary) entries() []summaryEntry {
+	entries := make([]summaryEntry, 0, len(s.order))
+	for _, key := range s.order {
+		row := s.rows[key]
+		kept := make(map[string]int, len(row.Kept))
+		for reason, n := range row.Kept {
+			kept[reason] = n
+		}
+		entries = append(entries, summaryEntry{
+			Cloud:         key.Cloud,
+			Account:       key.Account,
+			Kind:          key.Kind,
+			Scanned:       row.Scanned,
+			Kept:          kept,
+			Acted:         row.Acted,
+			Marked:        row.Marked,
+			Mock:          row.Mock,
+			Failed:        row.Failed,
+			Unsupported:   row.Unsupported,
+			ListError:     row.ListError,
+			FailedRegions: append([]string(nil), row.FailedRegions...),
+		})
+	}
+	return entries
+}
+
+// keptReasons formats kept counts as "age 7, permanent 3".
+func keptReasons(kept map[string]int) string {
+	reasons := make([]string, 0, len(kept))
+	for reason := range kept {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if kept[reasons[i]] != kept[reasons[j]] {
+			return kept[reasons[i]] > kept[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		label := reason
+		if label == "" {
+			label = "(no reason)"
+		}
+		parts[i] = fmt.Sprintf("%s %d", label, kept[reason])
+	}
+	return strings.Join(parts, ", ")
+}
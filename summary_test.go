@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloud66/janitor/core"
+)
+
+func TestSummary_TalliesDecisionsPerKind(t *testing.T) {
+	withFlags(t, false, 0.38, 5.0)
+	captureReport(t, outputText)
+	fe := &fakeExecutor{deleteErr: errors.New("volume is busy")}
+	ctx := context.WithValue(ctxWithExec(fe), core.CloudKey, "hetzner")
+
+	captureOutput(t, func() {
+		deleteVolumes(ctx, []core.Volume{
+			{VendorID: "1", Name: "old", Age: 3},
+			{VendorID: "2", Name: "new", Age: 0.01},
+			{VendorID: "3", Name: "permanent-data", Age: 9},
+		})
+	})
+	row := report.summary.rows[summaryKey{Cloud: "hetzner", Kind: kindVolume}]
+	if row == nil {
+		t.Fatal("want a hetzner volume row")
+	}
+	kept := 0
+	for _, n := range row.Kept {
+		kept += n
+	}
+	if row.Scanned != 3 || row.Failed != 1 || kept != 2 || row.Acted != 0 {
+		t.Errorf("unexpected tally %+v", row)
+	}
+	if got := report.summary.exitCode(); got != exitActionsFailed {
+		t.Errorf("want exit code %d after a failed delete, got %d", exitActionsFailed, got)
+	}
+}
+
+func TestSummary_ExitCodes(t *testing.T) {
+	var clean summary
+	clean.add(record{Cloud: "aws", Kind: kindServer, Result: resultMock})
+	clean.add(record{Cloud: "aws", Kind: kindServer, Result: resultSkipped, Reason: "age"})
+	clean.listUnsupported(summaryKey{Cloud: "vultr", Kind: kindSnapshot})
+	if got := clean.exitCode(); got != exitClean {
+		t.Errorf("unsupported kinds and mock runs are clean, got %d", got)
+	}
+
+	var failed summary
+	failed.add(record{Cloud: "aws", Kind: kindServer, Result: resultFailed})
+	failed.listFailed(summaryKey{Cloud: "aws", Kind: kindVolume}, errors.New("throttled"))
+	if got := failed.exitCode(); got != exitListingFailed {
+		t.Errorf("a failed listing outranks failed actions, got %d", got)
+	}
+}
+
+func TestSummary_WriteTable(t *testing.T) {
+	var s summary
+	for i := 0; i < 3; i++ {
+		s.add(record{Cloud: "aws", Account: "ci", Kind: kindServer, Result: resultSkipped, Reason: "age"})
+	}
+	s.add(record{Cloud: "aws", Account: "ci", Kind: kindServer, Result: resultSkipped, Reason: "permanent"})
+	s.add(record{Cloud: "aws", Account: "ci", Kind: kindServer, Result: resultDeleted})
+	s.add(record{Cloud: "aws", Account: "ci", Kind: kindServer, Result: resultFailed})
+	s.listFailed(summaryKey{Cloud: "aws", Account: "ci", Kind: kindVolume}, errors.New("all regions failed"))
+	s.listUnsupported(summaryKey{Cloud: "vultr", Kind: kindSnapshot})
+	buf := &bytes.Buffer{}
+
+	s.write(buf, "DELETED")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || lines[0] != "[SUMMARY]" {
+		t.Fatalf("want a title, a header and three rows, got:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "CLOUD ACCOUNT KIND SCANNED KEPT DELETED MARKED MOCK FAILED NOTES" {
+		t.Errorf("unexpected header %q", lines[1])
+	}
+	if got := strings.Join(strings.Fields(lines[2]), " "); got != "aws ci server 6 4 1 0 0 1 kept: age 3, permanent 1" {
+		t.Errorf("unexpected server row %q", got)
+	}
+	if !strings.Contains(lines[3], "listing failed: all regions failed") {
+		t.Errorf("unexpected volume row %q", lines[3])
+	}
+	if got := strings.Join(strings.Fields(lines[4]), " "); got != "vultr - snapshot - - - - - - unsupported" {
+		t.Errorf("unexpected snapshot row %q", got)
+	}
+	// columns line up across rows
+	if strings.Index(lines[1], "SCANNED") != strings.Index(lines[2], "6") {
+		t.Errorf("columns misaligned:\n%s", buf.String())
+	}
+}
+
+// failingExecutor fails every server listing; every other kind is
+// unsupported.
+type failingExecutor struct {
+	*fakeExecutor
+}
+
+func (f failingExecutor) ServersGet(ctx context.Context, vendorIDs []string, regions []string) ([]core.Server, error) {
+	return nil, errors.New("all regions failed: throttled")
+}
+
+func TestSweepCloud_RecordsListingOutcomes(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	prevAction := flagAction
+	flagAction = actionDelete
+	t.Cleanup(func() { flagAction = prevAction })
+	captureReport(t, outputText)
+	exec := failingExecutor{&fakeExecutor{}}
+
+	text := captureOutput(t, func() {
+		sweepCloud(context.Background(), "aws", exec)
+	})
+	if !strings.Contains(text, "Cannot get servers due to all regions failed: throttled") {
+		t.Errorf("want the listing error printed, got:\n%s", text)
+	}
+	servers := report.summary.rows[summaryKey{Cloud: "aws", Kind: kindServer}]
+	if servers == nil || servers.ListError == "" {
+		t.Errorf("want the server listing failure in the summary, got %+v", servers)
+	}
+	volumes := report.summary.rows[summaryKey{Cloud: "aws", Kind: kindVolume}]
+	if volumes == nil || !volumes.Unsupported {
+		t.Errorf("want volumes noted as unsupported, got %+v", volumes)
+	}
+	if got := report.summary.exitCode(); got != exitListingFailed {
+		t.Errorf("want exit code %d, got %d", exitListingFailed, got)
+	}
+}
+
+// partialExecutor lists one old volume but fails to reach eu-west-1.
+type partialExecutor struct {
+	*fakeExecutor
+}
+
+func (f partialExecutor) VolumesGet(ctx context.Context) ([]core.Volume, error) {
+	return []core.Volume{{VendorID: "vol-1", Name: "old", Region: "us-east-1", Age: 3}},
+		&core.PartialError{Regions: []string{"eu-west-1"}, Err: errors.New("eu-west-1: throttled")}
+}
+
+func TestSweepCloud_PartialListingIsSweptAndFailsTheRun(t *testing.T) {
+	withFlags(t, true, 0.38, 5.0)
+	prevAction := flagAction
+	flagAction = actionDelete
+	t.Cleanup(func() { flagAction = prevAction })
+	captureReport(t, outputText)
+	exec := partialExecutor{&fakeExecutor{}}
+	ctx := context.WithValue(context.Background(), core.CloudKey, "aws")
+	ctx = context.WithValue(ctx, core.ExecutorKey, core.ExecutorInterface(exec))
+
+	text := captureOutput(t, func() {
+		sweepCloud(ctx, "aws", exec)
+	})
+	if !strings.Contains(text, "Cannot list volume in eu-west-1 due to eu-west-1: throttled") {
+		t.Errorf("want the failed region printed, got:\n%s", text)
+	}
+	volumes := report.summary.rows[summaryKey{Cloud: "aws", Kind: kindVolume}]
+	if volumes == nil || volumes.Scanned != 1 || volumes.Mock != 1 {
+		t.Fatalf("want the reachable region's volume swept, got %+v", volumes)
+	}
+	if !reflect.DeepEqual(volumes.FailedRegions, []string{"eu-west-1"}) {
+		t.Errorf("want eu-west-1 recorded on the volume row, got %v", volumes.FailedRegions)
+	}
+	if got := report.summary.exitCode(); got != exitListingFailed {
+		t.Errorf("want exit code %d after a partial listing, got %d", exitListingFailed, got)
+	}
+	buf := &bytes.Buffer{}
+	report.summary.write(buf, "DELETED")
+	if !strings.Contains(buf.String(), "listing failed in eu-west-1") {
+		t.Errorf("want the failed region in the summary notes, got:\n%s", buf.String())
+	}
+}